@@ -2,10 +2,11 @@ package main
 
 import (
 	"context"
+	"errors"
 	"fmt"
-	"net/url"
 	"os"
 	"strings"
+	"text/tabwriter"
 	"time"
 
 	"github.com/briandowns/spinner"
@@ -14,8 +15,10 @@ import (
 	"ask-web/pkg/config"
 	"ask-web/pkg/database"
 	"ask-web/pkg/download"
+	"ask-web/pkg/filter"
 	"ask-web/pkg/linewrap"
 	"ask-web/pkg/logger"
+	"ask-web/pkg/query"
 	"ask-web/pkg/search"
 	"ask-web/pkg/summarize"
 	"ask-web/pkg/utils"
@@ -69,14 +72,20 @@ func main() {
 		return
 	}
 
-	resultFilter := func(result search.SearchResult) bool {
-		for _, url := range opts.FilteredURLs {
-			if strings.Contains(result.URL, url) {
-				return false
-			}
-		}
-
-		return true
+	// The filter list is for sites that scrape badly, so engines that give
+	// us the text (eg wikipedia) get past it
+	var denyRules []string
+	for _, rule := range opts.FilteredURLs {
+		denyRules = append(denyRules, "scraped:"+rule)
+	}
+	denyRules = append(denyRules, opts.ExcludeRules...)
+	allowRules := append([]string{}, opts.AllowRules...)
+	for _, site := range opts.OnlySites {
+		allowRules = append(allowRules, "domain:"+site)
+	}
+	resultFilter, err := filter.Parse(denyRules, allowRules)
+	if err != nil {
+		log.Fatal("Error in filter rules: ", err)
 	}
 
 	apiKeys := utils.SetupKeys(opts.ConfigDir)
@@ -90,114 +99,362 @@ func main() {
 		fmt.Println("Bing API Key:   ", apiKeys.BingAPIKey)
 		fmt.Println("Bing Config Key:", apiKeys.BingConfigKey)
 		fmt.Println("---")
+		fmt.Println("Brave API Key:  ", apiKeys.BraveAPIKey)
+		fmt.Println("---")
+		fmt.Println("Stack Exchange Key:", apiKeys.StackExchangeKey)
+		fmt.Println("---")
+		fmt.Println("GitHub Token:", apiKeys.GitHubToken)
+		fmt.Println("---")
 		fmt.Println("OpenAI Key:", apiKeys.OpenAIKey)
 		os.Exit(0)
 	}
 
-	var query string
-	if pflag.NArg() > 0 {
-		query, err = search.CreateSearchQuery(opts, apiKeys.OpenAIKey, pflag.Arg(0))
-		if err != nil {
-			query = pflag.Arg(0)
-		}
-
-		query = strings.Trim(query, "\"")
-		query = url.QueryEscape(query)
+	if pflag.NArg() == 1 && pflag.Arg(0) == "engines" {
+		showEngines(opts, db, apiKeys)
+		return
+	}
 
+	var queries []string
+	switch {
+	case opts.Query != "":
+		queries = []string{opts.Query}
+	case pflag.NArg() > 0 && opts.Rewrite:
 		log.Info("Original prompt: ", pflag.Arg(0))
-		log.Info("Generated query: ", query)
+		queries, err = generateQueries(opts, apiKeys, pflag.Arg(0))
+		if err != nil {
+			log.Warn("Error generating search query, using the prompt as is: ", err)
+			fmt.Fprintf(os.Stderr, "Couldn't generate a search query (%s); searching for the prompt as is\n", err)
+			queries = []string{pflag.Arg(0)}
+		}
+		for _, q := range queries {
+			log.Info("Generated query: ", q)
+		}
+	case pflag.NArg() > 0:
+		queries = []string{pflag.Arg(0)}
+	default:
+		log.Fatal("Nothing to search for; give a prompt or --query")
 	}
+	query := queries[0]
 
-	unescapedQuery, err := url.QueryUnescape(query)
+	safeSearch, err := search.ParseSafeSearch(opts.SafeSearch)
 	if err != nil {
-		log.Fatal("Error unescaping query:", err)
+		log.Fatal(err)
 	}
 
-	fmt.Println("Gathering search results for query:", unescapedQuery)
-	var ddgResults []search.SearchResult
-	ddgResults, err = search.DDGSearch(query, opts.NumResults, resultFilter)
+	since, err := search.ParseSince(opts.Since)
 	if err != nil {
-		log.Fatal("Error during web search:", err)
+		log.Fatal(err)
 	}
-	for _, result := range ddgResults {
-		log.Info("DuckDuckGo URL:", result.URL)
+	if opts.Stale != "flag" && opts.Stale != "drop" {
+		log.Fatal(fmt.Sprintf("invalid --stale %q (want flag or drop)", opts.Stale))
 	}
 
-	var googleResults []search.SearchResult
-	if apiKeys.GoogleAPIKey != "" && apiKeys.GoogleCSEID != "" {
-		googleResults, err = search.GoogleSearch(apiKeys.GoogleAPIKey, apiKeys.GoogleCSEID, query, opts.NumResults, resultFilter)
-		if err != nil {
-			log.Fatal("Error during web search:", err)
-		}
+	engines, errs := search.NewEngines(opts.Engines, opts.DisabledEngines, search.EngineConfig{
+		Keys:    apiKeys,
+		Opts:    opts,
+		Pages:   db.PageIndex(),
+		Indexes: db.IndexStore(),
+	})
+	for _, err := range errs {
+		log.Warn("Skipping search engine: ", err)
 	}
-	for _, result := range googleResults {
-		log.Info("Google URL:", result.URL)
+	if len(engines) == 0 {
+		log.Fatal("No search engines available; check --engines and your API keys")
 	}
 
-	var bingResults []search.SearchResult
-	if apiKeys.BingAPIKey != "" && apiKeys.BingConfigKey != "" {
-		bingResults, err = search.BingSearch(apiKeys.BingAPIKey, apiKeys.BingConfigKey, query, opts.NumResults, resultFilter)
-		if err != nil {
-			log.Fatal("Error during web search:", err)
+	engines = search.WithLimits(engines, db.UsageTracker(), opts.EngineQuotas)
+
+	// Cached results don't count against quotas, so the cache goes outside
+	if opts.Cache {
+		if pruned, err := db.PruneCache(opts.CacheTTL); err != nil {
+			log.Warn("Error pruning search cache: ", err)
+		} else if pruned > 0 {
+			log.Info(fmt.Sprintf("Pruned %d expired search cache entries", pruned))
 		}
+		engines = search.WithCache(engines, db.ResultCache(), opts.CacheTTL, opts.RefreshCache)
 	}
-	for _, result := range bingResults {
-		log.Info("Bing URL:", result.URL)
+
+	if len(queries) > 1 {
+		fmt.Println("Gathering search results for queries:")
+		for _, q := range queries {
+			fmt.Println("  " + q)
+		}
+	} else {
+		fmt.Println("Gathering search results for query:", query)
+	}
+	searchCtx, cancel := context.WithTimeout(context.Background(), opts.SearchDeadline)
+	engineResults := search.SearchAllQueries(searchCtx, engines, search.SearchRequest{
+		Query:        query,
+		MaxResults:   opts.NumResults,
+		MaxPages:     opts.MaxPages,
+		Region:       opts.Region,
+		Language:     opts.Language,
+		SafeSearch:   safeSearch,
+		Sites:        opts.Sites,
+		ExcludeSites: opts.ExcludeSites,
+		Since:        since,
+		Filter:       resultFilter.Filter(),
+		FilterKey:    resultFilter.String(),
+	}, queries, opts.EngineTimeout)
+	cancel()
+
+	quotaSkipped := make(map[string]bool)
+	for _, er := range engineResults {
+		if errors.Is(er.Err, search.ErrQuotaExceeded) {
+			if !quotaSkipped[er.Engine] {
+				quotaSkipped[er.Engine] = true
+				log.Warn("Skipping search engine: ", er.Err)
+				// The error starts with the engine's name
+				fmt.Fprintf(os.Stderr, "Skipping %s\n", er.Err)
+			}
+			continue
+		}
+		if er.Err != nil {
+			log.Error(fmt.Sprintf("%s failed for %q after %s: %s", er.Engine, er.Query, er.Duration.Round(time.Millisecond), er.Err))
+			fmt.Fprintf(os.Stderr, "Search engine %s failed: %s%s\n", er.Engine, er.Err, failureHint(er.Err))
+			continue
+		}
+		if er.Cached {
+			log.Info(fmt.Sprintf("%s results for %q came from the cache", er.Engine, er.Query))
+		}
+		for _, result := range er.Results {
+			log.Info(fmt.Sprintf("%s URL for %q: %s", er.Engine, er.Query, result.URL))
+		}
 	}
 
-	results := append(ddgResults, googleResults...)
-	results = append(results, bingResults...)
-	results = utils.DedupeResults(results)
+	if dropped := resultFilter.Dropped(); len(dropped) > 0 {
+		fmt.Printf("Filtered out %d results:\n", len(dropped))
+		for _, d := range dropped {
+			fmt.Printf("  %s (%s)\n", d.Result.URL, d.Reason)
+			log.Info(fmt.Sprintf("Filtered %s: %s", d.Result.URL, d.Reason))
+		}
+	}
+
+	results := search.Fuse(engineResults, opts.EngineWeights, opts.NumResults)
+	if len(results) == 0 {
+		log.Fatal("No search results from any engine")
+	}
+	fmt.Println("Results from:", search.Contributors(engineResults))
+	for _, result := range results {
+		log.Info(fmt.Sprintf("Fused URL: %s (engines: %s; queries: %s)", result.URL,
+			strings.Join(result.Engines, ", "), strings.Join(result.Queries, " | ")))
+	}
 
 	s := spinner.New(spinner.CharSets[14], 100*time.Millisecond)
 
+	var contents []string
+	newSummarizer := summarize.NewSummarizer
+	if opts.Fast {
+		// The snippets are already here; skip the slow part
+		contents = summarize.FormatSnippets(results)
+		newSummarizer = summarize.NewSnippetSummarizer
+	} else {
+		contents = downloadContents(opts, db, results, since, s)
+	}
+
+	fmt.Println("Summarizing content...")
+	s.Start()
+
+	summarizer, err := newSummarizer(opts.Model, apiKeyFor(opts.Model, apiKeys), opts)
+	if err != nil {
+		log.Fatal("Error creating summarizer:", err)
+	}
+
+	summary, err := summarizer.Summarize(context.Background(), contents, query)
+	if err != nil {
+		log.Fatal("Error during summarization:", err)
+	}
+
+	s.Stop()
+
+	db.SaveSearchResults(query, queries, results, summary)
+
+	wrapper := linewrap.NewLineWrapper(80, 4, os.Stdout)
+	wrapper.Write([]byte(summary))
+	fmt.Println()
+}
+
+// Download the pages for results and clean them up for summarizing, dropping
+// or flagging any published before since (see --stale). Results that came
+// with their text (and maybe their date) are used as they are. Pages from the
+// web are kept in db for the history engine.
+func downloadContents(opts *config.Opts, db *database.SearchDB, results []search.SearchResult, since time.Duration, s *spinner.Spinner) []string {
+	log := logger.GetLogger()
+
 	fmt.Println("Downloading search results...")
 	s.Start()
 	var contents []string
+	var stale []string
+	cutoff := time.Now().Add(-since)
 	for _, result := range results {
-		log.Info("Downloading unique URL:", result.URL)
-		content, err := download.Page(result.URL)
-		if err != nil {
-			log.Error(fmt.Sprintf("Error downloading %s: %s", result.URL, err.Error()))
-			continue
+		var content string
+		published, dated := result.Published, !result.Published.IsZero()
+		if result.Content != "" {
+			// Already clean
+			log.Info("Using the engine's text for ", result.URL)
+			content = result.Content
+		} else {
+			log.Info("Downloading unique URL:", result.URL)
+			page, err := download.ResultPage(result.URL, result.Engines, opts.LocalDirs)
+			if err != nil {
+				log.Error(fmt.Sprintf("Error downloading %s: %s", result.URL, err.Error()))
+				continue
+			}
+
+			// Engines only roughly honour time ranges (and some pages get
+			// re-crawled long after they were written), so check the page
+			// itself
+			if !dated {
+				published, dated = download.PublishedDate(page)
+			}
+			if download.IsPlainText(result.URL) {
+				// Local notes and source, which would lose their < and >
+				content = page
+			} else {
+				content = utils.CleanText(page)
+			}
+
+			// Only pages from the web, which the history engine can't
+			// just look at again
+			if strings.HasPrefix(result.URL, "http") {
+				var saved time.Time
+				if dated {
+					saved = published
+				}
+				if err := db.SavePage(result.URL, result.Title, content, saved); err != nil {
+					log.Warn("Error saving page for history: ", err)
+				}
+			}
+		}
+
+		if dated {
+			log.Info(fmt.Sprintf("%s published %s", result.URL, published.Format(time.DateOnly)))
+			if since > 0 && published.Before(cutoff) {
+				stale = append(stale, fmt.Sprintf("%s (published %s)", result.URL, published.Format(time.DateOnly)))
+				if opts.Stale == "drop" {
+					log.Info("Dropping stale page: ", result.URL)
+					continue
+				}
+				content = fmt.Sprintf("Note: this page was published on %s, before the requested time range, and may be out of date.\n%s",
+					published.Format(time.DateOnly), content)
+			}
 		}
+
 		contents = append(contents, content)
 	}
 	s.Stop()
 
-	var cleanedContents []string
-	for _, content := range contents {
-		cleanedContents = append(cleanedContents, utils.CleanText(content))
+	if len(stale) > 0 {
+		if opts.Stale == "drop" {
+			fmt.Printf("Dropped %d pages older than %s:\n", len(stale), opts.Since)
+		} else {
+			fmt.Printf("%d pages are older than %s:\n", len(stale), opts.Since)
+		}
+		for _, page := range stale {
+			fmt.Println("  " + page)
+		}
 	}
 
-	fmt.Println("Summarizing content...")
-	s.Start()
+	return contents
+}
 
-	// Determine which API key to use based on the model
-	var apiKey string
-	switch opts.Model {
+// Determine which API key to use based on the model
+func apiKeyFor(model string, keys search.APIKeys) string {
+	switch model {
 	case summarize.ModelOpenAI:
-		apiKey = apiKeys.OpenAIKey
+		return keys.OpenAIKey
 	case summarize.ModelGoogle:
-		apiKey = apiKeys.GeminiAPIKey
+		return keys.GeminiAPIKey
 	}
+	return ""
+}
 
-	summarizer, err := summarize.NewSummarizer(opts.Model, apiKey, opts)
-	if err != nil {
-		log.Fatal("Error creating summarizer:", err)
+// Suggest what to do about the kinds of engine failure a user can fix
+func failureHint(err error) string {
+	switch {
+	case errors.Is(err, search.ErrAuthFailed):
+		return " (check its API key with --show-keys)"
+	case errors.Is(err, search.ErrRateLimited):
+		return " (try again in a while)"
+	case errors.Is(err, search.ErrBlocked):
+		return " (try another engine for a while)"
+	default:
+		return ""
+	}
+}
+
+// Ask the LLM to turn the prompt into search queries, using the same provider
+// as the summary unless another one is configured for rewriting
+func generateQueries(opts *config.Opts, keys search.APIKeys, prompt string) ([]string, error) {
+	model := opts.RewriteModel
+	if model == "" {
+		model = opts.Model
 	}
 
-	summary, err := summarizer.Summarize(context.Background(), cleanedContents, query)
+	llm, err := summarize.NewCompleter(model, apiKeyFor(model, keys))
 	if err != nil {
-		log.Fatal("Error during summarization:", err)
+		return nil, err
 	}
 
-	s.Stop()
+	rewriter := query.NewRewriter(llm, opts.RewriteModelName, opts.QueryPrompt)
+	return rewriter.Rewrite(context.Background(), prompt, opts.QueryVariants)
+}
 
-	plainQuery, _ := url.QueryUnescape(query)
-	db.SaveSearchResults(plainQuery, results, summary)
+// Show every known engine, whether it can be used, and how much of its quota
+// is left today
+func showEngines(opts *config.Opts, db *database.SearchDB, keys search.APIKeys) {
+	cfg := search.EngineConfig{Keys: keys, Opts: opts, Pages: db.PageIndex(), Indexes: db.IndexStore()}
+	usage := db.UsageTracker()
+	today := search.QuotaDay(time.Now())
 
-	wrapper := linewrap.NewLineWrapper(80, 4, os.Stdout)
-	wrapper.Write([]byte(summary))
-	fmt.Println()
+	enabled := make(map[string]bool)
+	for _, name := range opts.Engines {
+		enabled[strings.ToLower(strings.TrimSpace(name))] = true
+	}
+	for _, name := range opts.DisabledEngines {
+		delete(enabled, strings.ToLower(strings.TrimSpace(name)))
+	}
+
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(w, "ENGINE\tSTATUS\tINTERVAL\tUSED TODAY\tQUOTA\tREMAINING")
+	for _, name := range search.Registered() {
+		used, err := usage.Usage(name, today)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Error reading usage for %s: %s\n", name, err)
+		}
+
+		engines, errs := search.NewEngines([]string{name}, nil, cfg)
+		if len(engines) == 0 {
+			status := "not configured"
+			if len(errs) > 0 && !errors.Is(errs[0], search.ErrNotConfigured) {
+				status = errs[0].Error()
+			}
+			fmt.Fprintf(w, "%s\t%s\t-\t%d\t-\t-\n", name, status, used)
+			continue
+		}
+
+		status := "available"
+		if enabled[name] {
+			status = "enabled"
+		}
+
+		limited := search.WithLimits(engines, usage, opts.EngineQuotas)[0].(*search.LimitedEngine)
+		interval := "-"
+		if i := limited.Capabilities().MinInterval; i > 0 {
+			interval = i.String()
+		}
+		quota, remaining := "none", "-"
+		if limited.Quota() > 0 {
+			quota = fmt.Sprintf("%d/day", limited.Quota())
+			if left, err := limited.Remaining(); err == nil {
+				remaining = fmt.Sprintf("%d", left)
+				if left == 0 {
+					status += " (used up)"
+				}
+			}
+		}
+
+		fmt.Fprintf(w, "%s\t%s\t%s\t%d\t%s\t%s\n", name, status, interval, used, quota, remaining)
+	}
+	w.Flush()
 }
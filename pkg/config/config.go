@@ -6,7 +6,9 @@ import (
 	"os"
 	"os/user"
 	"path/filepath"
+	"strconv"
 	"strings"
+	"time"
 
 	"golang.org/x/term"
 
@@ -42,12 +44,55 @@ type Opts struct {
 	DBTable     string
 
 	QueryPrompt   string
+	QueryVariants int
 	SummaryPrompt string
+	SnippetPrompt string
+
+	Query            string
+	Rewrite          bool
+	RewriteModel     string
+	RewriteModelName string
 
 	Search string
 	Show   int
 
 	FilteredURLs []string
+	AllowRules   []string
+	ExcludeRules []string
+	OnlySites    []string
+
+	Engines         []string
+	DisabledEngines []string
+	EngineTimeout   time.Duration
+	SearchDeadline  time.Duration
+	EngineWeights   map[string]float64
+	EngineQuotas    map[string]int
+
+	Region       string
+	Language     string
+	SafeSearch   string
+	Sites        []string
+	ExcludeSites []string
+	MaxPages     int
+	Since        string
+	Stale        string
+	Fast         bool
+
+	Cache        bool
+	CacheTTL     time.Duration
+	RefreshCache bool
+
+	DDGCapture    bool
+	DDGCaptureDir string
+
+	WikiURL           string
+	StackExchangeSite string
+	GitHubSearch      []string
+	LocalDirs         []string
+
+	SearxNGURL        string
+	SearxNGCategories []string
+	SearxNGEngines    []string
 
 	NumResults int
 	MaxTokens  int
@@ -83,21 +128,57 @@ func Initialize() (*Opts, error) {
 	viper.SetDefault("model.num_results", 3)
 	viper.SetDefault("model.temperature", 0.7)
 	viper.SetDefault("model.query_prompt", "Turn this prompt into a search query, ensuring to retain its meaning")
+	viper.SetDefault("model.query_variants", 1)
+	viper.SetDefault("rewrite.enabled", true)
+	viper.SetDefault("rewrite.model", "")
+	viper.SetDefault("rewrite.model_name", "")
 	viper.SetDefault("model.summary_prompt", "Please provide a detailed summary of the following text that is directly related to the query")
+	viper.SetDefault("model.snippet_prompt", "Using only the following search result titles, URLs and snippets, which may be cut short, answer the query concisely, citing the URLs you relied on and saying so if the snippets aren't enough to answer it. The query is")
 	viper.SetDefault("logging.file", defaultLogFileName)
 	viper.SetDefault("database.file", filepath.Join(configDir, "ask-web.db"))
 	viper.SetDefault("database.table", "conversations")
 	viper.SetDefault("screen.width", width)
 	viper.SetDefault("screen.height", height)
 	viper.SetDefault("filter", []string{"wikipedia.org", "britannica.com"})
+	viper.SetDefault("allow", []string{})
+	viper.SetDefault("engines.enabled", []string{"duckduckgo", "google", "bing", "brave"})
+	viper.SetDefault("engines.disabled", []string{})
+	viper.SetDefault("engines.timeout", "10s")
+	viper.SetDefault("engines.deadline", "20s")
+	viper.SetDefault("engines.weights", map[string]string{})
+	viper.SetDefault("web.region", "")
+	viper.SetDefault("web.language", "")
+	viper.SetDefault("web.safe_search", "")
+	viper.SetDefault("web.sites", []string{})
+	viper.SetDefault("web.exclude_sites", []string{})
+	viper.SetDefault("web.max_pages", 3)
+	viper.SetDefault("web.since", "")
+	viper.SetDefault("web.stale", "flag")
+	viper.SetDefault("web.fast", false)
+	viper.SetDefault("wikipedia.url", "")
+	viper.SetDefault("stackexchange.site", "stackoverflow")
+	viper.SetDefault("github.search", []string{"issues", "repositories"})
+	viper.SetDefault("local.dirs", []string{})
+	viper.SetDefault("searxng.url", "")
+	viper.SetDefault("cache.enabled", true)
+	viper.SetDefault("cache.ttl", "6h")
+	viper.SetDefault("duckduckgo.capture", false)
+	viper.SetDefault("duckduckgo.capture_dir", "")
+	viper.SetDefault("searxng.categories", []string{"general"})
+	viper.SetDefault("searxng.engines", []string{})
 
 	// Now define the rest of the flags using values from viper (which now has
 	// config file values)
 	pflag.StringP("model", "m", viper.GetString("model.default"), "Which LLM to use for summary (chatgpt|deepseek)")
-	pflag.IntP("num-results", "n", viper.GetInt("model.num_results"), "How many web pages to check per search engine")
+	pflag.IntP("num-results", "n", viper.GetInt("model.num_results"), "How many web pages to summarize")
 	pflag.IntP("context-length", "l", viper.GetInt("model.context_length"), "Maximum context length")
 	pflag.StringP("database", "d", viper.GetString("database.file"), "Database file")
 	pflag.StringP("query-prompt", "q", viper.GetString("model.query_prompt"), "Prompt for generating search query from prompt")
+	pflag.IntP("variants", "", viper.GetInt("model.query_variants"), "How many different search queries to generate from the prompt")
+	pflag.StringP("query", "", "", "Search for exactly this, without rewriting the prompt")
+	pflag.BoolP("no-rewrite", "", !viper.GetBool("rewrite.enabled"), "Search for the prompt as given instead of an LLM-generated query")
+	pflag.StringP("rewrite-model", "", viper.GetString("rewrite.model"), "Which LLM to use for generating queries (chatgpt|gemini); defaults to --model")
+	pflag.StringP("rewrite-model-name", "", viper.GetString("rewrite.model_name"), "Which of the rewrite LLM's models to use, eg gpt-4o; defaults to the provider's default")
 	pflag.StringP("summary-prompt", "S", viper.GetString("model.summary_prompt"), "System prompt for LLM")
 	pflag.IntP("max-tokens", "t", viper.GetInt("model.max_tokens"), "Maximum tokens to generate")
 	pflag.Float64P("temperature", "T", viper.GetFloat64("model.temperature"), "Temperature for summarization")
@@ -108,11 +189,34 @@ func Initialize() (*Opts, error) {
 	pflag.StringP("search", "s", "", "Search for a response")
 	pflag.IntP("show", "", 0, "Show response with ID")
 	pflag.BoolP("show-keys", "", false, "Show API keys")
+	pflag.StringArrayP("exclude", "x", []string{}, "Filter rule for results to drop, eg x.com, glob:*.pinterest.* or title:sponsored (repeatable)")
+	pflag.StringArrayP("only-site", "", []string{}, "Only keep results from this domain (repeatable)")
+	pflag.StringSliceP("engines", "e", viper.GetStringSlice("engines.enabled"), "Search engines to use, in order (comma separated)")
+	pflag.StringSliceP("disable-engines", "", viper.GetStringSlice("engines.disabled"), "Search engines to skip (comma separated)")
+	pflag.DurationP("engine-timeout", "", viper.GetDuration("engines.timeout"), "How long to wait for each search engine")
+	pflag.DurationP("search-deadline", "", viper.GetDuration("engines.deadline"), "How long to wait for all search engines")
+	pflag.StringToStringP("weights", "", viper.GetStringMapString("engines.weights"), "Per-engine weights for ranking, eg google=2,bing=0.5")
+	pflag.StringP("region", "r", viper.GetString("web.region"), "Region for search results as country-language, eg de-de or us-en")
+	pflag.StringP("lang", "", viper.GetString("web.language"), "Language for search results, eg de")
+	pflag.StringP("safe", "", viper.GetString("web.safe_search"), "Safe search level (off|moderate|strict); each engine's own default if unset")
+	pflag.IntP("max-pages", "", viper.GetInt("web.max_pages"), "Most pages of results to fetch from each search engine")
+	pflag.StringP("since", "", viper.GetString("web.since"), "Only search for results this recent, eg 7d, 2w, 1m or 1y")
+	pflag.StringP("stale", "", viper.GetString("web.stale"), "What to do with pages published before --since (flag|drop)")
+	pflag.BoolP("fast", "", viper.GetBool("web.fast"), "Answer from the search result snippets without downloading the pages")
+	pflag.BoolP("no-cache", "", !viper.GetBool("cache.enabled"), "Don't use or update the search result cache")
+	pflag.BoolP("refresh", "", false, "Ignore cached search results, but cache the new ones")
+	pflag.DurationP("cache-ttl", "", viper.GetDuration("cache.ttl"), "How long cached search results are used for")
+	pflag.BoolP("ddg-capture", "", viper.GetBool("duckduckgo.capture"), "Save DuckDuckGo's responses for debugging")
+	pflag.StringP("wiki-url", "", viper.GetString("wikipedia.url"), "API URL (api.php) of the MediaWiki to search instead of Wikipedia")
+	pflag.StringP("se-site", "", viper.GetString("stackexchange.site"), "Stack Exchange site to search, eg stackoverflow, superuser or unix")
+	pflag.StringSliceP("local-dirs", "", viper.GetStringSlice("local.dirs"), "Directories for the local engine to search (comma separated)")
+	pflag.StringSliceP("github-search", "", viper.GetStringSlice("github.search"), "What to search on GitHub: issues, repositories and/or discussions (comma separated)")
+	pflag.StringP("searxng-url", "", viper.GetString("searxng.url"), "Base URL of a SearxNG instance, eg http://localhost:8080")
 	pflag.StringP("width", "", "", "Width of the screen for linewrap")
 	pflag.StringP("height", "", "", "Height of the screen for linewrap")
 
 	// Bind all flags to viper
-	viper.BindPFlag("model.num_results", pflag.Lookup("context"))
+	viper.BindPFlag("model.num_results", pflag.Lookup("num-results"))
 	viper.BindPFlag("dump-config", pflag.Lookup("dump-config"))
 	viper.BindPFlag("stderr", pflag.Lookup("stderr"))
 	viper.BindPFlag("show-keys", pflag.Lookup("show-keys"))
@@ -120,9 +224,35 @@ func Initialize() (*Opts, error) {
 	viper.BindPFlag("show", pflag.Lookup("show"))
 	viper.BindPFlag("database.file", pflag.Lookup("database"))
 	viper.BindPFlag("model.system_prompt", pflag.Lookup("system-prompt"))
+	viper.BindPFlag("model.query_prompt", pflag.Lookup("query-prompt"))
+	viper.BindPFlag("model.query_variants", pflag.Lookup("variants"))
+	viper.BindPFlag("no-rewrite", pflag.Lookup("no-rewrite"))
+	viper.BindPFlag("rewrite.model", pflag.Lookup("rewrite-model"))
+	viper.BindPFlag("rewrite.model_name", pflag.Lookup("rewrite-model-name"))
 	viper.BindPFlag("model.max_tokens", pflag.Lookup("max-tokens"))
 	viper.BindPFlag("model.context_length", pflag.Lookup("context-length"))
 	viper.BindPFlag("model.temperature", pflag.Lookup("temperature"))
+	viper.BindPFlag("engines.enabled", pflag.Lookup("engines"))
+	viper.BindPFlag("engines.disabled", pflag.Lookup("disable-engines"))
+	viper.BindPFlag("engines.timeout", pflag.Lookup("engine-timeout"))
+	viper.BindPFlag("engines.deadline", pflag.Lookup("search-deadline"))
+	viper.BindPFlag("engines.weights", pflag.Lookup("weights"))
+	viper.BindPFlag("web.region", pflag.Lookup("region"))
+	viper.BindPFlag("web.language", pflag.Lookup("lang"))
+	viper.BindPFlag("web.safe_search", pflag.Lookup("safe"))
+	viper.BindPFlag("web.max_pages", pflag.Lookup("max-pages"))
+	viper.BindPFlag("web.since", pflag.Lookup("since"))
+	viper.BindPFlag("web.stale", pflag.Lookup("stale"))
+	viper.BindPFlag("web.fast", pflag.Lookup("fast"))
+	viper.BindPFlag("no-cache", pflag.Lookup("no-cache"))
+	viper.BindPFlag("refresh", pflag.Lookup("refresh"))
+	viper.BindPFlag("cache.ttl", pflag.Lookup("cache-ttl"))
+	viper.BindPFlag("duckduckgo.capture", pflag.Lookup("ddg-capture"))
+	viper.BindPFlag("wikipedia.url", pflag.Lookup("wiki-url"))
+	viper.BindPFlag("stackexchange.site", pflag.Lookup("se-site"))
+	viper.BindPFlag("github.search", pflag.Lookup("github-search"))
+	viper.BindPFlag("local.dirs", pflag.Lookup("local-dirs"))
+	viper.BindPFlag("searxng.url", pflag.Lookup("searxng-url"))
 	viper.BindPFlag("screen.width", pflag.Lookup("width"))
 	viper.BindPFlag("screen.height", pflag.Lookup("height"))
 
@@ -138,30 +268,111 @@ func Initialize() (*Opts, error) {
 		os.Exit(0)
 	}
 
+	weights, err := parseWeights(viper.GetStringMapString("engines.weights"))
+	if err != nil {
+		return nil, err
+	}
+
+	quotas, err := parseQuotas(viper.GetStringMapString("engines.quotas"))
+	if err != nil {
+		return nil, err
+	}
+
 	return &Opts{
-		ConfigDir:     configDir,
-		DumpConfig:    viper.GetBool("dump-config"),
-		ShowAPIKeys:   viper.GetBool("show-keys"),
-		Model:         pflag.Lookup("model").Value.String(),
-		ContextLength: viper.GetInt("model.context_length"),
-		Temperature:   viper.GetFloat64("model.temperature"),
-		FilteredURLs:  viper.GetStringSlice("filter"),
-		LogFileName:   viper.GetString("logging.file"),
-		LogStderr:     viper.GetBool("stderr"),
-		DBFileName:    os.ExpandEnv(viper.GetString("database.file")),
-		DBTable:       viper.GetString("database.table"),
-		QueryPrompt:   viper.GetString("model.query_prompt"),
-		SummaryPrompt: viper.GetString("model.summary_prompt"),
-		Search:        viper.GetString("search"),
-		Show:          viper.GetInt("show"),
-		NumResults:    viper.GetInt("model.num_results"),
-		MaxTokens:     viper.GetInt("model.max_tokens"),
-		ScreenWidth:   min(viper.GetInt("screen.width"), MaxTermWidth) - widthPad,
-		ScreenHeight:  viper.GetInt("screen.height"),
-		TabWidth:      TabWidth,
+		ConfigDir:         configDir,
+		DumpConfig:        viper.GetBool("dump-config"),
+		ShowAPIKeys:       viper.GetBool("show-keys"),
+		Model:             pflag.Lookup("model").Value.String(),
+		ContextLength:     viper.GetInt("model.context_length"),
+		Temperature:       viper.GetFloat64("model.temperature"),
+		FilteredURLs:      viper.GetStringSlice("filter"),
+		AllowRules:        viper.GetStringSlice("allow"),
+		ExcludeRules:      getStringArray("exclude"),
+		OnlySites:         getStringArray("only-site"),
+		Engines:           viper.GetStringSlice("engines.enabled"),
+		DisabledEngines:   viper.GetStringSlice("engines.disabled"),
+		EngineTimeout:     viper.GetDuration("engines.timeout"),
+		SearchDeadline:    viper.GetDuration("engines.deadline"),
+		EngineWeights:     weights,
+		EngineQuotas:      quotas,
+		Region:            viper.GetString("web.region"),
+		Language:          viper.GetString("web.language"),
+		SafeSearch:        viper.GetString("web.safe_search"),
+		Sites:             viper.GetStringSlice("web.sites"),
+		ExcludeSites:      viper.GetStringSlice("web.exclude_sites"),
+		MaxPages:          viper.GetInt("web.max_pages"),
+		Since:             viper.GetString("web.since"),
+		Stale:             viper.GetString("web.stale"),
+		Fast:              viper.GetBool("web.fast"),
+		Cache:             !viper.GetBool("no-cache"),
+		CacheTTL:          viper.GetDuration("cache.ttl"),
+		RefreshCache:      viper.GetBool("refresh"),
+		DDGCapture:        viper.GetBool("duckduckgo.capture"),
+		DDGCaptureDir:     os.ExpandEnv(viper.GetString("duckduckgo.capture_dir")),
+		WikiURL:           viper.GetString("wikipedia.url"),
+		StackExchangeSite: viper.GetString("stackexchange.site"),
+		GitHubSearch:      viper.GetStringSlice("github.search"),
+		LocalDirs:         expandPaths(viper.GetStringSlice("local.dirs")),
+		SearxNGURL:        viper.GetString("searxng.url"),
+		SearxNGCategories: viper.GetStringSlice("searxng.categories"),
+		SearxNGEngines:    viper.GetStringSlice("searxng.engines"),
+		LogFileName:       viper.GetString("logging.file"),
+		LogStderr:         viper.GetBool("stderr"),
+		DBFileName:        os.ExpandEnv(viper.GetString("database.file")),
+		DBTable:           viper.GetString("database.table"),
+		QueryPrompt:       viper.GetString("model.query_prompt"),
+		QueryVariants:     viper.GetInt("model.query_variants"),
+		SummaryPrompt:     viper.GetString("model.summary_prompt"),
+		SnippetPrompt:     viper.GetString("model.snippet_prompt"),
+		Query:             pflag.Lookup("query").Value.String(),
+		Rewrite:           !viper.GetBool("no-rewrite"),
+		RewriteModel:      viper.GetString("rewrite.model"),
+		RewriteModelName:  viper.GetString("rewrite.model_name"),
+		Search:            viper.GetString("search"),
+		Show:              viper.GetInt("show"),
+		NumResults:        viper.GetInt("model.num_results"),
+		MaxTokens:         viper.GetInt("model.max_tokens"),
+		ScreenWidth:       min(viper.GetInt("screen.width"), MaxTermWidth) - widthPad,
+		ScreenHeight:      viper.GetInt("screen.height"),
+		TabWidth:          TabWidth,
 	}, nil
 }
 
+func getStringArray(flag string) []string {
+	values, err := pflag.CommandLine.GetStringArray(flag)
+	if err != nil {
+		return nil
+	}
+	return values
+}
+
+func parseWeights(raw map[string]string) (map[string]float64, error) {
+	weights := make(map[string]float64, len(raw))
+	for engine, value := range raw {
+		w, err := strconv.ParseFloat(strings.TrimSpace(value), 64)
+		if err != nil || w < 0 {
+			return nil, fmt.Errorf("invalid weight for %s: %q", engine, value)
+		}
+		weights[strings.ToLower(engine)] = w
+	}
+
+	return weights, nil
+}
+
+// Quotas are requests per day; 0 turns off an engine's built-in quota
+func parseQuotas(raw map[string]string) (map[string]int, error) {
+	quotas := make(map[string]int, len(raw))
+	for engine, value := range raw {
+		q, err := strconv.Atoi(strings.TrimSpace(value))
+		if err != nil || q < 0 {
+			return nil, fmt.Errorf("invalid quota for %s: %q", engine, value)
+		}
+		quotas[strings.ToLower(engine)] = q
+	}
+
+	return quotas, nil
+}
+
 func determineScreenSize() (int, int) {
 	width, height, err := term.GetSize(int(os.Stdin.Fd()))
 	if err != nil {
@@ -239,6 +450,15 @@ oopsies:
 	return path
 }
 
+// Paths in the config file don't go through the shell
+func expandPaths(paths []string) []string {
+	expanded := make([]string, 0, len(paths))
+	for _, path := range paths {
+		expanded = append(expanded, expandHomePath(os.ExpandEnv(path)))
+	}
+	return expanded
+}
+
 func setupConfigFile() error {
 	cfgFile := checkConfigFlag()
 
@@ -263,12 +483,45 @@ func DumpConfig(cfg *Opts) {
 	fmt.Printf("Model: %s\n", cfg.Model)
 	fmt.Printf("MaxTokens: %d\n", cfg.MaxTokens)
 	fmt.Printf("NumResults: %d\n", cfg.NumResults)
+	fmt.Printf("FilteredURLs: %s\n", strings.Join(cfg.FilteredURLs, ", "))
+	fmt.Printf("AllowRules: %s\n", strings.Join(cfg.AllowRules, ", "))
+	fmt.Printf("Engines: %s\n", strings.Join(cfg.Engines, ", "))
+	fmt.Printf("DisabledEngines: %s\n", strings.Join(cfg.DisabledEngines, ", "))
+	fmt.Printf("EngineTimeout: %s\n", cfg.EngineTimeout)
+	fmt.Printf("SearchDeadline: %s\n", cfg.SearchDeadline)
+	fmt.Printf("EngineWeights: %v\n", cfg.EngineWeights)
+	fmt.Printf("EngineQuotas: %v\n", cfg.EngineQuotas)
+	fmt.Printf("Region: %s\n", cfg.Region)
+	fmt.Printf("Language: %s\n", cfg.Language)
+	fmt.Printf("SafeSearch: %s\n", cfg.SafeSearch)
+	fmt.Printf("Sites: %s\n", strings.Join(cfg.Sites, ", "))
+	fmt.Printf("ExcludeSites: %s\n", strings.Join(cfg.ExcludeSites, ", "))
+	fmt.Printf("MaxPages: %d\n", cfg.MaxPages)
+	fmt.Printf("Since: %s\n", cfg.Since)
+	fmt.Printf("Stale: %s\n", cfg.Stale)
+	fmt.Printf("Fast: %t\n", cfg.Fast)
+	fmt.Printf("Cache: %t\n", cfg.Cache)
+	fmt.Printf("CacheTTL: %s\n", cfg.CacheTTL)
+	fmt.Printf("RefreshCache: %t\n", cfg.RefreshCache)
+	fmt.Printf("DDGCapture: %t\n", cfg.DDGCapture)
+	fmt.Printf("DDGCaptureDir: %s\n", cfg.DDGCaptureDir)
+	fmt.Printf("WikiURL: %s\n", cfg.WikiURL)
+	fmt.Printf("StackExchangeSite: %s\n", cfg.StackExchangeSite)
+	fmt.Printf("GitHubSearch: %v\n", cfg.GitHubSearch)
+	fmt.Printf("LocalDirs: %v\n", cfg.LocalDirs)
+	fmt.Printf("SearxNGURL: %s\n", cfg.SearxNGURL)
 	fmt.Printf("ContextLength: %d\n", cfg.ContextLength)
 	fmt.Printf("Temperature: %f\n", cfg.Temperature)
 	fmt.Printf("DBFileName: %s\n", cfg.DBFileName)
 	fmt.Printf("DBTable: %s\n", cfg.DBTable)
 	fmt.Printf("LogFileName: %s\n", cfg.LogFileName)
+	fmt.Printf("QueryVariants: %d\n", cfg.QueryVariants)
 	fmt.Printf("SummaryPrompt: %s\n", cfg.SummaryPrompt)
+	fmt.Printf("SnippetPrompt: %s\n", cfg.SnippetPrompt)
+	fmt.Printf("Query: %s\n", cfg.Query)
+	fmt.Printf("Rewrite: %t\n", cfg.Rewrite)
+	fmt.Printf("RewriteModel: %s\n", cfg.RewriteModel)
+	fmt.Printf("RewriteModelName: %s\n", cfg.RewriteModelName)
 	fmt.Printf("ScreenWidth: %d\n", cfg.ScreenWidth)
 	fmt.Printf("ScreenHeight: %d\n", cfg.ScreenHeight)
 	fmt.Printf("TabWidth: %d\n", cfg.TabWidth)
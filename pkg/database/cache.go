@@ -0,0 +1,81 @@
+package database
+
+import (
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"time"
+
+	"ask-web/pkg/search"
+)
+
+// The cache lives alongside the results table, eg conversations_cache
+func cacheTable(dbTable string) string {
+	return dbTable + "_cache"
+}
+
+// resultCache implements search.ResultCache on top of the database
+type resultCache struct {
+	sqlDB *SearchDB
+}
+
+// ResultCache returns the engine result cache stored in this database.
+func (sqlDB *SearchDB) ResultCache() search.ResultCache {
+	return &resultCache{sqlDB: sqlDB}
+}
+
+func (c *resultCache) Get(key string, maxAge time.Duration) ([]search.SearchResult, bool, error) {
+	var resultsJSON string
+	var created int64
+	err := c.sqlDB.db.QueryRow(`
+		SELECT results, created FROM `+cacheTable(c.sqlDB.dbTable)+` WHERE key = ?;
+	`, key).Scan(&resultsJSON, &created)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, false, nil
+	}
+	if err != nil {
+		return nil, false, fmt.Errorf("error reading cache: %v", err)
+	}
+
+	if time.Since(time.Unix(created, 0)) > maxAge {
+		return nil, false, nil
+	}
+
+	var results []search.SearchResult
+	if err := json.Unmarshal([]byte(resultsJSON), &results); err != nil {
+		return nil, false, fmt.Errorf("error decoding cached results: %v", err)
+	}
+
+	return results, true, nil
+}
+
+func (c *resultCache) Put(key string, results []search.SearchResult) error {
+	resultsJSON, err := json.Marshal(results)
+	if err != nil {
+		return fmt.Errorf("error encoding results: %v", err)
+	}
+
+	_, err = c.sqlDB.db.Exec(`
+		INSERT OR REPLACE INTO `+cacheTable(c.sqlDB.dbTable)+` (key, results, created)
+		VALUES (?, ?, ?);
+	`, key, resultsJSON, time.Now().Unix())
+	if err != nil {
+		return fmt.Errorf("error writing cache: %v", err)
+	}
+
+	return nil
+}
+
+// PruneCache deletes cache entries older than maxAge and returns how many
+// were removed.
+func (sqlDB *SearchDB) PruneCache(maxAge time.Duration) (int64, error) {
+	res, err := sqlDB.db.Exec(`
+		DELETE FROM `+cacheTable(sqlDB.dbTable)+` WHERE created < ?;
+	`, time.Now().Add(-maxAge).Unix())
+	if err != nil {
+		return 0, fmt.Errorf("error pruning cache: %v", err)
+	}
+
+	return res.RowsAffected()
+}
@@ -0,0 +1,45 @@
+package database
+
+import (
+	"testing"
+	"time"
+
+	"ask-web/pkg/search"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestResultCache(t *testing.T) {
+	db, err := NewDB(dbPath, dbTable)
+	assert.Nil(t, err)
+	defer RemoveDB()
+	defer db.Close()
+
+	cache := db.ResultCache()
+
+	_, ok, err := cache.Get("google|go iterators", time.Hour)
+	assert.Nil(t, err)
+	assert.False(t, ok)
+
+	results := []search.SearchResult{{Title: "Iterators", URL: "https://go.dev/blog/range-functions", Snippet: "snippet"}}
+	assert.Nil(t, cache.Put("google|go iterators", results))
+
+	cached, ok, err := cache.Get("google|go iterators", time.Hour)
+	assert.Nil(t, err)
+	assert.True(t, ok)
+	assert.Equal(t, results, cached)
+
+	// Replacing an entry doesn't fail on the primary key
+	assert.Nil(t, cache.Put("google|go iterators", results))
+
+	// Backdate the entry so it's expired
+	_, err = db.db.Exec(`UPDATE `+cacheTable(dbTable)+` SET created = ?`, time.Now().Add(-2*time.Hour).Unix())
+	assert.Nil(t, err)
+
+	_, ok, err = cache.Get("google|go iterators", time.Hour)
+	assert.Nil(t, err)
+	assert.False(t, ok)
+
+	pruned, err := db.PruneCache(time.Hour)
+	assert.Nil(t, err)
+	assert.Equal(t, int64(1), pruned)
+}
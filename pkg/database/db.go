@@ -7,7 +7,7 @@ import (
 	"ask-web/pkg/logger"
 )
 
-const SchemaVersion = 3
+const SchemaVersion = 8
 
 func DBSchema(dbTable string) string {
 	return `
@@ -16,7 +16,19 @@ func DBSchema(dbTable string) string {
 		timestamp DATETIME DEFAULT CURRENT_TIMESTAMP,
 		query TEXT NOT NULL,
 		results TEXT NOT NULL,
-		summary TEXT NOT NULL
+		summary TEXT NOT NULL,
+		queries TEXT NOT NULL DEFAULT '[]',
+		sources TEXT NOT NULL DEFAULT '[]'
+	);
+	` + cacheSchema(dbTable) + usageSchema(dbTable) + pagesSchema(dbTable) + indexesSchema(dbTable)
+}
+
+func cacheSchema(dbTable string) string {
+	return `
+	CREATE TABLE IF NOT EXISTS ` + cacheTable(dbTable) + ` (
+		key TEXT PRIMARY KEY,
+		results TEXT NOT NULL,
+		created INTEGER NOT NULL
 	);
 	`
 }
@@ -25,6 +37,35 @@ func SchemaQueryV1(dbTable string) string {
 	return ""
 }
 
+// V4 records the query variants that were searched and which engines and
+// queries turned up each result
+func SchemaQueryV4(dbTable string) string {
+	return `
+	ALTER TABLE ` + dbTable + ` ADD COLUMN queries TEXT NOT NULL DEFAULT '[]';
+	ALTER TABLE ` + dbTable + ` ADD COLUMN sources TEXT NOT NULL DEFAULT '[]';
+	`
+}
+
+// V5 adds the engine result cache
+func SchemaQueryV5(dbTable string) string {
+	return cacheSchema(dbTable)
+}
+
+// V6 adds per-engine request counts for rate limits and quotas
+func SchemaQueryV6(dbTable string) string {
+	return usageSchema(dbTable)
+}
+
+// V7 keeps the text of downloaded pages for the history engine
+func SchemaQueryV7(dbTable string) string {
+	return pagesSchema(dbTable)
+}
+
+// V8 keeps the indexes that the godoc and local engines build, between runs
+func SchemaQueryV8(dbTable string) string {
+	return indexesSchema(dbTable)
+}
+
 // There's got to be a better way to do this
 func getSchemaSQL(schemaVersion int, dbTable string) string {
 	switch schemaVersion {
@@ -34,6 +75,16 @@ func getSchemaSQL(schemaVersion int, dbTable string) string {
 	// 	return SchemaQueryV2(dbTable)
 	// case 3:
 	// 	return SchemaQueryV3(dbTable)
+	case 4:
+		return SchemaQueryV4(dbTable)
+	case 5:
+		return SchemaQueryV5(dbTable)
+	case 6:
+		return SchemaQueryV6(dbTable)
+	case 7:
+		return SchemaQueryV7(dbTable)
+	case 8:
+		return SchemaQueryV8(dbTable)
 	default:
 		return ""
 	}
@@ -95,6 +146,11 @@ func InitializeDB(dbPath string, dbTable string) (*SearchDB, error) {
 			if err != nil {
 				return searchDB, err
 			}
+			// Record each step so a failure part way doesn't redo the rest
+			err = setSchemaVersion(searchDB.db, i)
+			if err != nil {
+				return searchDB, err
+			}
 		}
 	}
 
@@ -0,0 +1,69 @@
+package database
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"time"
+
+	"ask-web/pkg/search"
+)
+
+// Engines' indexes of this machine live alongside the results table, eg
+// conversations_indexes
+func indexesTable(dbTable string) string {
+	return dbTable + "_indexes"
+}
+
+func indexesSchema(dbTable string) string {
+	return `
+	CREATE TABLE IF NOT EXISTS ` + indexesTable(dbTable) + ` (
+		engine TEXT NOT NULL,
+		key TEXT NOT NULL,
+		data BLOB NOT NULL,
+		saved INTEGER NOT NULL,
+		PRIMARY KEY (engine, key)
+	);
+	`
+}
+
+// indexStore implements search.IndexStore on top of the database
+type indexStore struct {
+	sqlDB *SearchDB
+}
+
+// IndexStore returns the engines' indexes stored in this database.
+func (sqlDB *SearchDB) IndexStore() search.IndexStore {
+	return &indexStore{sqlDB: sqlDB}
+}
+
+func (s *indexStore) LoadIndex(engine string, key string) ([]byte, time.Time, error) {
+	var data []byte
+	var saved int64
+	err := s.sqlDB.db.QueryRow(`
+		SELECT data, saved FROM `+indexesTable(s.sqlDB.dbTable)+` WHERE engine = ? AND key = ?;
+	`, engine, key).Scan(&data, &saved)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, time.Time{}, nil
+	}
+	if err != nil {
+		return nil, time.Time{}, fmt.Errorf("error reading index: %v", err)
+	}
+
+	return data, time.Unix(saved, 0), nil
+}
+
+func (s *indexStore) SaveIndex(engine string, key string, data []byte) error {
+	_, err := s.sqlDB.db.Exec(`
+		INSERT INTO `+indexesTable(s.sqlDB.dbTable)+` (engine, key, data, saved)
+		VALUES (?, ?, ?, ?)
+		ON CONFLICT (engine, key) DO UPDATE SET
+			data = excluded.data,
+			saved = excluded.saved;
+	`, engine, key, data, time.Now().Unix())
+	if err != nil {
+		return fmt.Errorf("error saving index: %v", err)
+	}
+
+	return nil
+}
@@ -0,0 +1,37 @@
+package database
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestIndexStore(t *testing.T) {
+	db, err := NewDB(dbPath, dbTable)
+	assert.Nil(t, err)
+	defer RemoveDB()
+	defer db.Close()
+
+	indexes := db.IndexStore()
+
+	data, saved, err := indexes.LoadIndex("godoc", "/go/pkg/mod go1.23.0")
+	assert.Nil(t, err)
+	assert.Nil(t, data)
+	assert.True(t, saved.IsZero())
+
+	assert.Nil(t, indexes.SaveIndex("godoc", "/go/pkg/mod go1.23.0", []byte(`[{"import_path": "fmt"}]`)))
+	assert.Nil(t, indexes.SaveIndex("godoc", "/go/pkg/mod go1.23.0", []byte(`[{"import_path": "sync"}]`)))
+	assert.Nil(t, indexes.SaveIndex("local", "/home/me/notes", []byte(`[]`)))
+
+	// Saving again replaces the index
+	data, saved, err = indexes.LoadIndex("godoc", "/go/pkg/mod go1.23.0")
+	assert.Nil(t, err)
+	assert.Equal(t, `[{"import_path": "sync"}]`, string(data))
+	assert.WithinDuration(t, time.Now(), saved, time.Minute)
+
+	// Another key is another index
+	data, _, err = indexes.LoadIndex("godoc", "/go/pkg/mod go1.24.0")
+	assert.Nil(t, err)
+	assert.Nil(t, data)
+}
@@ -0,0 +1,147 @@
+package database
+
+import (
+	"database/sql"
+	"fmt"
+	"strings"
+	"time"
+	"unicode"
+
+	"ask-web/pkg/search"
+)
+
+// The text of downloaded pages lives alongside the results table, eg
+// conversations_pages. How it's searched depends on whether SQLite was
+// built with FTS5 (see pages_fts5.go and pages_bm25.go).
+func pagesTable(dbTable string) string {
+	return dbTable + "_pages"
+}
+
+func pagesSchema(dbTable string) string {
+	return `
+	CREATE TABLE IF NOT EXISTS ` + pagesTable(dbTable) + ` (
+		id INTEGER PRIMARY KEY AUTOINCREMENT,
+		url TEXT NOT NULL UNIQUE,
+		title TEXT NOT NULL DEFAULT '',
+		content TEXT NOT NULL,
+		published INTEGER NOT NULL DEFAULT 0,
+		fetched INTEGER NOT NULL
+	);
+	`
+}
+
+// Words either side of the first match in a snippet
+const (
+	snippetBefore = 10
+	snippetAfter  = 40
+)
+
+// SavePage stores the text of a page for the history engine. published is
+// the zero time if it isn't known. Saving the same text again leaves the
+// page as it was, so reusing a page doesn't make it look freshly fetched.
+func (sqlDB *SearchDB) SavePage(url string, title string, content string, published time.Time) error {
+	var publishedUnix int64
+	if !published.IsZero() {
+		publishedUnix = published.Unix()
+	}
+
+	_, err := sqlDB.db.Exec(`
+		INSERT INTO `+pagesTable(sqlDB.dbTable)+` (url, title, content, published, fetched)
+		VALUES (?, ?, ?, ?, ?)
+		ON CONFLICT (url) DO UPDATE SET
+			title = excluded.title,
+			content = excluded.content,
+			published = excluded.published,
+			fetched = excluded.fetched
+		WHERE content != excluded.content;
+	`, url, title, content, publishedUnix, time.Now().Unix())
+	if err != nil {
+		return fmt.Errorf("error saving page: %v", err)
+	}
+
+	return nil
+}
+
+// pageIndex implements search.PageIndex on top of the database
+type pageIndex struct {
+	sqlDB *SearchDB
+}
+
+// PageIndex returns the searchable history of downloaded pages stored in
+// this database.
+func (sqlDB *SearchDB) PageIndex() search.PageIndex {
+	return &pageIndex{sqlDB: sqlDB}
+}
+
+func (p *pageIndex) SearchPages(query string, since time.Time, limit int, offset int) ([]search.SearchResult, error) {
+	var sinceUnix int64
+	if !since.IsZero() {
+		sinceUnix = since.Unix()
+	}
+
+	results, err := searchPages(p.sqlDB, query, sinceUnix, limit, offset)
+	if err != nil {
+		return nil, fmt.Errorf("error searching pages: %v", err)
+	}
+
+	return results, nil
+}
+
+// A page is as old as its publish date, if it has one, and otherwise as old
+// as when it was fetched
+const pageDate = `(CASE WHEN published > 0 THEN published ELSE fetched END)`
+
+// scanPages turns rows of url, title, content and published into results.
+func scanPages(rows *sql.Rows, query string) ([]search.SearchResult, error) {
+	var results []search.SearchResult
+	for rows.Next() {
+		var result search.SearchResult
+		var published int64
+		if err := rows.Scan(&result.URL, &result.Title, &result.Content, &published); err != nil {
+			return nil, err
+		}
+		if published > 0 {
+			result.Published = time.Unix(published, 0)
+		}
+		result.Snippet = pageSnippet(result.Content, query)
+		results = append(results, result)
+	}
+
+	return results, rows.Err()
+}
+
+func queryTerms(query string) []string {
+	return strings.FieldsFunc(strings.ToLower(query), func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsDigit(r)
+	})
+}
+
+// pageSnippet cuts the words around the first match of the query out of a
+// page's text.
+func pageSnippet(content string, query string) string {
+	terms := make(map[string]bool)
+	for _, term := range queryTerms(query) {
+		terms[term] = true
+	}
+
+	words := strings.Fields(content)
+	first := 0
+	for i, word := range words {
+		if matched := queryTerms(word); len(matched) > 0 && terms[matched[0]] {
+			first = i
+			break
+		}
+	}
+
+	from := max(first-snippetBefore, 0)
+	to := min(first+snippetAfter, len(words))
+	snippet := strings.Join(words[from:to], " ")
+	if from > 0 {
+		snippet = "..." + snippet
+	}
+	if to < len(words) {
+		snippet += "..."
+	}
+
+	return snippet
+}
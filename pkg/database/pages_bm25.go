@@ -0,0 +1,86 @@
+//go:build !sqlite_fts5
+
+package database
+
+import (
+	"database/sql"
+
+	"ask-web/pkg/search"
+)
+
+// Without FTS5 the pages are ranked in memory with search.BM25Index. They're
+// streamed into it a row at a time, keeping only the index, and just the
+// pages that are returned are read again in full. That's slower than FTS5
+// with a big history, but it's all of it.
+
+// initPageIndex drops the triggers that a build with FTS5 would have left
+// behind, since they can't run without it. That build rebuilds its index
+// when it finds them missing.
+func initPageIndex(db *sql.DB, dbTable string) error {
+	pages := pagesTable(dbTable)
+	_, err := db.Exec(`
+		DROP TRIGGER IF EXISTS ` + pages + `_ai;
+		DROP TRIGGER IF EXISTS ` + pages + `_ad;
+		DROP TRIGGER IF EXISTS ` + pages + `_au;
+	`)
+	return err
+}
+
+func searchPages(sqlDB *SearchDB, query string, since int64, limit int, offset int) ([]search.SearchResult, error) {
+	index, ids, err := indexPages(sqlDB, since)
+	if err != nil {
+		return nil, err
+	}
+
+	matches := index.Search(query)
+	if offset >= len(matches) {
+		return nil, nil
+	}
+
+	var results []search.SearchResult
+	for _, match := range matches[offset:min(offset+limit, len(matches))] {
+		rows, err := sqlDB.db.Query(`
+			SELECT url, title, content, published FROM `+pagesTable(sqlDB.dbTable)+` WHERE id = ?;
+		`, ids[match.Doc])
+		if err != nil {
+			return nil, err
+		}
+		pages, err := scanPages(rows, query)
+		rows.Close()
+		if err != nil {
+			return nil, err
+		}
+		results = append(results, pages...)
+	}
+
+	return results, nil
+}
+
+// indexPages indexes the pages dated since, and returns their IDs in the
+// order they were added to the index.
+func indexPages(sqlDB *SearchDB, since int64) (*search.BM25Index, []int64, error) {
+	rows, err := sqlDB.db.Query(`
+		SELECT id, title, content FROM `+pagesTable(sqlDB.dbTable)+`
+		WHERE `+pageDate+` >= ?;
+	`, since)
+	if err != nil {
+		return nil, nil, err
+	}
+	// There's only one connection, which the rows hold until they're closed
+	defer rows.Close()
+
+	index := search.NewBM25Index()
+	var ids []int64
+	for rows.Next() {
+		var id int64
+		var title, content string
+		if err := rows.Scan(&id, &title, &content); err != nil {
+			return nil, nil, err
+		}
+		// Titles count for more than the body
+		index.Add(title + " " + title + " " + content)
+		ids = append(ids, id)
+	}
+
+	return index, ids, rows.Err()
+}
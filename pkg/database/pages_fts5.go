@@ -0,0 +1,85 @@
+//go:build sqlite_fts5
+
+package database
+
+import (
+	"database/sql"
+	"strings"
+
+	"ask-web/pkg/search"
+)
+
+// The index is an FTS5 table over the pages table, kept up to date by
+// triggers
+func ftsTable(dbTable string) string {
+	return pagesTable(dbTable) + "_fts"
+}
+
+func ftsSchema(dbTable string) string {
+	pages, fts := pagesTable(dbTable), ftsTable(dbTable)
+	return `
+	CREATE VIRTUAL TABLE IF NOT EXISTS ` + fts + ` USING fts5(
+		title, content, content='` + pages + `', content_rowid='id'
+	);
+	CREATE TRIGGER IF NOT EXISTS ` + pages + `_ai AFTER INSERT ON ` + pages + ` BEGIN
+		INSERT INTO ` + fts + ` (rowid, title, content) VALUES (new.id, new.title, new.content);
+	END;
+	CREATE TRIGGER IF NOT EXISTS ` + pages + `_ad AFTER DELETE ON ` + pages + ` BEGIN
+		INSERT INTO ` + fts + ` (` + fts + `, rowid, title, content) VALUES ('delete', old.id, old.title, old.content);
+	END;
+	CREATE TRIGGER IF NOT EXISTS ` + pages + `_au AFTER UPDATE ON ` + pages + ` BEGIN
+		INSERT INTO ` + fts + ` (` + fts + `, rowid, title, content) VALUES ('delete', old.id, old.title, old.content);
+		INSERT INTO ` + fts + ` (rowid, title, content) VALUES (new.id, new.title, new.content);
+	END;
+	`
+}
+
+// initPageIndex creates the FTS5 index if it's missing. Pages saved without
+// it (by a build without FTS5, which drops the triggers) are indexed then.
+func initPageIndex(db *sql.DB, dbTable string) error {
+	var triggers int
+	err := db.QueryRow(`
+		SELECT count(*) FROM sqlite_master WHERE type = 'trigger' AND name = ?;
+	`, pagesTable(dbTable)+"_ai").Scan(&triggers)
+	if err != nil {
+		return err
+	}
+
+	if _, err := db.Exec(ftsSchema(dbTable)); err != nil {
+		return err
+	}
+
+	if triggers == 0 {
+		fts := ftsTable(dbTable)
+		_, err = db.Exec(`INSERT INTO ` + fts + ` (` + fts + `) VALUES ('rebuild');`)
+	}
+	return err
+}
+
+// Titles count for more than the body
+const ftsTitleWeight = 5.0
+
+func searchPages(sqlDB *SearchDB, query string, since int64, limit int, offset int) ([]search.SearchResult, error) {
+	// Any of the words, quoted so that none of them is taken for FTS5
+	// syntax; bm25 ranks pages with more of them higher
+	terms := queryTerms(query)
+	if len(terms) == 0 {
+		return nil, nil
+	}
+	match := `"` + strings.Join(terms, `" OR "`) + `"`
+
+	pages, fts := pagesTable(sqlDB.dbTable), ftsTable(sqlDB.dbTable)
+	rows, err := sqlDB.db.Query(`
+		SELECT p.url, p.title, p.content, p.published
+		FROM `+fts+` JOIN `+pages+` p ON p.id = `+fts+`.rowid
+		WHERE `+fts+` MATCH ? AND `+pageDate+` >= ?
+		ORDER BY bm25(`+fts+`, ?, 1.0)
+		LIMIT ? OFFSET ?;
+	`, match, since, ftsTitleWeight, limit, offset)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	return scanPages(rows, query)
+}
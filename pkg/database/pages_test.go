@@ -0,0 +1,112 @@
+package database
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSearchPages(t *testing.T) {
+	db, err := NewDB(dbPath, dbTable)
+	assert.Nil(t, err)
+	defer RemoveDB()
+	defer db.Close()
+
+	published := time.Date(2024, 8, 13, 0, 0, 0, 0, time.UTC)
+	assert.Nil(t, db.SavePage("https://go.dev/blog/range-functions", "Range Over Function Types in Go",
+		"Go 1.23 adds range over function types, which makes iterators easy to write.", published))
+	assert.Nil(t, db.SavePage("https://go.dev/doc/effective_go", "Effective Go",
+		"Tips for writing clear, idiomatic Go code, including loops and channels.", time.Time{}))
+	assert.Nil(t, db.SavePage("https://example.com/rust", "Learning Rust",
+		"Rust has had iterators for a long time.", time.Time{}))
+
+	pages := db.PageIndex()
+
+	results, err := pages.SearchPages("go iterators", time.Time{}, 10, 0)
+	assert.Nil(t, err)
+	if assert.Len(t, results, 3) {
+		// The only page with both words comes first
+		assert.Equal(t, "https://go.dev/blog/range-functions", results[0].URL)
+		assert.Equal(t, "Range Over Function Types in Go", results[0].Title)
+		assert.Contains(t, results[0].Content, "range over function types")
+		assert.Equal(t, published, results[0].Published.UTC())
+		assert.True(t, results[1].Published.IsZero())
+	}
+
+	results, err = pages.SearchPages("go iterators", time.Time{}, 1, 1)
+	assert.Nil(t, err)
+	assert.Len(t, results, 1)
+
+	results, err = pages.SearchPages("kubernetes", time.Time{}, 10, 0)
+	assert.Nil(t, err)
+	assert.Empty(t, results)
+
+	// The blog post was published long ago; the others were fetched just now
+	results, err = pages.SearchPages("go iterators", time.Now().Add(-time.Hour), 10, 0)
+	assert.Nil(t, err)
+	assert.Len(t, results, 2)
+	for _, result := range results {
+		assert.NotEqual(t, "https://go.dev/blog/range-functions", result.URL)
+	}
+
+	// Quotes and operators are just words
+	_, err = pages.SearchPages(`"go" AND iterators*`, time.Time{}, 10, 0)
+	assert.Nil(t, err)
+}
+
+func TestSavePage(t *testing.T) {
+	db, err := NewDB(dbPath, dbTable)
+	assert.Nil(t, err)
+	defer RemoveDB()
+	defer db.Close()
+
+	url := "https://go.dev/blog/range-functions"
+	fetched := func() int64 {
+		var fetched int64
+		err := db.db.QueryRow(`SELECT fetched FROM `+pagesTable(dbTable)+` WHERE url = ?`, url).Scan(&fetched)
+		assert.Nil(t, err)
+		return fetched
+	}
+	backdate := func() {
+		_, err := db.db.Exec(`UPDATE `+pagesTable(dbTable)+` SET fetched = ?`, time.Now().Add(-48*time.Hour).Unix())
+		assert.Nil(t, err)
+	}
+
+	assert.Nil(t, db.SavePage(url, "Range Over Function Types", "first draft", time.Time{}))
+	backdate()
+	old := fetched()
+
+	// The same text again isn't a new fetch
+	assert.Nil(t, db.SavePage(url, "Range Over Function Types", "first draft", time.Time{}))
+	assert.Equal(t, old, fetched())
+
+	// New text replaces the old, in the index too
+	assert.Nil(t, db.SavePage(url, "Range Over Function Types", "second draft", time.Time{}))
+	assert.Greater(t, fetched(), old)
+
+	pages := db.PageIndex()
+	results, err := pages.SearchPages("first", time.Time{}, 10, 0)
+	assert.Nil(t, err)
+	assert.Empty(t, results)
+	results, err = pages.SearchPages("second", time.Time{}, 10, 0)
+	assert.Nil(t, err)
+	assert.Len(t, results, 1)
+}
+
+func TestPageSnippet(t *testing.T) {
+	words := make([]string, 100)
+	for i := range words {
+		words[i] = "filler"
+	}
+	words[50] = "Iterators,"
+
+	snippet := pageSnippet(strings.Join(words, " "), "go iterators")
+	assert.Contains(t, snippet, "...filler")
+	assert.Contains(t, snippet, "Iterators,")
+	assert.Len(t, strings.Fields(snippet), snippetBefore+snippetAfter)
+
+	// No match means the start of the page
+	assert.Equal(t, "short page", pageSnippet("short page", "kubernetes"))
+}
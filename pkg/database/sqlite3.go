@@ -2,7 +2,7 @@ package database
 
 // Use this module like this:
 // db := NewDB("path/to/database.db")
-// db.SaveSearchResults("query", searchResults, "summary")
+// db.SaveSearchResults("query", []string{"query"}, searchResults, "summary")
 
 import (
 	"database/sql"
@@ -10,6 +10,7 @@ import (
 	"fmt"
 	"log"
 	"os"
+	"strings"
 
 	"ask-web/pkg/search"
 	_ "github.com/mattn/go-sqlite3"
@@ -23,6 +24,16 @@ type SearchRow struct {
 type ResultRow struct {
 	Query   string
 	Summary string
+	Queries []string
+	Sources []Source
+}
+
+// Source is why a result ended up in the summary: which engines returned it
+// for which of the queries
+type Source struct {
+	URL     string   `json:"url"`
+	Engines []string `json:"engines"`
+	Queries []string `json:"queries"`
 }
 
 type SearchDB struct {
@@ -42,43 +53,70 @@ func NewDB(dbPath string, dbTable string) (*SearchDB, error) {
 		file.Close()
 	}
 
-	db, err := sql.Open("sqlite3", dbPath)
+	// Other ask-web runs may be updating engine usage at the same time, so
+	// wait for their locks rather than failing straight away
+	db, err := sql.Open("sqlite3", dbPath+"?_busy_timeout=5000")
 	if err != nil {
 		return nil, fmt.Errorf("%v", err)
 	}
+	// The engines write to the cache concurrently; SQLite only has one
+	// writer anyway, and this way they queue up instead of getting SQLITE_BUSY
+	db.SetMaxOpenConns(1)
 
 	_, err = db.Exec(DBSchema(dbTable))
 	if err != nil {
 		return nil, fmt.Errorf("error creating %s table: %v", dbTable, err)
 	}
 
+	err = initPageIndex(db, dbTable)
+	if err != nil {
+		return nil, fmt.Errorf("error creating page index: %v", err)
+	}
+
 	sqlDB := SearchDB{}
 	sqlDB.db = db
 	sqlDB.dbTable = dbTable
 	return &sqlDB, nil
 }
 
-func (sqlDB *SearchDB) SaveSearchResults(query string, results []search.SearchResult, summary string) error {
+func (sqlDB *SearchDB) SaveSearchResults(query string, queries []string, results []search.SearchResult, summary string) error {
 	// extract URLs from search results
 	var urls []string
+	sources := make([]Source, 0, len(results))
 	for _, result := range results {
 		urls = append(urls, result.URL)
+		sources = append(sources, Source{
+			URL:     result.URL,
+			Engines: result.Engines,
+			Queries: result.Queries,
+		})
 	}
 	urlsJSON, err := json.Marshal(urls)
 	if err != nil {
 		panic(err)
 	}
+	if queries == nil {
+		queries = []string{}
+	}
+	queriesJSON, err := json.Marshal(queries)
+	if err != nil {
+		panic(err)
+	}
+	sourcesJSON, err := json.Marshal(sources)
+	if err != nil {
+		panic(err)
+	}
 
 	stmt, err := sqlDB.db.Prepare(`
-	INSERT INTO ` + sqlDB.dbTable + `(query, results, summary)
-	VALUES(?, ?, ?)
+	INSERT INTO ` + sqlDB.dbTable + `(query, results, summary, queries, sources)
+	VALUES(?, ?, ?, ?, ?)
 	`)
 	if err != nil {
 		panic(err)
 	}
 	defer stmt.Close()
 
-	_, err = stmt.Exec(query, urlsJSON, summary)
+	_, err = stmt.Exec(query, urlsJSON, summary, queriesJSON, sourcesJSON)
 	if err != nil {
 		panic(err)
 	}
@@ -114,7 +152,7 @@ func (sqlDB *SearchDB) SearchForResult(keyword string) ([]SearchRow, error) {
 
 func (sqlDB *SearchDB) ReturnSearchResult(sumID int) *ResultRow {
 	rows, err := sqlDB.db.Query(`
-		SELECT query, summary FROM `+sqlDB.dbTable+` WHERE id = ?;
+		SELECT query, summary, queries, sources FROM `+sqlDB.dbTable+` WHERE id = ?;
 	`, sumID)
 	if err != nil {
 		log.Fatalf("error showing conversation: %v", err)
@@ -123,10 +161,14 @@ func (sqlDB *SearchDB) ReturnSearchResult(sumID int) *ResultRow {
 
 	var row ResultRow
 	for rows.Next() {
-		err := rows.Scan(&row.Query, &row.Summary)
+		var queriesJSON, sourcesJSON string
+		err := rows.Scan(&row.Query, &row.Summary, &queriesJSON, &sourcesJSON)
 		if err != nil {
 			log.Fatalf("error showing conversation: %v", err)
 		}
+		// Rows from before the queries were recorded just have empty lists
+		json.Unmarshal([]byte(queriesJSON), &row.Queries)
+		json.Unmarshal([]byte(sourcesJSON), &row.Sources)
 
 		return &row
 	}
@@ -137,6 +179,13 @@ func (sqlDB *SearchDB) ReturnSearchResult(sumID int) *ResultRow {
 func (sqlDB *SearchDB) ShowSearchResult(sumID int) {
 	result := sqlDB.ReturnSearchResult(sumID)
 	fmt.Printf("Prompt: %s\n", result.Query)
+	if len(result.Queries) > 0 {
+		fmt.Printf("Queries: %s\n", strings.Join(result.Queries, " | "))
+	}
+	for _, source := range result.Sources {
+		fmt.Printf("Source: %s (engines: %s; queries: %s)\n", source.URL,
+			strings.Join(source.Engines, ", "), strings.Join(source.Queries, " | "))
+	}
 	fmt.Printf("Summary: %s\n", result.Summary)
 }
 
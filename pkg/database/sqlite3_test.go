@@ -1,6 +1,7 @@
 package database
 
 import (
+	"database/sql"
 	"os"
 	"testing"
 
@@ -40,9 +41,15 @@ func TestSaveSearchResults(t *testing.T) {
 		Snippet: "snippet",
 	})
 
-	err = db.SaveSearchResults("query", results, "summary")
+	err = db.SaveSearchResults("query", []string{"query", "another query"}, results, "summary")
 	assert.Nil(t, err)
 
+	row := db.ReturnSearchResult(1)
+	assert.NotNil(t, row)
+	assert.Equal(t, []string{"query", "another query"}, row.Queries)
+	assert.Equal(t, 1, len(row.Sources))
+	assert.Equal(t, "url", row.Sources[0].URL)
+
 	db.Close()
 	RemoveDB()
 }
@@ -74,6 +81,44 @@ func TestInsertConversationWithError(t *testing.T) {
 	RemoveDB()
 }
 
+func TestMigrateToV4(t *testing.T) {
+	// A database from before the queries and sources columns existed
+	db, err := sql.Open("sqlite3", dbPath)
+	assert.Nil(t, err)
+	_, err = db.Exec(`
+	CREATE TABLE ` + dbTable + ` (
+		id INTEGER PRIMARY KEY AUTOINCREMENT,
+		timestamp DATETIME DEFAULT CURRENT_TIMESTAMP,
+		query TEXT NOT NULL,
+		results TEXT NOT NULL,
+		summary TEXT NOT NULL
+	);
+	INSERT INTO ` + dbTable + `(query, results, summary) VALUES('old', '[]', 'old summary');
+	PRAGMA user_version = 3;
+	`)
+	assert.Nil(t, err)
+	db.Close()
+
+	searchDB, err := InitializeDB(dbPath, dbTable)
+	assert.Nil(t, err)
+
+	row := searchDB.ReturnSearchResult(1)
+	assert.NotNil(t, row)
+	assert.Equal(t, "old summary", row.Summary)
+	assert.Empty(t, row.Queries)
+
+	err = searchDB.SaveSearchResults("new", []string{"new"}, nil, "new summary")
+	assert.Nil(t, err)
+
+	var version int
+	err = searchDB.db.QueryRow("PRAGMA user_version").Scan(&version)
+	assert.Nil(t, err)
+	assert.Equal(t, SchemaVersion, version)
+
+	searchDB.Close()
+	RemoveDB()
+}
+
 func RemoveDB() {
 	os.Remove(dbPath)
 }
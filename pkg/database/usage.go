@@ -0,0 +1,76 @@
+package database
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"math"
+
+	"ask-web/pkg/search"
+)
+
+// Engine usage lives alongside the results table, eg conversations_usage
+func usageTable(dbTable string) string {
+	return dbTable + "_usage"
+}
+
+func usageSchema(dbTable string) string {
+	return `
+	CREATE TABLE IF NOT EXISTS ` + usageTable(dbTable) + ` (
+		engine TEXT NOT NULL,
+		day TEXT NOT NULL,
+		requests INTEGER NOT NULL DEFAULT 0,
+		PRIMARY KEY (engine, day)
+	);
+	`
+}
+
+// usageTracker implements search.UsageTracker on top of the database
+type usageTracker struct {
+	sqlDB *SearchDB
+}
+
+// UsageTracker returns the per-engine request counts stored in this database.
+func (sqlDB *SearchDB) UsageTracker() search.UsageTracker {
+	return &usageTracker{sqlDB: sqlDB}
+}
+
+// Use counts the request in a single statement, so concurrent runs can't both
+// take the last one.
+func (u *usageTracker) Use(engine string, day string, limit int) (bool, error) {
+	if limit <= 0 {
+		limit = math.MaxInt32
+	}
+
+	res, err := u.sqlDB.db.Exec(`
+		INSERT INTO `+usageTable(u.sqlDB.dbTable)+` (engine, day, requests)
+		SELECT ?, ?, 1 WHERE ? > 0
+		ON CONFLICT (engine, day) DO UPDATE SET requests = requests + 1
+		WHERE requests < ?;
+	`, engine, day, limit, limit)
+	if err != nil {
+		return false, fmt.Errorf("error recording usage: %v", err)
+	}
+
+	n, err := res.RowsAffected()
+	if err != nil {
+		return false, fmt.Errorf("error recording usage: %v", err)
+	}
+
+	return n > 0, nil
+}
+
+func (u *usageTracker) Usage(engine string, day string) (int, error) {
+	var requests int
+	err := u.sqlDB.db.QueryRow(`
+		SELECT requests FROM `+usageTable(u.sqlDB.dbTable)+` WHERE engine = ? AND day = ?;
+	`, engine, day).Scan(&requests)
+	if errors.Is(err, sql.ErrNoRows) {
+		return 0, nil
+	}
+	if err != nil {
+		return 0, fmt.Errorf("error reading usage: %v", err)
+	}
+
+	return requests, nil
+}
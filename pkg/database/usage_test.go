@@ -0,0 +1,57 @@
+package database
+
+import (
+	"sync"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestUsageTracker(t *testing.T) {
+	db, err := NewDB(dbPath, dbTable)
+	assert.Nil(t, err)
+	defer RemoveDB()
+	defer db.Close()
+
+	usage := db.UsageTracker()
+
+	used, err := usage.Usage("google", "2024-06-15")
+	assert.Nil(t, err)
+	assert.Equal(t, 0, used)
+
+	// Lots of requests at once shouldn't go over the limit
+	var wg sync.WaitGroup
+	var mu sync.Mutex
+	allowed := 0
+	for i := 0; i < 10; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			ok, err := usage.Use("google", "2024-06-15", 3)
+			assert.Nil(t, err)
+			if ok {
+				mu.Lock()
+				allowed++
+				mu.Unlock()
+			}
+		}()
+	}
+	wg.Wait()
+	assert.Equal(t, 3, allowed)
+
+	used, err = usage.Usage("google", "2024-06-15")
+	assert.Nil(t, err)
+	assert.Equal(t, 3, used)
+
+	// A new day starts again, and no limit means no limit
+	ok, err := usage.Use("google", "2024-06-16", 3)
+	assert.Nil(t, err)
+	assert.True(t, ok)
+	for i := 0; i < 5; i++ {
+		ok, err = usage.Use("duckduckgo", "2024-06-15", 0)
+		assert.Nil(t, err)
+		assert.True(t, ok)
+	}
+	used, _ = usage.Usage("duckduckgo", "2024-06-15")
+	assert.Equal(t, 5, used)
+}
@@ -4,6 +4,11 @@ import (
 	"fmt"
 	"io"
 	"net/http"
+	"net/url"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
 )
 
 type DownloadError struct {
@@ -15,7 +20,15 @@ func (e *DownloadError) Error() string {
 	return fmt.Sprintf("%s (%d)", e.Message, e.StatusCode)
 }
 
+// The search engine whose results are local files
+const localEngine = "local"
+
+// Page downloads a web page. It doesn't read file:// URLs; see ResultPage.
 func Page(url string) (string, error) {
+	if IsFileURL(url) {
+		return "", fmt.Errorf("not reading %s: local files are only read for the local engine", url)
+	}
+
 	resp, err := http.Get(url)
 	if err != nil {
 		return "", err
@@ -36,3 +49,78 @@ func Page(url string) (string, error) {
 
 	return string(body), nil
 }
+
+// ResultPage gets the text of a search result's page, given the engines that
+// returned it. Web pages are downloaded. A file:// URL is only read if the
+// local engine returned it, and only from under one of localDirs, so that no
+// other engine can get a file off the disk and sent to the summarizer.
+func ResultPage(pageURL string, engines []string, localDirs []string) (string, error) {
+	if !IsFileURL(pageURL) {
+		return Page(pageURL)
+	}
+	if !slices.Contains(engines, localEngine) {
+		return "", fmt.Errorf("not reading %s: it didn't come from the local engine", pageURL)
+	}
+
+	return readFile(pageURL, localDirs)
+}
+
+// IsPlainText reports whether the page at url is text rather than HTML, and
+// so shouldn't be cleaned up as if it were. Only local files (from the local
+// engine) can be; anything from the web is assumed to be HTML.
+func IsPlainText(url string) bool {
+	if !IsFileURL(url) {
+		return false
+	}
+	ext := strings.ToLower(filepath.Ext(url))
+	return ext != ".html" && ext != ".htm"
+}
+
+func IsFileURL(url string) bool {
+	return strings.HasPrefix(url, "file://")
+}
+
+// readFile reads the file at fileURL if it's in one of dirs, once symlinks
+// and any ..s are resolved.
+func readFile(fileURL string, dirs []string) (string, error) {
+	u, err := url.Parse(fileURL)
+	if err != nil {
+		return "", err
+	}
+	if u.Host != "" && u.Host != "localhost" {
+		return "", fmt.Errorf("can't read files from %s", u.Host)
+	}
+
+	path, err := filepath.EvalSymlinks(u.Path)
+	if err != nil {
+		return "", err
+	}
+	if !inDirs(path, dirs) {
+		return "", fmt.Errorf("not reading %s: it isn't in any of the local directories", path)
+	}
+
+	body, err := os.ReadFile(path)
+	if err != nil {
+		return "", err
+	}
+
+	return string(body), nil
+}
+
+func inDirs(path string, dirs []string) bool {
+	for _, dir := range dirs {
+		dir, err := filepath.Abs(dir)
+		if err != nil {
+			continue
+		}
+		if resolved, err := filepath.EvalSymlinks(dir); err == nil {
+			dir = resolved
+		}
+
+		rel, err := filepath.Rel(dir, path)
+		if err == nil && rel != ".." && !strings.HasPrefix(rel, ".."+string(filepath.Separator)) {
+			return true
+		}
+	}
+	return false
+}
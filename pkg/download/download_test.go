@@ -6,6 +6,9 @@ import (
 	"io"
 	"net/http"
 	"net/http/httptest"
+	"net/url"
+	"os"
+	"path/filepath"
 	"strings"
 	"testing"
 )
@@ -64,6 +67,13 @@ func TestPage(t *testing.T) {
 		}
 	})
 
+	t.Run("local file", func(t *testing.T) {
+		// Only through ResultPage
+		if _, err := Page("file:///etc/passwd"); err == nil {
+			t.Error("expected an error for a file URL")
+		}
+	})
+
 	t.Run("read error", func(t *testing.T) {
 		testServer := httptest.NewServer(http.HandlerFunc(func(w http.
 			ResponseWriter, r *http.Request) {
@@ -95,3 +105,64 @@ func TestPage(t *testing.T) {
 		resp.Body = originalBody
 	})
 }
+
+func TestResultPage(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "notes.md")
+	if err := os.WriteFile(path, []byte("# Notes\n\nfoo < bar"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	local := []string{"local"}
+
+	content, err := ResultPage((&url.URL{Scheme: "file", Path: path}).String(), local, []string{dir})
+	if err != nil {
+		t.Fatalf("expected no error, got: %v", err)
+	}
+	if content != "# Notes\n\nfoo < bar" {
+		t.Errorf("expected the file's content, got: %q", content)
+	}
+
+	if _, err := ResultPage("file://"+filepath.Join(dir, "missing.md"), local, []string{dir}); !errors.Is(err, os.ErrNotExist) {
+		t.Errorf("expected os.ErrNotExist, got: %v", err)
+	}
+	if _, err := ResultPage("file://elsewhere/etc/hosts", local, []string{dir}); err == nil {
+		t.Error("expected an error for a file on another host")
+	}
+
+	// Any other engine could have been tricked into returning a file URL
+	if _, err := ResultPage("file:///etc/passwd", []string{"duckduckgo"}, []string{"/"}); err == nil {
+		t.Error("expected an error for a file URL from another engine")
+	}
+
+	// Even the local engine's files have to be in its directories, however
+	// the path gets out of them
+	for _, escape := range []string{"file:///etc/passwd", "file://" + dir + "/../../../../etc/passwd"} {
+		if _, err := ResultPage(escape, local, []string{dir}); err == nil {
+			t.Errorf("expected an error for %s outside %s", escape, dir)
+		}
+	}
+	link := filepath.Join(dir, "passwd.md")
+	if err := os.Symlink("/etc/passwd", link); err == nil {
+		if _, err := ResultPage("file://"+link, local, []string{dir}); err == nil {
+			t.Error("expected an error for a symlink out of the directory")
+		}
+	}
+}
+
+func TestIsPlainText(t *testing.T) {
+	testCases := []struct {
+		url      string
+		expected bool
+	}{
+		{"https://example.com/notes.md", false},
+		{"file:///home/me/notes/todo.md", true},
+		{"file:///home/me/src/main.go", true},
+		{"file:///home/me/docs/index.HTML", false},
+	}
+
+	for _, tc := range testCases {
+		if got := IsPlainText(tc.url); got != tc.expected {
+			t.Errorf("IsPlainText(%q) = %v; want %v", tc.url, got, tc.expected)
+		}
+	}
+}
@@ -0,0 +1,82 @@
+package download
+
+import (
+	"regexp"
+	"strings"
+	"time"
+
+	"github.com/PuerkitoBio/goquery"
+)
+
+// Where pages commonly put their publication date, most reliable first
+var publishedSelectors = []struct {
+	selector string
+	attr     string
+}{
+	{`meta[property="article:published_time"]`, "content"},
+	{`meta[property="og:published_time"]`, "content"},
+	{`meta[itemprop="datePublished"]`, "content"},
+	{`meta[name="date"]`, "content"},
+	{`meta[name="pubdate"]`, "content"},
+	{`meta[name="publish-date"]`, "content"},
+	{`meta[name="DC.date.issued"]`, "content"},
+	{`meta[name="dcterms.created"]`, "content"},
+	{`time[itemprop="datePublished"]`, "datetime"},
+	{`article time[datetime]`, "datetime"},
+}
+
+var jsonLDPublished = regexp.MustCompile(`"datePublished"\s*:\s*"([^"]+)"`)
+
+var dateLayouts = []string{
+	time.RFC3339,
+	"2006-01-02T15:04:05Z0700",
+	"2006-01-02T15:04:05",
+	"2006-01-02T15:04",
+	time.DateOnly,
+	time.RFC1123Z,
+	time.RFC1123,
+	"January 2, 2006",
+	"Jan 2, 2006",
+	"2006/01/02",
+}
+
+// PublishedDate looks for the date an HTML page says it was published, in the
+// usual meta tags and JSON-LD. The second return value is false if the page
+// doesn't say or the date can't be parsed.
+func PublishedDate(html string) (time.Time, bool) {
+	doc, err := goquery.NewDocumentFromReader(strings.NewReader(html))
+	if err != nil {
+		return time.Time{}, false
+	}
+
+	for _, s := range publishedSelectors {
+		if value, ok := doc.Find(s.selector).First().Attr(s.attr); ok {
+			if t, ok := parseDate(value); ok {
+				return t, true
+			}
+		}
+	}
+
+	var published time.Time
+	doc.Find(`script[type="application/ld+json"]`).EachWithBreak(func(i int, s *goquery.Selection) bool {
+		if m := jsonLDPublished.FindStringSubmatch(s.Text()); m != nil {
+			if t, ok := parseDate(m[1]); ok {
+				published = t
+				return false
+			}
+		}
+		return true
+	})
+
+	return published, !published.IsZero()
+}
+
+func parseDate(value string) (time.Time, bool) {
+	value = strings.TrimSpace(value)
+	for _, layout := range dateLayouts {
+		if t, err := time.Parse(layout, value); err == nil {
+			return t, true
+		}
+	}
+	return time.Time{}, false
+}
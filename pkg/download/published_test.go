@@ -0,0 +1,63 @@
+package download
+
+import (
+	"testing"
+	"time"
+)
+
+func TestPublishedDate(t *testing.T) {
+	testCases := []struct {
+		name     string
+		html     string
+		expected string
+		found    bool
+	}{
+		{
+			name:     "Open Graph",
+			html:     `<html><head><meta property="article:published_time" content="2023-04-05T10:00:00+02:00"></head></html>`,
+			expected: "2023-04-05",
+			found:    true,
+		},
+		{
+			name:     "Meta date",
+			html:     `<html><head><meta name="date" content="2021-11-30"></head></html>`,
+			expected: "2021-11-30",
+			found:    true,
+		},
+		{
+			name:     "JSON-LD",
+			html:     `<html><head><script type="application/ld+json">{"@type": "NewsArticle", "datePublished": "2022-01-15T08:30:00Z"}</script></head></html>`,
+			expected: "2022-01-15",
+			found:    true,
+		},
+		{
+			name:     "Time element",
+			html:     `<html><body><article><time datetime="2020-07-04">July 4</time><p>Text</p></article></body></html>`,
+			expected: "2020-07-04",
+			found:    true,
+		},
+		{
+			name:     "Unparseable date is skipped",
+			html:     `<html><head><meta name="date" content="last Tuesday"><meta name="pubdate" content="March 3, 2019"></head></html>`,
+			expected: "2019-03-03",
+			found:    true,
+		},
+		{
+			name:  "No date",
+			html:  `<html><body><p>Hello</p></body></html>`,
+			found: false,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			published, found := PublishedDate(tc.html)
+			if found != tc.found {
+				t.Fatalf("Expected found=%v, got %v", tc.found, found)
+			}
+			if found && published.Format(time.DateOnly) != tc.expected {
+				t.Errorf("Expected %s, got %s", tc.expected, published.Format(time.DateOnly))
+			}
+		})
+	}
+}
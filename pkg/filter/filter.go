@@ -0,0 +1,298 @@
+package filter
+
+// Rules are written as "[allow:|deny:][scraped:]<kind>:<pattern>", eg:
+//
+//	x.com                     deny x.com and its subdomains (domain is the default kind)
+//	glob:*.pinterest.*        deny hosts (or host/path) matching the glob
+//	regex:medium\.com/@       deny URLs (without the scheme) matching the regex
+//	title:sponsored           deny results with "sponsored" in the title
+//	snippet:buy now           deny results with "buy now" in the snippet
+//	text:coupon               deny results with "coupon" in the title or snippet
+//	allow:domain:go.dev       only keep results from go.dev
+//	scraped:wikipedia.org     deny wikipedia.org, unless the engine gave us the text
+//
+// If there are any allow rules, a result has to match at least one of them.
+// Deny rules are checked after that.
+//
+// scraped: rules only match results whose pages would have to be downloaded
+// and cleaned up, not ones that come with their text (see
+// search.SearchResult.Content). The rules in the config file's filter list
+// are all scraped: rules, since they're there to keep out pages that scrape
+// badly.
+
+import (
+	"fmt"
+	"net/url"
+	"regexp"
+	"strings"
+	"sync"
+
+	"ask-web/pkg/search"
+)
+
+type Kind string
+
+const (
+	KindDomain  Kind = "domain"
+	KindGlob    Kind = "glob"
+	KindRegex   Kind = "regex"
+	KindTitle   Kind = "title"
+	KindSnippet Kind = "snippet"
+	KindText    Kind = "text"
+)
+
+type Rule struct {
+	Kind    Kind
+	Pattern string
+	Allow   bool
+	// ScrapedOnly rules ignore results that come with their text
+	ScrapedOnly bool
+
+	re *regexp.Regexp
+}
+
+// ParseRule turns the text form of a rule (see the top of this file) into a
+// Rule. Anything without a recognized kind is treated as a domain.
+func ParseRule(text string) (Rule, error) {
+	text = strings.TrimSpace(text)
+	rule := Rule{}
+
+	if rest, ok := strings.CutPrefix(text, "scraped:"); ok {
+		rule.ScrapedOnly = true
+		text = rest
+	}
+	if rest, ok := strings.CutPrefix(text, "allow:"); ok {
+		rule.Allow = true
+		text = rest
+	} else if rest, ok := strings.CutPrefix(text, "deny:"); ok {
+		text = rest
+	}
+	// Either order will do
+	if rest, ok := strings.CutPrefix(text, "scraped:"); ok {
+		rule.ScrapedOnly = true
+		text = rest
+	}
+
+	rule.Kind = KindDomain
+	rule.Pattern = text
+	if kind, pattern, ok := strings.Cut(text, ":"); ok {
+		switch Kind(strings.ToLower(kind)) {
+		case KindDomain, KindGlob, KindRegex, KindTitle, KindSnippet, KindText:
+			rule.Kind = Kind(strings.ToLower(kind))
+			rule.Pattern = pattern
+		}
+	}
+
+	if rule.Pattern == "" {
+		return Rule{}, fmt.Errorf("empty filter rule %q", text)
+	}
+
+	var err error
+	switch rule.Kind {
+	case KindDomain:
+		rule.Pattern = strings.TrimPrefix(strings.ToLower(rule.Pattern), ".")
+	case KindGlob:
+		rule.re, err = globToRegexp(strings.ToLower(rule.Pattern))
+	case KindRegex:
+		rule.re, err = regexp.Compile(rule.Pattern)
+	case KindTitle, KindSnippet, KindText:
+		rule.Pattern = strings.ToLower(rule.Pattern)
+	}
+	if err != nil {
+		return Rule{}, fmt.Errorf("invalid filter rule %q: %w", text, err)
+	}
+
+	return rule, nil
+}
+
+func (r Rule) String() string {
+	s := string(r.Kind) + ":" + r.Pattern
+	if r.ScrapedOnly {
+		s = "scraped:" + s
+	}
+	if r.Allow {
+		s = "allow:" + s
+	}
+	return s
+}
+
+// Matches reports whether the rule's pattern matches the result, regardless of
+// whether it's an allow or deny rule.
+func (r Rule) Matches(result search.SearchResult) bool {
+	if r.ScrapedOnly && result.Content != "" {
+		return false
+	}
+
+	switch r.Kind {
+	case KindDomain:
+		host := hostOf(result.URL)
+		return host == r.Pattern || strings.HasSuffix(host, "."+r.Pattern)
+	case KindGlob:
+		host := hostOf(result.URL)
+		return r.re.MatchString(host) || r.re.MatchString(withoutScheme(result.URL))
+	case KindRegex:
+		return r.re.MatchString(withoutScheme(result.URL))
+	case KindTitle:
+		return strings.Contains(strings.ToLower(result.Title), r.Pattern)
+	case KindSnippet:
+		return strings.Contains(strings.ToLower(result.Snippet), r.Pattern)
+	case KindText:
+		return strings.Contains(strings.ToLower(result.Title), r.Pattern) ||
+			strings.Contains(strings.ToLower(result.Snippet), r.Pattern)
+	}
+
+	return false
+}
+
+// Drop records a result that was filtered out and why.
+type Drop struct {
+	Result search.SearchResult
+	Reason string
+}
+
+// Set is a collection of rules. It's safe to use from several engines at once
+// and keeps track of everything it has dropped.
+type Set struct {
+	allow []Rule
+	deny  []Rule
+
+	mu      sync.Mutex
+	dropped []Drop
+	seen    map[string]bool
+}
+
+func New(rules ...Rule) *Set {
+	s := &Set{seen: make(map[string]bool)}
+	for _, rule := range rules {
+		if rule.Allow {
+			s.allow = append(s.allow, rule)
+		} else {
+			s.deny = append(s.deny, rule)
+		}
+	}
+
+	return s
+}
+
+// Parse builds a Set from the text form of deny and allow rules. Rules in
+// allow don't need the "allow:" prefix.
+func Parse(deny []string, allow []string) (*Set, error) {
+	var rules []Rule
+	for _, text := range deny {
+		rule, err := ParseRule(text)
+		if err != nil {
+			return nil, err
+		}
+		rules = append(rules, rule)
+	}
+	for _, text := range allow {
+		rule, err := ParseRule(text)
+		if err != nil {
+			return nil, err
+		}
+		rule.Allow = true
+		rules = append(rules, rule)
+	}
+
+	return New(rules...), nil
+}
+
+// Check reports whether the result should be kept and, if not, why.
+func (s *Set) Check(result search.SearchResult) (bool, string) {
+	if len(s.allow) > 0 {
+		allowed := false
+		for _, rule := range s.allow {
+			if rule.Matches(result) {
+				allowed = true
+				break
+			}
+		}
+		if !allowed {
+			return false, "not matched by any allow rule"
+		}
+	}
+
+	for _, rule := range s.deny {
+		if rule.Matches(result) {
+			return false, "denied by " + rule.String()
+		}
+	}
+
+	return true, ""
+}
+
+// Filter returns a search.FilterFunc for the set that records what it drops.
+func (s *Set) Filter() search.FilterFunc {
+	return func(result search.SearchResult) bool {
+		ok, reason := s.Check(result)
+		if !ok {
+			s.mu.Lock()
+			// Engines that page can see the same result more than once
+			if !s.seen[result.URL] {
+				s.seen[result.URL] = true
+				s.dropped = append(s.dropped, Drop{Result: result, Reason: reason})
+			}
+			s.mu.Unlock()
+		}
+		return ok
+	}
+}
+
+// Dropped returns everything the set's Filter has dropped so far.
+func (s *Set) Dropped() []Drop {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	return append([]Drop(nil), s.dropped...)
+}
+
+// String returns all the rules in a stable, readable form.
+func (s *Set) String() string {
+	var parts []string
+	for _, rule := range s.allow {
+		parts = append(parts, rule.String())
+	}
+	for _, rule := range s.deny {
+		parts = append(parts, rule.String())
+	}
+
+	return strings.Join(parts, " ")
+}
+
+func hostOf(rawURL string) string {
+	u, err := url.Parse(rawURL)
+	if err != nil || u.Host == "" {
+		// Probably missing a scheme
+		u, err = url.Parse("//" + rawURL)
+		if err != nil {
+			return ""
+		}
+	}
+
+	return strings.ToLower(u.Hostname())
+}
+
+func withoutScheme(rawURL string) string {
+	if _, rest, ok := strings.Cut(rawURL, "://"); ok {
+		return rest
+	}
+	return rawURL
+}
+
+func globToRegexp(glob string) (*regexp.Regexp, error) {
+	var b strings.Builder
+	b.WriteString("^")
+	for _, r := range glob {
+		switch r {
+		case '*':
+			b.WriteString(".*")
+		case '?':
+			b.WriteString(".")
+		default:
+			b.WriteString(regexp.QuoteMeta(string(r)))
+		}
+	}
+	b.WriteString("$")
+
+	return regexp.Compile(b.String())
+}
@@ -0,0 +1,134 @@
+package filter
+
+import (
+	"testing"
+
+	"ask-web/pkg/search"
+)
+
+func TestParseRule(t *testing.T) {
+	testCases := []struct {
+		input    string
+		expected string
+		wantErr  bool
+	}{
+		{"x.com", "domain:x.com", false},
+		{".X.com", "domain:x.com", false},
+		{"domain:x.com", "domain:x.com", false},
+		{"allow:go.dev", "allow:domain:go.dev", false},
+		{"deny:glob:*.pinterest.*", "glob:*.pinterest.*", false},
+		{"regex:medium\\.com/@", "regex:medium\\.com/@", false},
+		{"title:Sponsored", "title:sponsored", false},
+		{"scraped:wikipedia.org", "scraped:domain:wikipedia.org", false},
+		{"deny:scraped:wikipedia.org", "scraped:domain:wikipedia.org", false},
+		{"scraped:allow:go.dev", "allow:scraped:domain:go.dev", false},
+		{"regex:(", "", true},
+		{"glob:", "", true},
+		{"", "", true},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.input, func(t *testing.T) {
+			rule, err := ParseRule(tc.input)
+			if (err != nil) != tc.wantErr {
+				t.Fatalf("ParseRule(%q) error = %v, wantErr %v", tc.input, err, tc.wantErr)
+			}
+			if !tc.wantErr && rule.String() != tc.expected {
+				t.Errorf("ParseRule(%q) = %q; want %q", tc.input, rule.String(), tc.expected)
+			}
+		})
+	}
+}
+
+func TestRuleMatches(t *testing.T) {
+	testCases := []struct {
+		name     string
+		rule     string
+		result   search.SearchResult
+		expected bool
+	}{
+		{"Domain", "x.com", search.SearchResult{URL: "https://x.com/status/1"}, true},
+		{"Subdomain", "x.com", search.SearchResult{URL: "https://mobile.x.com/status/1"}, true},
+		{"Not a suffix match", "x.com", search.SearchResult{URL: "https://max.com/shows"}, false},
+		{"Not in the path", "x.com", search.SearchResult{URL: "https://example.org/x.com"}, false},
+		{"No scheme", "x.com", search.SearchResult{URL: "x.com/status/1"}, true},
+		{"Glob host", "glob:*.pinterest.*", search.SearchResult{URL: "https://www.pinterest.co.uk/pin/1"}, true},
+		{"Glob path", "glob:github.com/*/issues/*", search.SearchResult{URL: "https://github.com/golang/go/issues/1"}, true},
+		{"Glob no match", "glob:*.pinterest.*", search.SearchResult{URL: "https://pinterest.com/pin/1"}, false},
+		{"Regex", "regex:medium\\.com/@", search.SearchResult{URL: "https://medium.com/@someone/post"}, true},
+		{"Regex no match", "regex:^medium\\.com/@", search.SearchResult{URL: "https://blog.medium.com/@someone"}, false},
+		{"Title", "title:sponsored", search.SearchResult{Title: "A Sponsored Post"}, true},
+		{"Title not snippet", "title:sponsored", search.SearchResult{Snippet: "sponsored"}, false},
+		{"Snippet", "snippet:buy now", search.SearchResult{Snippet: "Great deals, BUY NOW"}, true},
+		{"Text", "text:coupon", search.SearchResult{Snippet: "Coupon codes"}, true},
+		{"Scraped", "scraped:wikipedia.org", search.SearchResult{URL: "https://en.wikipedia.org/wiki/Go"}, true},
+		{"Scraped with content", "scraped:wikipedia.org", search.SearchResult{URL: "https://en.wikipedia.org/wiki/Go", Content: "Go is a language"}, false},
+		{"Not scraped with content", "wikipedia.org", search.SearchResult{URL: "https://en.wikipedia.org/wiki/Go", Content: "Go is a language"}, true},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			rule, err := ParseRule(tc.rule)
+			if err != nil {
+				t.Fatalf("ParseRule(%q) failed: %v", tc.rule, err)
+			}
+			if got := rule.Matches(tc.result); got != tc.expected {
+				t.Errorf("%s matching %+v = %v; want %v", tc.rule, tc.result, got, tc.expected)
+			}
+		})
+	}
+}
+
+func TestSet(t *testing.T) {
+	set, err := Parse([]string{"wikipedia.org", "title:sponsored"}, []string{"wikipedia.org", "go.dev"})
+	if err != nil {
+		t.Fatalf("Parse failed: %v", err)
+	}
+
+	testCases := []struct {
+		name   string
+		result search.SearchResult
+		keep   bool
+		reason string
+	}{
+		{"Allowed", search.SearchResult{URL: "https://go.dev/doc"}, true, ""},
+		{"Not allowed", search.SearchResult{URL: "https://example.com"}, false, "not matched by any allow rule"},
+		{"Allowed but denied", search.SearchResult{URL: "https://en.wikipedia.org/wiki/Go"}, false, "denied by domain:wikipedia.org"},
+		{"Denied by title", search.SearchResult{URL: "https://go.dev/ad", Title: "Sponsored"}, false, "denied by title:sponsored"},
+	}
+
+	filter := set.Filter()
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			keep, reason := set.Check(tc.result)
+			if keep != tc.keep || reason != tc.reason {
+				t.Errorf("Check(%+v) = %v, %q; want %v, %q", tc.result, keep, reason, tc.keep, tc.reason)
+			}
+			if filter(tc.result) != tc.keep {
+				t.Errorf("Filter disagrees with Check for %+v", tc.result)
+			}
+		})
+	}
+
+	// Seeing the same result again shouldn't record it twice
+	filter(search.SearchResult{URL: "https://example.com"})
+
+	dropped := set.Dropped()
+	if len(dropped) != 3 {
+		t.Fatalf("Expected 3 dropped results, got %d: %v", len(dropped), dropped)
+	}
+	if dropped[0].Result.URL != "https://example.com" || dropped[0].Reason != "not matched by any allow rule" {
+		t.Errorf("Unexpected drop: %+v", dropped[0])
+	}
+
+	if got := set.String(); got != "allow:domain:wikipedia.org allow:domain:go.dev domain:wikipedia.org title:sponsored" {
+		t.Errorf("Unexpected String(): %q", got)
+	}
+}
+
+func TestEmptySet(t *testing.T) {
+	set := New()
+	if keep, _ := set.Check(search.SearchResult{URL: "https://example.com"}); !keep {
+		t.Error("An empty set should keep everything")
+	}
+}
@@ -0,0 +1,94 @@
+package query
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"regexp"
+	"strings"
+
+	"ask-web/pkg/summarize"
+)
+
+const (
+	singleSystemPrompt = "You are generating a query to pass to a search engine. Return only the query, do not generate extraneous information. Try not to include dates unless in the prompt itself; your knowledge base is cutoff and you may get it wrong."
+	multiSystemPrompt  = "You are generating queries to pass to a search engine. Return exactly %d queries, one per line, with no numbering or extraneous information. Make them diverse: different wording, different interpretations of an ambiguous question, and different aspects of the topic. Try not to include dates unless in the prompt itself; your knowledge base is cutoff and you may get it wrong."
+
+	tokensPerQuery = 50
+)
+
+// Rewriter turns a user's prompt into search engine queries using whichever
+// LLM provider it's given.
+type Rewriter struct {
+	llm    summarize.Completer
+	model  string
+	prompt string
+}
+
+// NewRewriter returns a Rewriter that asks llm (using model, or the
+// provider's default if empty) to rewrite prompts following queryPrompt.
+func NewRewriter(llm summarize.Completer, model string, queryPrompt string) *Rewriter {
+	return &Rewriter{
+		llm:    llm,
+		model:  model,
+		prompt: queryPrompt,
+	}
+}
+
+// Rewrite generates n search queries for prompt. With n > 1 the queries are
+// meant to search an ambiguous question from a few angles; it may return fewer
+// than n if the model repeats itself.
+func (r *Rewriter) Rewrite(ctx context.Context, prompt string, n int) ([]string, error) {
+	n = max(n, 1)
+
+	req := summarize.CompletionRequest{
+		Model:       r.model,
+		System:      singleSystemPrompt,
+		Prompt:      fmt.Sprintf("%s: '%s'", r.prompt, prompt),
+		MaxTokens:   tokensPerQuery,
+		Temperature: 0.3,
+	}
+	if n > 1 {
+		req.System = fmt.Sprintf(multiSystemPrompt, n)
+		req.MaxTokens = tokensPerQuery * n
+		req.Temperature = 0.8
+	}
+
+	text, err := r.llm.Complete(ctx, req)
+	if err != nil {
+		return nil, err
+	}
+
+	queries := parseQueryVariants(text, n)
+	if len(queries) == 0 {
+		return nil, errors.New("no query generated")
+	}
+
+	return queries, nil
+}
+
+var variantPrefix = regexp.MustCompile(`^\s*(?:[-*•]|\d+[.)])\s*`)
+
+// Models like to number things even when told not to, so strip list markers
+// and quotes and drop blanks and repeats.
+func parseQueryVariants(text string, n int) []string {
+	var queries []string
+	seen := make(map[string]bool)
+
+	for _, line := range strings.Split(text, "\n") {
+		line = variantPrefix.ReplaceAllString(line, "")
+		line = strings.Trim(strings.TrimSpace(line), "\"'`")
+		key := strings.ToLower(line)
+		if line == "" || seen[key] {
+			continue
+		}
+		seen[key] = true
+		queries = append(queries, line)
+
+		if len(queries) == n {
+			break
+		}
+	}
+
+	return queries
+}
@@ -0,0 +1,106 @@
+package query
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+
+	"ask-web/pkg/summarize"
+)
+
+type fakeCompleter struct {
+	response string
+	err      error
+	sent     summarize.CompletionRequest
+}
+
+func (f *fakeCompleter) Complete(ctx context.Context, req summarize.CompletionRequest) (string, error) {
+	f.sent = req
+	return f.response, f.err
+}
+
+func TestRewrite(t *testing.T) {
+	testCases := []struct {
+		name     string
+		response string
+		err      error
+		n        int
+		expected []string
+		wantErr  bool
+	}{
+		{"Single", "\"go iterators\"", nil, 1, []string{"go iterators"}, false},
+		{"Single ignores extra lines", "go iterators\nrange over func", nil, 1, []string{"go iterators"}, false},
+		{"Variants", "1. go iterators\n2. range over func", nil, 2, []string{"go iterators", "range over func"}, false},
+		{"Nothing generated", "\n\n", nil, 1, nil, true},
+		{"Provider error", "", errors.New("no API key"), 1, nil, true},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			llm := &fakeCompleter{response: tc.response, err: tc.err}
+			rewriter := NewRewriter(llm, "some-model", "Make a query")
+
+			got, err := rewriter.Rewrite(context.Background(), "how do go iterators work", tc.n)
+			if (err != nil) != tc.wantErr {
+				t.Fatalf("Rewrite() error = %v, wantErr %v", err, tc.wantErr)
+			}
+			if strings.Join(got, "|") != strings.Join(tc.expected, "|") {
+				t.Errorf("Rewrite() = %q; want %q", got, tc.expected)
+			}
+
+			if llm.sent.Model != "some-model" {
+				t.Errorf("Expected model 'some-model', got '%s'", llm.sent.Model)
+			}
+			if llm.sent.Prompt != "Make a query: 'how do go iterators work'" {
+				t.Errorf("Unexpected prompt: %q", llm.sent.Prompt)
+			}
+			if llm.sent.MaxTokens != tokensPerQuery*max(tc.n, 1) {
+				t.Errorf("Expected %d max tokens, got %d", tokensPerQuery*tc.n, llm.sent.MaxTokens)
+			}
+		})
+	}
+}
+
+func TestParseQueryVariants(t *testing.T) {
+	testCases := []struct {
+		name     string
+		text     string
+		n        int
+		expected []string
+	}{
+		{
+			name:     "One per line",
+			text:     "go iterators\nrange over func go 1.23\ngo iter package",
+			n:        3,
+			expected: []string{"go iterators", "range over func go 1.23", "go iter package"},
+		},
+		{
+			name:     "Numbered and quoted",
+			text:     "1. \"go iterators\"\n2) range over func\n- go iter package\n",
+			n:        3,
+			expected: []string{"go iterators", "range over func", "go iter package"},
+		},
+		{
+			name:     "Blanks and repeats",
+			text:     "go iterators\n\nGo Iterators\nrange over func",
+			n:        3,
+			expected: []string{"go iterators", "range over func"},
+		},
+		{
+			name:     "Too many",
+			text:     "a\nb\nc\nd",
+			n:        2,
+			expected: []string{"a", "b"},
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			got := parseQueryVariants(tc.text, tc.n)
+			if strings.Join(got, "|") != strings.Join(tc.expected, "|") {
+				t.Errorf("parseQueryVariants() = %q; want %q", got, tc.expected)
+			}
+		})
+	}
+}
@@ -0,0 +1,184 @@
+package search
+
+import (
+	"context"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+)
+
+const ArxivBaseURL = "https://export.arxiv.org/api/query"
+
+// The API will return up to 2000 entries at once, but a page of abstracts
+// is plenty
+const arxivPageSize = 50
+
+// arXiv's format for submittedDate ranges
+const arxivDateFormat = "200601021504"
+
+type arxivFeed struct {
+	Entries []arxivEntry `xml:"http://www.w3.org/2005/Atom entry"`
+}
+
+type arxivEntry struct {
+	ID        string `xml:"http://www.w3.org/2005/Atom id"`
+	Title     string `xml:"http://www.w3.org/2005/Atom title"`
+	Summary   string `xml:"http://www.w3.org/2005/Atom summary"`
+	Published string `xml:"http://www.w3.org/2005/Atom published"`
+	Authors   []struct {
+		Name string `xml:"http://www.w3.org/2005/Atom name"`
+	} `xml:"http://www.w3.org/2005/Atom author"`
+	Links []struct {
+		Href  string `xml:"href,attr"`
+		Rel   string `xml:"rel,attr"`
+		Title string `xml:"title,attr"`
+	} `xml:"http://www.w3.org/2005/Atom link"`
+}
+
+// ArxivEngine searches arXiv's Atom API for papers. The abstract is used as
+// both the snippet and the content, so the PDFs are never downloaded.
+type ArxivEngine struct {
+	// BaseURL and Client default to ArxivBaseURL and a client with the
+	// usual timeout
+	BaseURL string
+	Client  *http.Client
+}
+
+func init() {
+	Register("arxiv", func(cfg EngineConfig) (SearchEngine, error) {
+		return &ArxivEngine{}, nil
+	})
+}
+
+func (e *ArxivEngine) Name() string { return "arxiv" }
+
+func (e *ArxivEngine) Capabilities() Capabilities {
+	return Capabilities{
+		MaxResultsPerRequest: arxivPageSize,
+		// arXiv asks for no more than one request every three seconds
+		MinInterval: 3 * time.Second,
+	}
+}
+
+func (e *ArxivEngine) Search(ctx context.Context, sr SearchRequest) ([]SearchResult, error) {
+	if sr.Query == "" {
+		return nil, fmt.Errorf("search query cannot be empty")
+	}
+
+	client := httpClient(e.Client)
+
+	return collectPages(ctx, sr, func(ctx context.Context, offset int) ([]SearchResult, error) {
+		return e.fetchPage(ctx, client, sr, offset)
+	})
+}
+
+func (e *ArxivEngine) fetchPage(ctx context.Context, client *http.Client, sr SearchRequest, offset int) ([]SearchResult, error) {
+	params := url.Values{}
+	params.Set("search_query", arxivQuery(sr))
+	params.Set("start", fmt.Sprintf("%d", offset))
+	params.Set("max_results", fmt.Sprintf("%d", pageSize(sr, arxivPageSize)))
+	params.Set("sortBy", "relevance")
+
+	baseURL := e.BaseURL
+	if baseURL == "" {
+		baseURL = ArxivBaseURL
+	}
+
+	resp, err := doRequest(ctx, client, e.Name(), func(ctx context.Context) (*http.Request, error) {
+		return http.NewRequestWithContext(ctx, "GET", baseURL+"?"+params.Encode(), nil)
+	})
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	results, err := extractArxivResults(resp.Body)
+	if err != nil {
+		return nil, &EngineError{Engine: e.Name(), Err: err}
+	}
+
+	return results, nil
+}
+
+// arxivQuery turns the request into arXiv's query syntax: every word has to
+// appear somewhere (all:), and --since becomes a submittedDate range. Sites
+// don't mean anything here.
+func arxivQuery(sr SearchRequest) string {
+	var terms []string
+	for _, word := range strings.Fields(sr.Query) {
+		// Quotes and brackets are query syntax
+		word = strings.Trim(word, `"()`)
+		if word != "" {
+			terms = append(terms, "all:"+word)
+		}
+	}
+
+	if sr.Since > 0 {
+		terms = append(terms, fmt.Sprintf("submittedDate:[%s TO %s]",
+			sr.SinceDate().Format(arxivDateFormat), now().Format(arxivDateFormat)))
+	}
+
+	return strings.Join(terms, " AND ")
+}
+
+func extractArxivResults(body io.Reader) ([]SearchResult, error) {
+	var feed arxivFeed
+	if err := xml.NewDecoder(body).Decode(&feed); err != nil {
+		return nil, fmt.Errorf("error decoding response: %w", err)
+	}
+
+	results := make([]SearchResult, 0, len(feed.Entries))
+	for _, entry := range feed.Entries {
+		// A bad query gets a feed with a single entry describing the error
+		if strings.Contains(entry.ID, "arxiv.org/api/errors") {
+			return nil, fmt.Errorf("%s", collapseSpace(entry.Summary))
+		}
+
+		result := SearchResult{
+			Title:   collapseSpace(entry.Title),
+			URL:     entry.ID,
+			Snippet: collapseSpace(entry.Summary),
+		}
+		for _, link := range entry.Links {
+			if link.Rel == "alternate" && link.Href != "" {
+				result.URL = link.Href
+			}
+		}
+		for _, author := range entry.Authors {
+			result.Authors = append(result.Authors, collapseSpace(author.Name))
+		}
+		if published, err := time.Parse(time.RFC3339, entry.Published); err == nil {
+			result.Published = published
+		}
+		result.Content = arxivContent(result)
+
+		results = append(results, result)
+	}
+
+	return results, nil
+}
+
+// The abstract is the content, with enough about the paper around it for
+// the summary to cite it properly
+func arxivContent(result SearchResult) string {
+	var b strings.Builder
+	b.WriteString(result.Title + "\n")
+	if len(result.Authors) > 0 {
+		b.WriteString("Authors: " + strings.Join(result.Authors, ", ") + "\n")
+	}
+	if !result.Published.IsZero() {
+		b.WriteString("Published: " + result.Published.Format(time.DateOnly) + "\n")
+	}
+	b.WriteString("\n" + result.Snippet)
+
+	return b.String()
+}
+
+// Titles and abstracts in the feed are wrapped over several lines
+func collapseSpace(s string) string {
+	return strings.Join(strings.Fields(s), " ")
+}
@@ -0,0 +1,99 @@
+package search
+
+import (
+	"bytes"
+	"context"
+	"net/http"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestExtractArxivResults(t *testing.T) {
+	results, err := extractArxivResults(bytes.NewReader(fixture(t, "arxiv.xml")))
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	expected := []string{"http://arxiv.org/abs/2005.11710v5", "http://arxiv.org/abs/2208.06810v1"}
+	if !reflect.DeepEqual(urls(results), expected) {
+		t.Fatalf("Expected %v, got %v", expected, urls(results))
+	}
+
+	paper := results[0]
+	if paper.Title != "Featherweight Go" {
+		t.Errorf("Unexpected title: %q", paper.Title)
+	}
+	if !reflect.DeepEqual(paper.Authors, []string{"Robert Griesemer", "Raymond Hu", "Philip Wadler"}) {
+		t.Errorf("Unexpected authors: %v", paper.Authors)
+	}
+	if !paper.Published.Equal(time.Date(2020, 5, 24, 8, 41, 36, 0, time.UTC)) {
+		t.Errorf("Unexpected published date: %s", paper.Published)
+	}
+	if !strings.HasPrefix(paper.Snippet, "We describe a design for generics in Go inspired by previous work on Featherweight Java") {
+		t.Errorf("Expected the abstract on one line as the snippet, got %q", paper.Snippet)
+	}
+	if !strings.HasPrefix(paper.Content, "Featherweight Go\nAuthors: Robert Griesemer, Raymond Hu, Philip Wadler\nPublished: 2020-05-24\n\nWe describe") {
+		t.Errorf("Unexpected content: %q", paper.Content)
+	}
+	if results[1].Title != "Generic Go to Go: Dictionary-Passing, Monomorphisation, and Hybrid" {
+		t.Errorf("Expected the wrapped title on one line, got %q", results[1].Title)
+	}
+
+	if _, err := extractArxivResults(bytes.NewReader(fixture(t, "arxiv_error.xml"))); err == nil || !strings.Contains(err.Error(), "max_results must be non-negative") {
+		t.Errorf("Expected the API's error, got %v", err)
+	}
+}
+
+func TestArxivQuery(t *testing.T) {
+	defer func(orig func() time.Time) { now = orig }(now)
+	now = func() time.Time { return time.Date(2024, 5, 3, 12, 30, 0, 0, time.UTC) }
+
+	testCases := []struct {
+		name     string
+		sr       SearchRequest
+		expected string
+	}{
+		{"Words", SearchRequest{Query: "go generics"}, "all:go AND all:generics"},
+		{"Quotes", SearchRequest{Query: `"type parameters" (go)`}, "all:type AND all:parameters AND all:go"},
+		{"Since", SearchRequest{Query: "go", Since: 7 * 24 * time.Hour}, "all:go AND submittedDate:[202404261230 TO 202405031230]"},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			if got := arxivQuery(tc.sr); got != tc.expected {
+				t.Errorf("arxivQuery(%+v) = %q; want %q", tc.sr, got, tc.expected)
+			}
+		})
+	}
+}
+
+func TestArxivSearch(t *testing.T) {
+	var requests []*http.Request
+	server := fixtureServer(t, map[string]string{"/api/query": "arxiv.xml"}, &requests)
+
+	engine := &ArxivEngine{BaseURL: server.URL + "/api/query", Client: server.Client()}
+	results, err := engine.Search(context.Background(), SearchRequest{Query: "go generics", MaxResults: 1})
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if len(results) != 1 || results[0].Content == "" {
+		t.Errorf("Expected 1 result with content, got %+v", results)
+	}
+
+	if len(requests) != 1 {
+		t.Fatalf("Expected 1 request, got %d", len(requests))
+	}
+	want := map[string]string{
+		"search_query": "all:go AND all:generics",
+		"start":        "0",
+		"max_results":  "2",
+		"sortBy":       "relevance",
+	}
+	for k, v := range want {
+		if got := requests[0].URL.Query().Get(k); got != v {
+			t.Errorf("Expected %s=%q, got %q", k, v, got)
+		}
+	}
+}
@@ -1,9 +1,9 @@
 package search
 
 import (
+	"context"
 	"encoding/json"
 	"fmt"
-	"io"
 	"net/http"
 	"net/url"
 	"time"
@@ -11,60 +11,145 @@ import (
 
 const BaseURL = "https://api.bing.microsoft.com/v7.0/custom/search"
 
+const bingPageSize = 50
+
 type SearchResponse struct {
 	WebPages struct {
-		Value []SearchResult `json:"value"`
+		Value []struct {
+			Name    string `json:"name"`
+			URL     string `json:"url"`
+			Snippet string `json:"snippet"`
+		} `json:"value"`
 	} `json:"webPages"`
 }
 
-func BingSearch(apiKey string, configKey string, query string, maxResults int, filter FilterFunc) ([]SearchResult, error) {
-	client := &http.Client{
-		Timeout: time.Duration(MaxTimeoutSeconds) * time.Second,
+type BingEngine struct {
+	apiKey    string
+	configKey string
+
+	// BaseURL and Client default to the package's BaseURL and a client with
+	// the usual timeout
+	BaseURL string
+	Client  *http.Client
+}
+
+func init() {
+	Register("bing", func(cfg EngineConfig) (SearchEngine, error) {
+		if cfg.Keys.BingAPIKey == "" || cfg.Keys.BingConfigKey == "" {
+			return nil, fmt.Errorf("%w: BING_API_KEY and BING_CONFIG_KEY are required", ErrNotConfigured)
+		}
+		return &BingEngine{apiKey: cfg.Keys.BingAPIKey, configKey: cfg.Keys.BingConfigKey}, nil
+	})
+}
+
+func (e *BingEngine) Name() string { return "bing" }
+
+func (e *BingEngine) Capabilities() Capabilities {
+	return Capabilities{
+		RequiresKey:          true,
+		MaxResultsPerRequest: bingPageSize,
+		// 3 transactions per second on the S1 tier
+		MinInterval: 334 * time.Millisecond,
 	}
+}
 
-	params := url.Values{}
-	params.Add("q", query)
-	n := float32(maxResults) * ExtraResultsFactor
-	// fmt.Printf("maxResults: %d, ExtraResultsFactor: %f, n: %f, int(n): %d\n", maxResults, ExtraResultsFactor, n, int(n))
-	params.Add("count", fmt.Sprintf("%d", int(n)))
-	params.Add("customConfig", configKey)
-	params.Add("safeSearch", "Off")
+func BingSearch(apiKey string, configKey string, query string, maxResults int, filter FilterFunc) ([]SearchResult, error) {
+	e := &BingEngine{apiKey: apiKey, configKey: configKey}
+	return e.Search(context.Background(), SearchRequest{
+		Query:      query,
+		MaxResults: maxResults,
+		Filter:     filter,
+	})
+}
 
-	reqURL := fmt.Sprintf("%s?%s", BaseURL, params.Encode())
+func (e *BingEngine) Search(ctx context.Context, sr SearchRequest) ([]SearchResult, error) {
+	client := httpClient(e.Client)
 
-	req, err := http.NewRequest("GET", reqURL, nil)
-	if err != nil {
-		return nil, fmt.Errorf("error creating request: %w", err)
+	return collectPages(ctx, sr, func(ctx context.Context, offset int) ([]SearchResult, error) {
+		return e.fetchPage(ctx, client, sr, offset)
+	})
+}
+
+func (e *BingEngine) fetchPage(ctx context.Context, client *http.Client, sr SearchRequest, offset int) ([]SearchResult, error) {
+	params := url.Values{}
+	params.Add("q", sr.QueryText())
+	params.Add("count", fmt.Sprintf("%d", pageSize(sr, bingPageSize)))
+	params.Add("customConfig", e.configKey)
+	params.Add("safeSearch", bingSafeSearch(sr.SafeSearch))
+	if freshness := bingFreshness(sr); freshness != "" {
+		params.Add("freshness", freshness)
+	}
+	if offset > 0 {
+		params.Add("offset", fmt.Sprintf("%d", offset))
+	}
+	if sr.Lang() != "" && sr.Country() != "" {
+		params.Add("mkt", sr.Locale())
+	}
+	if lang := sr.Lang(); lang != "" {
+		params.Add("setLang", lang)
 	}
 
-	req.Header.Add("Ocp-Apim-Subscription-Key", apiKey)
+	baseURL := e.BaseURL
+	if baseURL == "" {
+		baseURL = BaseURL
+	}
+	reqURL := fmt.Sprintf("%s?%s", baseURL, params.Encode())
 
-	resp, err := client.Do(req)
+	resp, err := doRequest(ctx, client, e.Name(), func(ctx context.Context) (*http.Request, error) {
+		req, err := http.NewRequestWithContext(ctx, "GET", reqURL, nil)
+		if err != nil {
+			return nil, err
+		}
+		req.Header.Add("Ocp-Apim-Subscription-Key", e.apiKey)
+		return req, nil
+	})
 	if err != nil {
-		return nil, fmt.Errorf("error making request: %w", err)
+		return nil, err
 	}
 	defer resp.Body.Close()
 
-	if resp.StatusCode != http.StatusOK {
-		body, _ := io.ReadAll(resp.Body)
-		return nil, fmt.Errorf("search request failed with status %d: %s",
-			resp.StatusCode, string(body))
-	}
-
 	var searchResp SearchResponse
 	if err := json.NewDecoder(resp.Body).Decode(&searchResp); err != nil {
-		return nil, fmt.Errorf("error decoding response: %w", err)
+		return nil, &EngineError{Engine: e.Name(), Message: "error decoding response", Err: err}
 	}
 
-	filteredResults := make([]SearchResult, 0, maxResults)
-	for _, result := range searchResp.WebPages.Value {
-		if filter == nil || filter(result) {
-			filteredResults = append(filteredResults, result)
-			if len(filteredResults) == maxResults {
-				break
-			}
-		}
+	results := make([]SearchResult, 0, len(searchResp.WebPages.Value))
+	for _, item := range searchResp.WebPages.Value {
+		results = append(results, SearchResult{
+			Title:   item.Name,
+			URL:     item.URL,
+			Snippet: item.Snippet,
+		})
+	}
+
+	return results, nil
+}
+
+// Bing takes Day, Week or Month, or a custom from..to range
+func bingFreshness(sr SearchRequest) string {
+	switch sr.Freshness() {
+	case FreshnessDay:
+		return "Day"
+	case FreshnessWeek:
+		return "Week"
+	case FreshnessMonth:
+		return "Month"
+	}
+	if sr.Since <= 0 {
+		return ""
 	}
+	from, to := sr.dateRange()
+	return from + ".." + to
+}
 
-	return filteredResults, nil
+// Bing has always been asked for Off unless told otherwise
+func bingSafeSearch(safe SafeSearch) string {
+	switch safe {
+	case SafeSearchModerate:
+		return "Moderate"
+	case SafeSearchStrict:
+		return "Strict"
+	default:
+		return "Off"
+	}
 }
@@ -0,0 +1,128 @@
+package search
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestBingSearch(t *testing.T) {
+	var requests []*http.Request
+	server := fixtureServer(t, map[string]string{"/search": "bing.json"}, &requests)
+
+	engine := &BingEngine{apiKey: "key", configKey: "config", BaseURL: server.URL + "/search", Client: server.Client()}
+	results, err := engine.Search(context.Background(), SearchRequest{
+		Query:      "golang generics",
+		MaxResults: 2,
+		Region:     "de-de",
+		SafeSearch: SafeSearchModerate,
+		Since:      24 * time.Hour,
+		Filter: func(r SearchResult) bool {
+			return r.URL != "https://go.dev/blog/intro-generics"
+		},
+	})
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	expected := []string{"https://go.dev/doc/tutorial/generics", "https://gobyexample.com/generics"}
+	if !reflect.DeepEqual(urls(results), expected) {
+		t.Errorf("Expected %v, got %v", expected, urls(results))
+	}
+	if results[1].Title != "Go by Example: Generics" || results[1].Snippet != "Starting with version 1.18, Go has added support for generics." {
+		t.Errorf("Unexpected result: %+v", results[1])
+	}
+
+	if len(requests) != 1 {
+		t.Fatalf("Expected 1 request, got %d", len(requests))
+	}
+	want := map[string]string{
+		"q":            "golang generics",
+		"count":        "4",
+		"customConfig": "config",
+		"safeSearch":   "Moderate",
+		"freshness":    "Day",
+		"mkt":          "de-DE",
+		"setLang":      "de",
+	}
+	for k, v := range want {
+		if got := requests[0].URL.Query().Get(k); got != v {
+			t.Errorf("Expected %s=%q, got %q", k, v, got)
+		}
+	}
+	if key := requests[0].Header.Get("Ocp-Apim-Subscription-Key"); key != "key" {
+		t.Errorf("Expected the API key in the header, got %q", key)
+	}
+}
+
+func TestBingSearchPaging(t *testing.T) {
+	var requests []*http.Request
+	server := fixtureServer(t, map[string]string{"/search": "bing.json"}, &requests)
+
+	engine := &BingEngine{apiKey: "key", configKey: "config", BaseURL: server.URL + "/search", Client: server.Client()}
+	results, err := engine.Search(context.Background(), SearchRequest{Query: "golang generics", MaxResults: 10})
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	// The second page is the first one again, which ends the paging
+	if len(results) != 3 {
+		t.Errorf("Expected 3 results, got %d", len(results))
+	}
+	if len(requests) != 2 {
+		t.Fatalf("Expected 2 requests, got %d", len(requests))
+	}
+	if offset := requests[1].URL.Query().Get("offset"); offset != "3" {
+		t.Errorf("Expected offset=3 for the second page, got %q", offset)
+	}
+}
+
+func TestBingSearchRetries(t *testing.T) {
+	defer func(orig RetryPolicy) { DefaultRetryPolicy = orig }(DefaultRetryPolicy)
+	DefaultRetryPolicy = RetryPolicy{MaxAttempts: 3, BaseDelay: time.Millisecond, MaxDelay: 10 * time.Millisecond}
+
+	calls := 0
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		calls++
+		if calls == 1 {
+			w.Header().Set("Retry-After", "0")
+			w.WriteHeader(http.StatusTooManyRequests)
+			return
+		}
+		w.Write(fixture(t, "bing.json"))
+	}))
+	defer server.Close()
+
+	engine := &BingEngine{apiKey: "key", configKey: "config", BaseURL: server.URL, Client: server.Client()}
+	results, err := engine.Search(context.Background(), SearchRequest{Query: "golang generics", MaxResults: 3})
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if len(results) != 3 || calls != 2 {
+		t.Errorf("Expected 3 results after 2 requests, got %d after %d", len(results), calls)
+	}
+
+	unauthorized := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusUnauthorized)
+		w.Write([]byte(`{"error": {"code": "401", "message": "Access denied due to invalid subscription key."}}`))
+	}))
+	defer unauthorized.Close()
+
+	engine.BaseURL = unauthorized.URL
+	if _, err := engine.Search(context.Background(), SearchRequest{Query: "golang generics", MaxResults: 3}); !errors.Is(err, ErrAuthFailed) {
+		t.Errorf("Expected ErrAuthFailed, got %v", err)
+	}
+}
+
+func TestBingSafeSearch(t *testing.T) {
+	// Unset stays off, as Bing has always been asked for
+	for safe, expected := range map[SafeSearch]string{"": "Off", SafeSearchOff: "Off", SafeSearchModerate: "Moderate", SafeSearchStrict: "Strict"} {
+		if got := bingSafeSearch(safe); got != expected {
+			t.Errorf("bingSafeSearch(%q) = %q; want %q", safe, got, expected)
+		}
+	}
+}
@@ -0,0 +1,105 @@
+package search
+
+import (
+	"math"
+	"sort"
+	"strings"
+	"unicode"
+)
+
+// Okapi BM25's usual tuning: how quickly repeated terms stop counting, and
+// how much long documents are penalized
+const (
+	bm25K1 = 1.2
+	bm25B  = 0.75
+)
+
+// BM25Index ranks a fixed set of documents against queries with Okapi BM25.
+// It's small and entirely in memory, which is plenty for a directory of
+// notes, or for the page history when SQLite doesn't have FTS5.
+type BM25Index struct {
+	// Term frequencies for each document
+	docs    []map[string]int
+	lengths []int
+	total   int
+	// How many documents each term appears in
+	docFreq map[string]int
+}
+
+type BM25Match struct {
+	// Doc is the number Add returned for the document
+	Doc   int
+	Score float64
+}
+
+func NewBM25Index() *BM25Index {
+	return &BM25Index{docFreq: make(map[string]int)}
+}
+
+// Add indexes a document's text and returns its number.
+func (idx *BM25Index) Add(text string) int {
+	freqs := make(map[string]int)
+	tokens := tokenize(text)
+	for _, token := range tokens {
+		if freqs[token] == 0 {
+			idx.docFreq[token]++
+		}
+		freqs[token]++
+	}
+
+	idx.docs = append(idx.docs, freqs)
+	idx.lengths = append(idx.lengths, len(tokens))
+	idx.total += len(tokens)
+
+	return len(idx.docs) - 1
+}
+
+// Search returns the documents matching any of the query's terms, best
+// first.
+func (idx *BM25Index) Search(query string) []BM25Match {
+	terms := uniqueTokens(query)
+	n := float64(len(idx.docs))
+	avgLen := float64(idx.total) / max(n, 1)
+
+	var matches []BM25Match
+	for doc, freqs := range idx.docs {
+		score := 0.0
+		for _, term := range terms {
+			tf := float64(freqs[term])
+			if tf == 0 {
+				continue
+			}
+			df := float64(idx.docFreq[term])
+			idf := math.Log(1 + (n-df+0.5)/(df+0.5))
+			norm := 1 - bm25B + bm25B*float64(idx.lengths[doc])/avgLen
+			score += idf * tf * (bm25K1 + 1) / (tf + bm25K1*norm)
+		}
+		if score > 0 {
+			matches = append(matches, BM25Match{Doc: doc, Score: score})
+		}
+	}
+
+	sort.SliceStable(matches, func(i, j int) bool {
+		return matches[i].Score > matches[j].Score
+	})
+	return matches
+}
+
+// tokenize splits text into lower case words (runs of letters and digits).
+func tokenize(text string) []string {
+	return strings.FieldsFunc(strings.ToLower(text), func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsDigit(r)
+	})
+}
+
+func uniqueTokens(text string) []string {
+	var tokens []string
+	seen := make(map[string]bool)
+	for _, token := range tokenize(text) {
+		if !seen[token] {
+			seen[token] = true
+			tokens = append(tokens, token)
+		}
+	}
+	return tokens
+}
@@ -0,0 +1,41 @@
+package search
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestBM25Index(t *testing.T) {
+	idx := NewBM25Index()
+	idx.Add("Configuring nginx as a reverse proxy")
+	idx.Add("nginx nginx nginx: a long list of nginx tips, tricks, notes, links and everything else about web servers")
+	idx.Add("Postgres backups with pg_dump")
+	idx.Add("Reverse proxy notes: Caddy vs nginx")
+
+	testCases := []struct {
+		query    string
+		expected []int
+	}{
+		// Both words beat one word repeated, and shorter documents win ties
+		{"nginx reverse proxy", []int{0, 3, 1}},
+		{"PG_DUMP", []int{2}},
+		{"kubernetes", nil},
+	}
+
+	for _, tc := range testCases {
+		var docs []int
+		for _, match := range idx.Search(tc.query) {
+			docs = append(docs, match.Doc)
+		}
+		if !reflect.DeepEqual(docs, tc.expected) {
+			t.Errorf("Search(%q) = %v; want %v", tc.query, docs, tc.expected)
+		}
+	}
+}
+
+func TestTokenize(t *testing.T) {
+	expected := []string{"how", "do", "i", "use", "go", "1", "22", "s", "range", "over", "func"}
+	if got := tokenize("How do I use Go 1.22's range-over-func?"); !reflect.DeepEqual(got, expected) {
+		t.Errorf("Expected %v, got %v", expected, got)
+	}
+}
@@ -0,0 +1,161 @@
+package search
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"time"
+)
+
+const BraveBaseURL = "https://api.search.brave.com/res/v1/web/search"
+
+// Brave won't return more than braveMaxCount results per request, or go
+// further than braveMaxOffset pages
+const (
+	braveMaxCount  = 20
+	braveMaxOffset = 9
+)
+
+type braveSearchResponse struct {
+	Web struct {
+		Results []struct {
+			Title       string `json:"title"`
+			URL         string `json:"url"`
+			Description string `json:"description"`
+		} `json:"results"`
+	} `json:"web"`
+}
+
+type BraveEngine struct {
+	apiKey string
+
+	// BaseURL and Client default to BraveBaseURL and a client with the usual
+	// timeout
+	BaseURL string
+	Client  *http.Client
+}
+
+func init() {
+	Register("brave", func(cfg EngineConfig) (SearchEngine, error) {
+		if cfg.Keys.BraveAPIKey == "" {
+			return nil, fmt.Errorf("%w: BRAVE_API_KEY is required", ErrNotConfigured)
+		}
+		return &BraveEngine{apiKey: cfg.Keys.BraveAPIKey}, nil
+	})
+}
+
+func (e *BraveEngine) Name() string { return "brave" }
+
+func (e *BraveEngine) Capabilities() Capabilities {
+	return Capabilities{
+		RequiresKey:          true,
+		MaxResultsPerRequest: braveMaxCount,
+		// The free plan allows 1 query per second (and 2000 a month, which
+		// can be set as a daily quota in the config if need be)
+		MinInterval: time.Second,
+	}
+}
+
+func (e *BraveEngine) Search(ctx context.Context, sr SearchRequest) ([]SearchResult, error) {
+	if sr.Query == "" {
+		return nil, fmt.Errorf("search query cannot be empty")
+	}
+
+	client := httpClient(e.Client)
+
+	// Brave's offset is in pages of count, not results, so count has to stay
+	// the same from page to page
+	count := pageSize(sr, braveMaxCount)
+	page := sr.Offset / count
+
+	return collectPages(ctx, sr, func(ctx context.Context, offset int) ([]SearchResult, error) {
+		if page > braveMaxOffset {
+			return nil, nil
+		}
+		results, err := e.fetchPage(ctx, client, sr, count, page)
+		page++
+		return results, err
+	})
+}
+
+func (e *BraveEngine) fetchPage(ctx context.Context, client *http.Client, sr SearchRequest, count int, page int) ([]SearchResult, error) {
+	params := url.Values{}
+	params.Add("q", sr.QueryText())
+	params.Add("count", fmt.Sprintf("%d", count))
+	if sr.SafeSearch != "" {
+		params.Add("safesearch", string(sr.SafeSearch))
+	}
+	if freshness := braveFreshness(sr); freshness != "" {
+		params.Add("freshness", freshness)
+	}
+	if page > 0 {
+		params.Add("offset", fmt.Sprintf("%d", page))
+	}
+	if country := sr.Country(); country != "" {
+		params.Add("country", country)
+	}
+	if lang := sr.Lang(); lang != "" {
+		params.Add("search_lang", lang)
+	}
+
+	baseURL := e.BaseURL
+	if baseURL == "" {
+		baseURL = BraveBaseURL
+	}
+
+	resp, err := doRequest(ctx, client, e.Name(), func(ctx context.Context) (*http.Request, error) {
+		req, err := http.NewRequestWithContext(ctx, "GET", baseURL+"?"+params.Encode(), nil)
+		if err != nil {
+			return nil, err
+		}
+		req.Header.Set("Accept", "application/json")
+		req.Header.Set("X-Subscription-Token", e.apiKey)
+		return req, nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	results, err := extractBraveResults(resp.Body)
+	if err != nil {
+		return nil, &EngineError{Engine: e.Name(), Err: err}
+	}
+
+	return results, nil
+}
+
+// Brave takes pd, pw, pm or py (past day etc), or a custom fromto range
+func braveFreshness(sr SearchRequest) string {
+	switch sr.Freshness() {
+	case "":
+		if sr.Since <= 0 {
+			return ""
+		}
+		from, to := sr.dateRange()
+		return from + "to" + to
+	default:
+		return "p" + string(sr.Freshness()[:1])
+	}
+}
+
+func extractBraveResults(body io.Reader) ([]SearchResult, error) {
+	var braveResp braveSearchResponse
+	if err := json.NewDecoder(body).Decode(&braveResp); err != nil {
+		return nil, fmt.Errorf("error decoding response: %w", err)
+	}
+
+	results := make([]SearchResult, 0, len(braveResp.Web.Results))
+	for _, item := range braveResp.Web.Results {
+		results = append(results, SearchResult{
+			Title:   item.Title,
+			URL:     item.URL,
+			Snippet: item.Description,
+		})
+	}
+
+	return results, nil
+}
@@ -0,0 +1,89 @@
+package search
+
+import (
+	"bytes"
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestExtractBraveResults(t *testing.T) {
+	results, err := extractBraveResults(bytes.NewReader(fixture(t, "brave_page1.json")))
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	expected := []string{"https://go.dev/", "https://en.wikipedia.org/wiki/Go_(programming_language)", "https://go.dev/tour/"}
+	if strings.Join(urls(results), " ") != strings.Join(expected, " ") {
+		t.Errorf("Expected %v, got %v", expected, urls(results))
+	}
+
+	if results[0].Title != "The Go Programming Language" {
+		t.Errorf("Unexpected title: %q", results[0].Title)
+	}
+	if results[0].Snippet != "Go is an open source programming language." {
+		t.Errorf("Unexpected snippet: %q", results[0].Snippet)
+	}
+
+	if _, err := extractBraveResults(strings.NewReader("{")); err == nil {
+		t.Error("Expected an error decoding bad JSON")
+	}
+}
+
+func TestBraveSearch(t *testing.T) {
+	var requests []*http.Request
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests = append(requests, r)
+		w.Header().Set("Content-Type", "application/json")
+		if r.URL.Query().Get("offset") == "" {
+			w.Write(fixture(t, "brave_page1.json"))
+		} else {
+			w.Write(fixture(t, "brave_page2.json"))
+		}
+	}))
+	defer server.Close()
+
+	engine := &BraveEngine{apiKey: "key", BaseURL: server.URL, Client: server.Client()}
+	results, err := engine.Search(context.Background(), SearchRequest{
+		Query:      "golang",
+		MaxResults: 4,
+		Region:     "de-de",
+		Filter: func(r SearchResult) bool {
+			return !strings.Contains(r.URL, "wikipedia.org")
+		},
+	})
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	expected := []string{"https://go.dev/", "https://go.dev/tour/", "https://gobyexample.com/", "https://go.dev/doc/effective_go"}
+	if !reflect.DeepEqual(urls(results), expected) {
+		t.Errorf("Expected %v, got %v", expected, urls(results))
+	}
+
+	if len(requests) != 2 {
+		t.Fatalf("Expected 2 requests, got %d", len(requests))
+	}
+	want := map[string]string{
+		"q":           "golang",
+		"count":       "8",
+		"safesearch":  "",
+		"country":     "de",
+		"search_lang": "de",
+	}
+	for k, v := range want {
+		if got := requests[0].URL.Query().Get(k); got != v {
+			t.Errorf("Expected %s=%q, got %q", k, v, got)
+		}
+	}
+	if token := requests[0].Header.Get("X-Subscription-Token"); token != "key" {
+		t.Errorf("Expected the API key in the header, got %q", token)
+	}
+	// Brave's offset counts pages, not results
+	if offset := requests[1].URL.Query().Get("offset"); offset != "1" {
+		t.Errorf("Expected offset=1 for the second page, got %q", offset)
+	}
+}
@@ -0,0 +1,92 @@
+package search
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+)
+
+// ResultCache stores engine results between runs. It's implemented by the
+// database package; search only knows about the interface.
+type ResultCache interface {
+	// Get returns the results stored under key if they're no older than
+	// maxAge. The bool is false on a miss.
+	Get(key string, maxAge time.Duration) ([]SearchResult, bool, error)
+	Put(key string, results []SearchResult) error
+}
+
+// CachedEngine wraps an engine so that repeating a search within ttl doesn't
+// hit the engine (and its quota) again. With refresh set the cache is never
+// read, only updated.
+type CachedEngine struct {
+	SearchEngine
+	cache   ResultCache
+	ttl     time.Duration
+	refresh bool
+}
+
+func NewCachedEngine(engine SearchEngine, cache ResultCache, ttl time.Duration, refresh bool) *CachedEngine {
+	return &CachedEngine{
+		SearchEngine: engine,
+		cache:        cache,
+		ttl:          ttl,
+		refresh:      refresh,
+	}
+}
+
+// WithCache wraps each of engines in a CachedEngine.
+func WithCache(engines []SearchEngine, cache ResultCache, ttl time.Duration, refresh bool) []SearchEngine {
+	cached := make([]SearchEngine, 0, len(engines))
+	for _, engine := range engines {
+		cached = append(cached, NewCachedEngine(engine, cache, ttl, refresh))
+	}
+	return cached
+}
+
+func (e *CachedEngine) Search(ctx context.Context, sr SearchRequest) ([]SearchResult, error) {
+	key := CacheKey(e.Name(), sr)
+
+	// A broken cache shouldn't stop the search, so errors just count as misses
+	if !e.refresh {
+		if results, ok, err := e.cache.Get(key, e.ttl); err == nil && ok {
+			if hit, ok := ctx.Value(cacheHitKey{}).(*bool); ok {
+				*hit = true
+			}
+			return results, nil
+		}
+	}
+
+	results, err := e.SearchEngine.Search(ctx, sr)
+	if err != nil {
+		return nil, err
+	}
+
+	// Don't remember "nothing found"; it's more likely a hiccup than the truth
+	if len(results) > 0 {
+		e.cache.Put(key, results)
+	}
+
+	return results, nil
+}
+
+// runEngine passes a *bool under this key so it can tell whether the results
+// came from the cache
+type cacheHitKey struct{}
+
+// CacheKey identifies a search for caching: the engine, the query with case
+// and spacing normalized, and every other request field that changes which
+// results come back.
+func CacheKey(engine string, sr SearchRequest) string {
+	query := strings.Join(strings.Fields(strings.ToLower(sr.Query)), " ")
+
+	return strings.Join([]string{
+		engine,
+		query,
+		fmt.Sprintf("n=%d,offset=%d,pages=%d", sr.MaxResults, sr.Offset, sr.MaxPages),
+		fmt.Sprintf("locale=%s,safe=%s,since=%s", sr.Locale(), sr.SafeSearch, sr.Since),
+		"sites=" + strings.Join(sr.Sites, ","),
+		"exclude=" + strings.Join(sr.ExcludeSites, ","),
+		"filter=" + sr.FilterKey,
+	}, "|")
+}
@@ -0,0 +1,101 @@
+package search
+
+import (
+	"context"
+	"sync"
+	"testing"
+	"time"
+)
+
+type memoryCache struct {
+	mu      sync.Mutex
+	entries map[string][]SearchResult
+}
+
+func (c *memoryCache) Get(key string, maxAge time.Duration) ([]SearchResult, bool, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	results, ok := c.entries[key]
+	return results, ok, nil
+}
+
+func (c *memoryCache) Put(key string, results []SearchResult) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.entries[key] = results
+	return nil
+}
+
+type countingEngine struct {
+	fakeEngine
+	calls int
+}
+
+func (c *countingEngine) Search(ctx context.Context, req SearchRequest) ([]SearchResult, error) {
+	c.calls++
+	return c.fakeEngine.Search(ctx, req)
+}
+
+func TestCachedEngine(t *testing.T) {
+	cache := &memoryCache{entries: make(map[string][]SearchResult)}
+	engine := &countingEngine{fakeEngine: fakeEngine{name: "google", results: []SearchResult{{URL: "https://example.com"}}}}
+	cached := WithCache([]SearchEngine{engine}, cache, time.Hour, false)
+
+	results := SearchAll(context.Background(), cached, SearchRequest{Query: "Go  Iterators"}, 0)
+	if engine.calls != 1 || results[0].Cached {
+		t.Fatalf("Expected the first search to hit the engine, got %d calls, cached=%v", engine.calls, results[0].Cached)
+	}
+
+	// Same query, different case and spacing
+	results = SearchAll(context.Background(), cached, SearchRequest{Query: "go iterators"}, 0)
+	if engine.calls != 1 || !results[0].Cached || len(results[0].Results) != 1 {
+		t.Errorf("Expected a cache hit, got %d calls, cached=%v", engine.calls, results[0].Cached)
+	}
+	if got := Contributors(results); got != "google (1, cached)" {
+		t.Errorf("Unexpected contributors: %q", got)
+	}
+
+	// Different options are a different search
+	SearchAll(context.Background(), cached, SearchRequest{Query: "go iterators", Region: "de-de"}, 0)
+	if engine.calls != 2 {
+		t.Errorf("Expected a cache miss for a different region, got %d calls", engine.calls)
+	}
+
+	refreshed := WithCache([]SearchEngine{engine}, cache, time.Hour, true)
+	results = SearchAll(context.Background(), refreshed, SearchRequest{Query: "go iterators"}, 0)
+	if engine.calls != 3 || results[0].Cached {
+		t.Errorf("Expected --refresh to skip the cache, got %d calls, cached=%v", engine.calls, results[0].Cached)
+	}
+}
+
+func TestCachedEngineSkipsEmpty(t *testing.T) {
+	cache := &memoryCache{entries: make(map[string][]SearchResult)}
+	engine := &countingEngine{fakeEngine: fakeEngine{name: "google"}}
+	cached := NewCachedEngine(engine, cache, time.Hour, false)
+
+	cached.Search(context.Background(), SearchRequest{Query: "q"})
+	cached.Search(context.Background(), SearchRequest{Query: "q"})
+	if engine.calls != 2 {
+		t.Errorf("Expected empty results not to be cached, got %d calls", engine.calls)
+	}
+}
+
+func TestCacheKey(t *testing.T) {
+	base := SearchRequest{Query: "go iterators", MaxResults: 3}
+
+	if CacheKey("google", base) == CacheKey("bing", base) {
+		t.Error("Expected different engines to have different keys")
+	}
+
+	filtered := base
+	filtered.FilterKey = "domain:x.com"
+	if CacheKey("google", base) == CacheKey("google", filtered) {
+		t.Error("Expected different filters to have different keys")
+	}
+
+	recent := base
+	recent.Since = 7 * 24 * time.Hour
+	if CacheKey("google", base) == CacheKey("google", recent) {
+		t.Error("Expected different time ranges to have different keys")
+	}
+}
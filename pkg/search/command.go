@@ -0,0 +1,65 @@
+package search
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+	"regexp"
+	"strings"
+)
+
+// Longest output to keep from a documentation command; the start (synopsis,
+// description) is what matters
+const maxCommandOutput = 64 * 1024
+
+// Keep pagers out of the way, man pages a readable width, and the go command
+// from going online or fetching toolchains
+var commandEnv = []string{
+	"MANPAGER=cat",
+	"PAGER=cat",
+	"MANWIDTH=80",
+	"GOPROXY=off",
+	"GOTOOLCHAIN=local",
+	"GOWORK=off",
+}
+
+// runCommand runs a program in dir (or the current directory) and returns
+// its standard output. It's a variable so that tests don't depend on what's
+// installed.
+var runCommand = func(ctx context.Context, dir string, name string, args ...string) ([]byte, error) {
+	cmd := exec.CommandContext(ctx, name, args...)
+	cmd.Dir = dir
+	cmd.Env = append(os.Environ(), commandEnv...)
+
+	out, err := cmd.Output()
+	var exitErr *exec.ExitError
+	if errors.As(err, &exitErr) && len(exitErr.Stderr) > 0 {
+		err = fmt.Errorf("%s: %s", err, strings.TrimSpace(string(exitErr.Stderr)))
+	}
+	return out, err
+}
+
+var (
+	// Bold and underlining in man's output are done by overstriking
+	overstrike = regexp.MustCompile(".\x08")
+	ansiEscape = regexp.MustCompile("\x1b\\[[0-9;]*[A-Za-z]")
+	blankLines = regexp.MustCompile(`\n{3,}`)
+)
+
+// plainOutput strips terminal formatting from a command's output, and cuts
+// it down to maxCommandOutput.
+func plainOutput(out []byte) string {
+	text := ansiEscape.ReplaceAllString(overstrike.ReplaceAllString(string(out), ""), "")
+	text = strings.TrimSpace(blankLines.ReplaceAllString(text, "\n\n"))
+	if len(text) > maxCommandOutput {
+		// At the end of a line, to keep the layout
+		cut := strings.LastIndex(text[:maxCommandOutput], "\n")
+		if cut <= 0 {
+			cut = maxCommandOutput
+		}
+		text = text[:cut] + "\n..."
+	}
+	return text
+}
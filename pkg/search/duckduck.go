@@ -1,6 +1,8 @@
 package search
 
 import (
+	"context"
+	"errors"
 	"fmt"
 	"io"
 	"net/http"
@@ -11,86 +13,329 @@ import (
 
 	// For parsing HTML results:
 	"github.com/PuerkitoBio/goquery"
+
+	"ask-web/pkg/logger"
 )
 
+// DDGRegion is used when the request doesn't ask for a region
 const DDGRegion = "wt-wt"
 
+const (
+	ddgHTMLURL = "https://html.duckduckgo.com/html/"
+	ddgLiteURL = "https://lite.duckduckgo.com/lite/"
+
+	ddgUserAgent = "Mozilla/5.0 (Windows NT 10.0; Win64; x64) AppleWebKit/537.36 (KHTML, like Gecko) Chrome/91.0.4472.124 Safari/537.36"
+)
+
+// DDGEngine scrapes DuckDuckGo's HTML endpoint, falling back to the lite one
+// if the HTML endpoint decides we're a bot.
+type DDGEngine struct {
+	// captureDir, if set, is where every response is saved for debugging
+	captureDir string
+
+	// BaseURL, LiteURL and Client default to the real endpoints and a
+	// client with the usual timeout
+	BaseURL string
+	LiteURL string
+	Client  *http.Client
+}
+
+func init() {
+	Register("duckduckgo", func(cfg EngineConfig) (SearchEngine, error) {
+		e := &DDGEngine{}
+		if cfg.Opts != nil && cfg.Opts.DDGCapture {
+			e.captureDir = cfg.Opts.DDGCaptureDir
+			if e.captureDir == "" {
+				e.captureDir = os.TempDir()
+			}
+		}
+		return e, nil
+	})
+}
+
+func (e *DDGEngine) Name() string { return "duckduckgo" }
+
+func (e *DDGEngine) Capabilities() Capabilities {
+	// No official limits, but the HTML endpoint starts serving captchas to
+	// anyone who hammers it
+	return Capabilities{
+		MinInterval: time.Second,
+	}
+}
+
 func DDGSearch(query string, maxResults int, filter FilterFunc) ([]SearchResult, error) {
-	if query == "" {
+	e := &DDGEngine{}
+	return e.Search(context.Background(), SearchRequest{
+		Query:      query,
+		MaxResults: maxResults,
+		Filter:     filter,
+	})
+}
+
+func (e *DDGEngine) Search(ctx context.Context, sr SearchRequest) ([]SearchResult, error) {
+	if sr.Query == "" {
 		return nil, fmt.Errorf("search query cannot be empty")
 	}
 
-	client := &http.Client{
-		Timeout: time.Duration(MaxTimeoutSeconds) * time.Second,
-	}
+	client := httpClient(e.Client)
+
+	// Once the HTML endpoint has blocked us, don't bother with it for the
+	// rest of the pages
+	lite := false
+
+	return collectPages(ctx, sr, func(ctx context.Context, offset int) ([]SearchResult, error) {
+		if !lite {
+			results, err := e.fetchPage(ctx, client, sr, false, offset)
+			if !errors.Is(err, ErrBlocked) {
+				return results, err
+			}
+			lite = true
+		}
+		return e.fetchPage(ctx, client, sr, true, offset)
+	})
+}
+
+// Neither endpoint lets us pick a page size; they page with s (the offset)
+// and dc (the number of the first result on the page).
+func (e *DDGEngine) fetchPage(ctx context.Context, client *http.Client, sr SearchRequest, lite bool, offset int) ([]SearchResult, error) {
+	baseURL := e.endpoint(lite)
 
-	baseURL := "https://html.duckduckgo.com/html/"
 	params := url.Values{}
-	params.Add("q", query)
-	params.Add("kl", DDGRegion)
+	params.Add("q", sr.QueryText())
+	params.Add("kl", ddgRegion(sr))
+	if sr.SafeSearch != "" {
+		params.Add("kp", ddgSafeSearch(sr.SafeSearch))
+	}
+	if df := ddgFreshness(sr); df != "" {
+		params.Add("df", df)
+	}
+	if offset > 0 {
+		params.Add("s", fmt.Sprintf("%d", offset))
+		params.Add("dc", fmt.Sprintf("%d", offset+1))
+	}
 
-	req, err := http.NewRequest("GET", baseURL+"?"+params.Encode(), nil)
+	resp, err := doRequest(ctx, client, e.Name(), func(ctx context.Context) (*http.Request, error) {
+		req, err := http.NewRequestWithContext(ctx, "GET", baseURL+"?"+params.Encode(), nil)
+		if err != nil {
+			return nil, err
+		}
+		req.Header.Set("User-Agent", ddgUserAgent)
+		return req, nil
+	})
+	var engineErr *EngineError
+	if errors.As(err, &engineErr) && engineErr.StatusCode == http.StatusAccepted {
+		// 202 with an empty page is how DDG turns away bots without a captcha
+		engineErr.Kind = ErrBlocked
+	}
 	if err != nil {
-		return nil, fmt.Errorf("failed to create request: %v", err)
+		return nil, err
 	}
+	defer resp.Body.Close()
 
-	req.Header.Set("User-Agent", "Mozilla/5.0 (Windows NT 10.0; Win64; x64) AppleWebKit/537.36 (KHTML, like Gecko) Chrome/91.0.4472.124 Safari/537.36")
-
-	resp, err := client.Do(req)
+	body, err := io.ReadAll(resp.Body)
 	if err != nil {
-		return nil, fmt.Errorf("failed to make request: %v", err)
+		return nil, &EngineError{Engine: e.Name(), Kind: ErrTransient, Message: "failed to read response body", Err: err}
 	}
-	defer resp.Body.Close()
 
-	if resp.StatusCode != http.StatusOK {
-		return nil, fmt.Errorf("received non-200 status code: %d", resp.StatusCode)
+	e.capture(lite, body)
+
+	if isDDGBlocked(string(body)) {
+		return nil, &EngineError{Engine: e.Name(), Kind: ErrBlocked, Message: baseURL}
 	}
 
-	body, err := io.ReadAll(resp.Body)
+	var results []SearchResult
+	if lite {
+		results, err = extractDDGLiteResults(string(body))
+	} else {
+		results, err = extractDDGResults(string(body))
+	}
 	if err != nil {
-		return nil, fmt.Errorf("failed to read response body: %v", err)
+		return nil, &EngineError{Engine: e.Name(), Message: "failed to extract results", Err: err}
+	}
+
+	return results, nil
+}
+
+func (e *DDGEngine) endpoint(lite bool) string {
+	switch {
+	case lite && e.LiteURL != "":
+		return e.LiteURL
+	case lite:
+		return ddgLiteURL
+	case e.BaseURL != "":
+		return e.BaseURL
+	default:
+		return ddgHTMLURL
+	}
+}
+
+// Save the response when debug capture is on. Failing to is only worth a
+// mention in the log, not failing the search.
+func (e *DDGEngine) capture(lite bool, body []byte) {
+	if e.captureDir == "" {
+		return
 	}
 
-	f, err := os.CreateTemp("/tmp", "ddgsearch")
+	prefix := "ddg-html-"
+	if lite {
+		prefix = "ddg-lite-"
+	}
+
+	f, err := os.CreateTemp(e.captureDir, prefix+"*.html")
 	if err != nil {
-		return nil, fmt.Errorf("failed to create temp file: %v", err)
+		logger.GetLogger().Warn("Error creating DuckDuckGo capture file: ", err)
+		return
 	}
+	defer f.Close()
+
 	if _, err := f.Write(body); err != nil {
-		return nil, fmt.Errorf("failed to write to temp file: %v", err)
+		logger.GetLogger().Warn("Error writing DuckDuckGo capture file: ", err)
+		return
+	}
+	logger.GetLogger().Info("Saved DuckDuckGo response to ", f.Name())
+}
+
+// DDG's regions are country-language ("de-de", "us-en"), which is the format
+// SearchRequest.Region uses. If only a language was given there's no country
+// to go with it, so stick with no region.
+func ddgRegion(sr SearchRequest) string {
+	if sr.Country() == "" {
+		return DDGRegion
+	}
+
+	lang := sr.Lang()
+	if lang == "" {
+		lang = sr.Country()
+	}
+
+	return sr.Country() + "-" + lang
+}
+
+func ddgSafeSearch(safe SafeSearch) string {
+	switch safe {
+	case SafeSearchStrict:
+		return "1"
+	case SafeSearchOff:
+		return "-2"
+	default:
+		return "-1"
 	}
-	f.Close()
+}
+
+// DDG takes d, w, m or y, or a custom from..to range
+func ddgFreshness(sr SearchRequest) string {
+	switch sr.Freshness() {
+	case "":
+		if sr.Since <= 0 {
+			return ""
+		}
+		from, to := sr.dateRange()
+		return from + ".." + to
+	default:
+		return string(sr.Freshness()[:1])
+	}
+}
+
+// The anomaly page asks you to pick the ducks out of a grid of images
+var ddgBlockMarkers = []string{
+	"anomaly-modal",
+	"anomaly.js",
+	"challenge-form",
+	"bots use DuckDuckGo too",
+}
+
+func isDDGBlocked(htmlContent string) bool {
+	for _, marker := range ddgBlockMarkers {
+		if strings.Contains(htmlContent, marker) {
+			return true
+		}
+	}
+	return false
+}
+
+// Result links go through DDG's redirector, eg
+// //duckduckgo.com/l/?uddg=https%3A%2F%2Fgo.dev%2F&rut=..., so dig out the
+// real URL. Anything else is returned as is.
+func decodeDDGURL(href string) string {
+	href = strings.TrimSpace(href)
 
-	results, err := extractDDGResults(string(body), maxResults, filter)
+	u, err := url.Parse(href)
 	if err != nil {
-		return nil, fmt.Errorf("failed to extract results: %v", err)
+		return href
+	}
+	if strings.HasSuffix(u.Host, "duckduckgo.com") && u.Path == "/l/" {
+		if target := u.Query().Get("uddg"); target != "" {
+			return target
+		}
+	}
+	if u.Scheme == "" && strings.HasPrefix(href, "//") {
+		return "https:" + href
 	}
 
-	return results, nil
+	return href
+}
+
+// Ads go through a different redirector and aren't search results
+func isDDGAd(href string) bool {
+	return strings.Contains(href, "duckduckgo.com/y.js")
 }
 
-func extractDDGResults(htmlContent string, maxResults int, filter FilterFunc) ([]SearchResult, error) {
+func extractDDGResults(htmlContent string) ([]SearchResult, error) {
 	doc, err := goquery.NewDocumentFromReader(strings.NewReader(htmlContent))
 	if err != nil {
 		return nil, err
 	}
 
-	var filteredResults []SearchResult
+	var results []SearchResult
 
 	doc.Find(".result").Each(func(i int, s *goquery.Selection) {
-		result := SearchResult{}
-
-		result.URL, _ = s.Find(".result__url").Attr("href")
-		result.Title = s.Find(".result__title").Text()
-		result.Snippet = s.Find(".result__snippet").Text()
+		if s.HasClass("result--ad") {
+			return
+		}
 
-		if filter == nil || filter(result) {
-			filteredResults = append(filteredResults, result)
+		href, ok := s.Find(".result__a").Attr("href")
+		if !ok {
+			href, _ = s.Find(".result__url").Attr("href")
+		}
+		if href == "" || isDDGAd(href) {
+			return
 		}
+
+		results = append(results, SearchResult{
+			Title:   strings.TrimSpace(s.Find(".result__title").Text()),
+			URL:     decodeDDGURL(href),
+			Snippet: strings.TrimSpace(s.Find(".result__snippet").Text()),
+		})
 	})
 
-	if len(filteredResults) < maxResults {
-		return filteredResults, nil
+	return results, nil
+}
+
+// The lite page is a table with a row for the link, one for the snippet and
+// one for the display URL for each result
+func extractDDGLiteResults(htmlContent string) ([]SearchResult, error) {
+	doc, err := goquery.NewDocumentFromReader(strings.NewReader(htmlContent))
+	if err != nil {
+		return nil, err
 	}
 
-	return filteredResults[:maxResults], nil
+	var results []SearchResult
+
+	doc.Find("a.result-link").Each(func(i int, s *goquery.Selection) {
+		href, _ := s.Attr("href")
+		if href == "" || isDDGAd(href) {
+			return
+		}
+
+		row := s.Closest("tr")
+		snippet := row.NextAllFiltered("tr").First().Find(".result-snippet")
+
+		results = append(results, SearchResult{
+			Title:   strings.TrimSpace(s.Text()),
+			URL:     decodeDDGURL(href),
+			Snippet: strings.TrimSpace(snippet.Text()),
+		})
+	})
+
+	return results, nil
 }
@@ -0,0 +1,222 @@
+package search
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestDecodeDDGURL(t *testing.T) {
+	testCases := []struct {
+		input    string
+		expected string
+	}{
+		{"//duckduckgo.com/l/?uddg=https%3A%2F%2Fgo.dev%2Fdoc%2F&rut=abc123", "https://go.dev/doc/"},
+		{"https://duckduckgo.com/l/?uddg=https%3A%2F%2Fexample.com%2F%3Fa%3D1%26b%3D2", "https://example.com/?a=1&b=2"},
+		{"https://go.dev/doc/", "https://go.dev/doc/"},
+		{"//example.com/page", "https://example.com/page"},
+		{"//duckduckgo.com/l/?rut=abc", "https://duckduckgo.com/l/?rut=abc"},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.input, func(t *testing.T) {
+			if got := decodeDDGURL(tc.input); got != tc.expected {
+				t.Errorf("decodeDDGURL(%q) = %q; want %q", tc.input, got, tc.expected)
+			}
+		})
+	}
+}
+
+func TestExtractDDGResults(t *testing.T) {
+	html := `<html><body>
+	<div class="result results_links result--ad">
+	  <h2 class="result__title"><a class="result__a" href="https://duckduckgo.com/y.js?ad_domain=ads.com">Ad</a></h2>
+	</div>
+	<div class="result results_links">
+	  <h2 class="result__title"><a class="result__a" href="//duckduckgo.com/l/?uddg=https%3A%2F%2Fgo.dev%2Fblog%2Frange-functions&rut=x">
+	    Range Over Function Types</a></h2>
+	  <a class="result__url" href="//duckduckgo.com/l/?uddg=https%3A%2F%2Fgo.dev%2Fblog%2Frange-functions&rut=x">go.dev/blog/range-functions</a>
+	  <a class="result__snippet"> Go 1.23 adds range over function types. </a>
+	</div>
+	<div class="result results_links">
+	  <h2 class="result__title"><a class="result__a" href="https://pkg.go.dev/iter">iter package</a></h2>
+	  <a class="result__snippet">Package iter provides basic definitions.</a>
+	</div>
+	</body></html>`
+
+	results, err := extractDDGResults(html)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	expected := []SearchResult{
+		{Title: "Range Over Function Types", URL: "https://go.dev/blog/range-functions", Snippet: "Go 1.23 adds range over function types."},
+		{Title: "iter package", URL: "https://pkg.go.dev/iter", Snippet: "Package iter provides basic definitions."},
+	}
+	if !reflect.DeepEqual(results, expected) {
+		t.Errorf("Expected %+v, got %+v", expected, results)
+	}
+}
+
+func TestExtractDDGLiteResults(t *testing.T) {
+	html := `<html><body><table>
+	<tr><td>1.&nbsp;</td><td><a rel="nofollow" href="//duckduckgo.com/l/?uddg=https%3A%2F%2Fgo.dev%2Fblog%2Frange-functions&amp;rut=x" class="result-link">Range Over Function Types</a></td></tr>
+	<tr><td>&nbsp;</td><td class="result-snippet">Go 1.23 adds range over function types.</td></tr>
+	<tr><td>&nbsp;</td><td><span class="link-text">go.dev/blog/range-functions</span></td></tr>
+	<tr><td>2.&nbsp;</td><td><a rel="nofollow" href="https://pkg.go.dev/iter" class="result-link">iter package</a></td></tr>
+	<tr><td>&nbsp;</td><td class="result-snippet">Package iter provides basic definitions.</td></tr>
+	<tr><td>&nbsp;</td><td><span class="link-text">pkg.go.dev/iter</span></td></tr>
+	</table></body></html>`
+
+	results, err := extractDDGLiteResults(html)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	expected := []SearchResult{
+		{Title: "Range Over Function Types", URL: "https://go.dev/blog/range-functions", Snippet: "Go 1.23 adds range over function types."},
+		{Title: "iter package", URL: "https://pkg.go.dev/iter", Snippet: "Package iter provides basic definitions."},
+	}
+	if !reflect.DeepEqual(results, expected) {
+		t.Errorf("Expected %+v, got %+v", expected, results)
+	}
+}
+
+func TestIsDDGBlocked(t *testing.T) {
+	blocked := `<html><body><div class="anomaly-modal__title">Unfortunately, bots use DuckDuckGo too.</div></body></html>`
+	if !isDDGBlocked(blocked) {
+		t.Error("Expected the anomaly page to be detected")
+	}
+	if isDDGBlocked(`<html><body><div class="result">Bots and anomalies in statistics</div></body></html>`) {
+		t.Error("Expected an ordinary results page not to be detected")
+	}
+}
+
+func TestDDGSearchRequest(t *testing.T) {
+	var requests []*http.Request
+	server := fixtureServer(t, map[string]string{"/html/": "ddg.html"}, &requests)
+
+	engine := &DDGEngine{BaseURL: server.URL + "/html/", Client: server.Client()}
+	results, err := engine.Search(context.Background(), SearchRequest{
+		Query:      "golang programming",
+		MaxResults: 3,
+		Region:     "de-de",
+		SafeSearch: SafeSearchStrict,
+		Since:      7 * 24 * time.Hour,
+		Filter: func(r SearchResult) bool {
+			return !strings.Contains(r.URL, "wikipedia.org")
+		},
+	})
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	expected := []string{"https://go.dev/", "https://go.dev/tour/", "https://gobyexample.com/"}
+	if !reflect.DeepEqual(urls(results), expected) {
+		t.Errorf("Expected %v, got %v", expected, urls(results))
+	}
+	if results[0].Snippet != "Go is an open source programming language that makes it simple to build secure, scalable systems." {
+		t.Errorf("Unexpected snippet: %q", results[0].Snippet)
+	}
+
+	if len(requests) != 1 {
+		t.Fatalf("Expected 1 request, got %d", len(requests))
+	}
+	want := map[string]string{"q": "golang programming", "kl": "de-de", "kp": "1", "df": "w"}
+	for k, v := range want {
+		if got := requests[0].URL.Query().Get(k); got != v {
+			t.Errorf("Expected %s=%q, got %q", k, v, got)
+		}
+	}
+	if ua := requests[0].Header.Get("User-Agent"); ua != ddgUserAgent {
+		t.Errorf("Expected the browser user agent, got %q", ua)
+	}
+}
+
+func TestDDGSearchPaging(t *testing.T) {
+	var requests []*http.Request
+	server := fixtureServer(t, map[string]string{"/html/": "ddg.html"}, &requests)
+
+	engine := &DDGEngine{BaseURL: server.URL + "/html/", Client: server.Client()}
+	results, err := engine.Search(context.Background(), SearchRequest{Query: "golang programming", MaxResults: 10})
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	// The server hands back the same page, so paging stops after the second
+	if len(results) != 4 {
+		t.Errorf("Expected 4 results, got %d", len(results))
+	}
+	if len(requests) != 2 {
+		t.Fatalf("Expected 2 requests, got %d", len(requests))
+	}
+	if q := requests[1].URL.Query(); q.Get("s") != "4" || q.Get("dc") != "5" {
+		t.Errorf("Expected s=4 and dc=5 for the second page, got %q and %q", q.Get("s"), q.Get("dc"))
+	}
+}
+
+func TestDDGSearchLiteFallback(t *testing.T) {
+	testCases := []struct {
+		name    string
+		handler http.HandlerFunc
+	}{
+		{
+			name: "Captcha",
+			handler: func(w http.ResponseWriter, r *http.Request) {
+				w.Write(fixture(t, "ddg_blocked.html"))
+			},
+		},
+		{
+			name: "Empty 202",
+			handler: func(w http.ResponseWriter, r *http.Request) {
+				w.WriteHeader(http.StatusAccepted)
+			},
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			htmlRequests := 0
+			var liteRequests []*http.Request
+			lite := fixtureServer(t, map[string]string{"/lite/": "ddg_lite.html"}, &liteRequests)
+			html := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				htmlRequests++
+				tc.handler(w, r)
+			}))
+			defer html.Close()
+
+			engine := &DDGEngine{BaseURL: html.URL + "/html/", LiteURL: lite.URL + "/lite/"}
+			results, err := engine.Search(context.Background(), SearchRequest{Query: "golang programming", MaxResults: 10})
+			if err != nil {
+				t.Fatalf("Unexpected error: %v", err)
+			}
+
+			expected := []string{"https://go.dev/", "https://gobyexample.com/"}
+			if !reflect.DeepEqual(urls(results), expected) {
+				t.Errorf("Expected %v, got %v", expected, urls(results))
+			}
+			// Once blocked, the later pages go straight to lite
+			if htmlRequests != 1 || len(liteRequests) != 2 {
+				t.Errorf("Expected 1 HTML and 2 lite requests, got %d and %d", htmlRequests, len(liteRequests))
+			}
+		})
+	}
+}
+
+func TestDDGSearchBlocked(t *testing.T) {
+	server := fixtureServer(t, map[string]string{
+		"/html/": "ddg_blocked.html",
+		"/lite/": "ddg_blocked.html",
+	}, nil)
+
+	engine := &DDGEngine{BaseURL: server.URL + "/html/", LiteURL: server.URL + "/lite/", Client: server.Client()}
+	_, err := engine.Search(context.Background(), SearchRequest{Query: "golang programming", MaxResults: 3})
+	if !errors.Is(err, ErrBlocked) {
+		t.Errorf("Expected ErrBlocked, got %v", err)
+	}
+}
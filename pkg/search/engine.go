@@ -0,0 +1,108 @@
+package search
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	"ask-web/pkg/config"
+)
+
+// SearchEngine is implemented by every search backend. Backends register a
+// factory for themselves (see Register) so that the set of engines used for a
+// run can be driven entirely from the config file and the command line.
+type SearchEngine interface {
+	Name() string
+	Search(ctx context.Context, req SearchRequest) ([]SearchResult, error)
+	Capabilities() Capabilities
+}
+
+// Capabilities describes what a backend needs and what it can do, so callers
+// don't have to special-case engines by name.
+type Capabilities struct {
+	// RequiresKey is true if the engine can't be used without an API key
+	RequiresKey bool
+	// MaxResultsPerRequest is the most results a single request can return;
+	// 0 means the engine doesn't document a limit
+	MaxResultsPerRequest int
+	// MinInterval is the shortest gap to leave between requests
+	MinInterval time.Duration
+	// DailyQuota is how many requests can be made per day; 0 means no limit
+	DailyQuota int
+}
+
+// EngineConfig is everything a factory might need to build its engine.
+type EngineConfig struct {
+	Keys APIKeys
+	Opts *config.Opts
+	// Pages is the history of downloaded pages, for the history engine
+	Pages PageIndex
+	// Indexes keeps the indexes of engines that search this machine
+	Indexes IndexStore
+}
+
+type EngineFactory func(cfg EngineConfig) (SearchEngine, error)
+
+// ErrNotConfigured is returned by a factory when the engine is missing
+// something it needs (usually an API key) and should just be skipped.
+var ErrNotConfigured = errors.New("engine not configured")
+
+var registry = make(map[string]EngineFactory)
+
+// Register makes an engine available by name. It's meant to be called from
+// an init() in the file that implements the engine.
+func Register(name string, factory EngineFactory) {
+	if _, ok := registry[name]; ok {
+		panic(fmt.Sprintf("search engine %q registered twice", name))
+	}
+	registry[name] = factory
+}
+
+// Registered returns the names of all known engines, sorted.
+func Registered() []string {
+	names := make([]string, 0, len(registry))
+	for name := range registry {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	return names
+}
+
+// NewEngines builds the engines in the order given by names, leaving out any
+// that are in disabled. Engines that can't be built are skipped and the
+// reason is returned in errs so the caller can decide how loud to be about it.
+func NewEngines(names []string, disabled []string, cfg EngineConfig) (engines []SearchEngine, errs []error) {
+	skip := make(map[string]bool)
+	for _, name := range disabled {
+		skip[strings.ToLower(strings.TrimSpace(name))] = true
+	}
+
+	seen := make(map[string]bool)
+	for _, name := range names {
+		name = strings.ToLower(strings.TrimSpace(name))
+		if name == "" || skip[name] || seen[name] {
+			continue
+		}
+		seen[name] = true
+
+		factory, ok := registry[name]
+		if !ok {
+			errs = append(errs, fmt.Errorf("unknown search engine %q (known engines: %s)",
+				name, strings.Join(Registered(), ", ")))
+			continue
+		}
+
+		engine, err := factory(cfg)
+		if err != nil {
+			errs = append(errs, fmt.Errorf("%s: %w", name, err))
+			continue
+		}
+		engines = append(engines, engine)
+	}
+
+	return engines, errs
+}
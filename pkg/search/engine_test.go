@@ -0,0 +1,93 @@
+package search
+
+import (
+	"errors"
+	"testing"
+)
+
+func engineNames(engines []SearchEngine) []string {
+	var names []string
+	for _, e := range engines {
+		names = append(names, e.Name())
+	}
+	return names
+}
+
+func TestNewEngines(t *testing.T) {
+	keys := APIKeys{
+		GoogleAPIKey: "google_key",
+		GoogleCSEID:  "google_cse",
+	}
+
+	testCases := []struct {
+		name     string
+		names    []string
+		disabled []string
+		keys     APIKeys
+		expected []string
+		numErrs  int
+	}{
+		{
+			name:     "Order is preserved",
+			names:    []string{"google", "duckduckgo"},
+			keys:     keys,
+			expected: []string{"google", "duckduckgo"},
+		},
+		{
+			name:     "Disabled engines are skipped",
+			names:    []string{"duckduckgo", "google"},
+			disabled: []string{"google"},
+			keys:     keys,
+			expected: []string{"duckduckgo"},
+		},
+		{
+			name:     "Duplicates and case are ignored",
+			names:    []string{"DuckDuckGo", "duckduckgo", " google "},
+			keys:     keys,
+			expected: []string{"duckduckgo", "google"},
+		},
+		{
+			name:     "Missing keys",
+			names:    []string{"duckduckgo", "bing"},
+			keys:     keys,
+			expected: []string{"duckduckgo"},
+			numErrs:  1,
+		},
+		{
+			name:     "Unknown engine",
+			names:    []string{"altavista", "duckduckgo"},
+			expected: []string{"duckduckgo"},
+			numErrs:  1,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			engines, errs := NewEngines(tc.names, tc.disabled, EngineConfig{Keys: tc.keys})
+
+			got := engineNames(engines)
+			if len(got) != len(tc.expected) {
+				t.Fatalf("Expected engines %v, got %v", tc.expected, got)
+			}
+			for i := range got {
+				if got[i] != tc.expected[i] {
+					t.Errorf("Expected engines %v, got %v", tc.expected, got)
+				}
+			}
+
+			if len(errs) != tc.numErrs {
+				t.Errorf("Expected %d errors, got %d: %v", tc.numErrs, len(errs), errs)
+			}
+		})
+	}
+}
+
+func TestNewEnginesNotConfigured(t *testing.T) {
+	_, errs := NewEngines([]string{"google"}, nil, EngineConfig{})
+	if len(errs) != 1 {
+		t.Fatalf("Expected 1 error, got %d", len(errs))
+	}
+	if !errors.Is(errs[0], ErrNotConfigured) {
+		t.Errorf("Expected ErrNotConfigured, got %v", errs[0])
+	}
+}
@@ -0,0 +1,146 @@
+package search
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// What went wrong with a backend, for errors.Is. An EngineError's Kind is one
+// of these, or nil for failures that don't fit (bad responses and the like).
+var (
+	// ErrRateLimited means the engine wants us to slow down; see
+	// EngineError.RetryAfter
+	ErrRateLimited = errors.New("rate limited")
+	// ErrAuthFailed means the API key (or similar) was rejected
+	ErrAuthFailed = errors.New("authentication failed")
+	// ErrQuotaExceeded means the engine's daily quota is used up, either by
+	// our own count (see LimitedEngine) or the engine's; callers should treat
+	// the engine as skipped rather than broken
+	ErrQuotaExceeded = errors.New("daily quota used up")
+	// ErrBlocked means the engine served a captcha or otherwise decided we're
+	// a bot
+	ErrBlocked = errors.New("blocked by captcha")
+	// ErrTransient is for network errors and 5xx responses that are worth
+	// retrying
+	ErrTransient = errors.New("transient failure")
+)
+
+// EngineError is returned by the backends for failed requests.
+type EngineError struct {
+	Engine string
+	// Kind is ErrRateLimited, ErrAuthFailed, ErrQuotaExceeded, ErrBlocked,
+	// ErrTransient or nil
+	Kind       error
+	StatusCode int
+	// RetryAfter is how long the engine asked us to wait, if it said
+	RetryAfter time.Duration
+	Message    string
+	Err        error
+}
+
+func (e *EngineError) Error() string {
+	var b strings.Builder
+	b.WriteString(e.Engine)
+	if e.Kind != nil {
+		b.WriteString(": " + e.Kind.Error())
+	}
+	if e.StatusCode != 0 {
+		fmt.Fprintf(&b, " (%d)", e.StatusCode)
+	}
+	if e.Message != "" {
+		b.WriteString(": " + e.Message)
+	}
+	if e.Err != nil {
+		b.WriteString(": " + e.Err.Error())
+	}
+	return b.String()
+}
+
+func (e *EngineError) Unwrap() []error {
+	var errs []error
+	if e.Kind != nil {
+		errs = append(errs, e.Kind)
+	}
+	if e.Err != nil {
+		errs = append(errs, e.Err)
+	}
+	return errs
+}
+
+// Retryable is true for failures that might go away if the same request is
+// made again later.
+func (e *EngineError) Retryable() bool {
+	return e.Kind == ErrRateLimited || e.Kind == ErrTransient
+}
+
+// IsRetryable reports whether err is an EngineError worth retrying.
+func IsRetryable(err error) bool {
+	var engineErr *EngineError
+	return errors.As(err, &engineErr) && engineErr.Retryable()
+}
+
+// Longest error body worth quoting in a message
+const maxErrorBody = 200
+
+// classifyResponse turns a non-200 response into an EngineError. body is
+// (the start of) the response body, which some APIs use to say why.
+func classifyResponse(engine string, resp *http.Response, body []byte) *EngineError {
+	engineErr := &EngineError{
+		Engine:     engine,
+		StatusCode: resp.StatusCode,
+		Message:    strings.TrimSpace(string(body)),
+	}
+	if len(engineErr.Message) > maxErrorBody {
+		engineErr.Message = engineErr.Message[:maxErrorBody] + "..."
+	}
+
+	// Google reports a used up quota as a 429 or 403 that mentions it
+	quota := strings.Contains(strings.ToLower(string(body)), "quota")
+
+	switch code := resp.StatusCode; {
+	case quota && (code == http.StatusTooManyRequests || code == http.StatusForbidden):
+		engineErr.Kind = ErrQuotaExceeded
+	case code == http.StatusTooManyRequests:
+		engineErr.Kind = ErrRateLimited
+		engineErr.RetryAfter = parseRetryAfter(resp.Header.Get("Retry-After"), time.Now())
+	case code == http.StatusUnauthorized || code == http.StatusForbidden:
+		engineErr.Kind = ErrAuthFailed
+	case code == http.StatusRequestTimeout || code == http.StatusTooEarly || code >= 500:
+		engineErr.Kind = ErrTransient
+		engineErr.RetryAfter = parseRetryAfter(resp.Header.Get("Retry-After"), time.Now())
+	}
+
+	return engineErr
+}
+
+// classifyNetworkError wraps an error from http.Client.Do. Running out of
+// time isn't worth retrying; anything else at this level usually is.
+func classifyNetworkError(engine string, err error) error {
+	if errors.Is(err, context.Canceled) || errors.Is(err, context.DeadlineExceeded) {
+		return err
+	}
+	return &EngineError{Engine: engine, Kind: ErrTransient, Err: err}
+}
+
+// Retry-After is either a number of seconds or an HTTP date
+func parseRetryAfter(value string, now time.Time) time.Duration {
+	value = strings.TrimSpace(value)
+	if value == "" {
+		return 0
+	}
+
+	if seconds, err := strconv.Atoi(value); err == nil {
+		return max(time.Duration(seconds)*time.Second, 0)
+	}
+
+	if t, err := http.ParseTime(value); err == nil {
+		return max(t.Sub(now), 0)
+	}
+
+	return 0
+}
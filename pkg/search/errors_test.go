@@ -0,0 +1,91 @@
+package search
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"testing"
+	"time"
+)
+
+func TestClassifyResponse(t *testing.T) {
+	testCases := []struct {
+		name       string
+		status     int
+		retryAfter string
+		body       string
+		kind       error
+		wait       time.Duration
+	}{
+		{"Rate limited", http.StatusTooManyRequests, "3", "", ErrRateLimited, 3 * time.Second},
+		{"Quota", http.StatusTooManyRequests, "", `{"error": {"message": "Quota exceeded for quota metric 'Queries'"}}`, ErrQuotaExceeded, 0},
+		{"Forbidden quota", http.StatusForbidden, "", "Daily Limit Exceeded. The quota will be reset at midnight", ErrQuotaExceeded, 0},
+		{"Bad key", http.StatusUnauthorized, "", "invalid key", ErrAuthFailed, 0},
+		{"Forbidden", http.StatusForbidden, "", "", ErrAuthFailed, 0},
+		{"Unavailable", http.StatusServiceUnavailable, "1", "", ErrTransient, time.Second},
+		{"Bad gateway", http.StatusBadGateway, "", "", ErrTransient, 0},
+		{"Bad request", http.StatusBadRequest, "", "missing q", nil, 0},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			resp := &http.Response{StatusCode: tc.status, Header: http.Header{}}
+			if tc.retryAfter != "" {
+				resp.Header.Set("Retry-After", tc.retryAfter)
+			}
+
+			err := classifyResponse("google", resp, []byte(tc.body))
+			if err.Kind != tc.kind {
+				t.Errorf("Expected kind %v, got %v", tc.kind, err.Kind)
+			}
+			if tc.kind != nil && !errors.Is(err, tc.kind) {
+				t.Errorf("Expected errors.Is(%v, %v)", err, tc.kind)
+			}
+			if err.RetryAfter != tc.wait {
+				t.Errorf("Expected Retry-After %s, got %s", tc.wait, err.RetryAfter)
+			}
+		})
+	}
+}
+
+func TestEngineErrorWrapping(t *testing.T) {
+	err := fmt.Errorf("searching: %w", &EngineError{Engine: "bing", Kind: ErrTransient, Err: context.DeadlineExceeded})
+
+	if !errors.Is(err, ErrTransient) || !errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf("Expected both the kind and the cause to be found in %v", err)
+	}
+	if !IsRetryable(err) {
+		t.Errorf("Expected %v to be retryable", err)
+	}
+	if IsRetryable(&EngineError{Engine: "bing", Kind: ErrAuthFailed}) {
+		t.Error("Expected auth failures not to be retryable")
+	}
+	if got := err.Error(); got != "searching: bing: transient failure: context deadline exceeded" {
+		t.Errorf("Unexpected message: %q", got)
+	}
+}
+
+func TestParseRetryAfter(t *testing.T) {
+	now := time.Date(2024, 6, 15, 12, 0, 0, 0, time.UTC)
+
+	testCases := []struct {
+		value    string
+		expected time.Duration
+	}{
+		{"", 0},
+		{"120", 2 * time.Minute},
+		{"-5", 0},
+		{"Sat, 15 Jun 2024 12:00:30 GMT", 30 * time.Second},
+		{"Sat, 15 Jun 2024 11:00:00 GMT", 0},
+		{"soon", 0},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.value, func(t *testing.T) {
+			if got := parseRetryAfter(tc.value, now); got != tc.expected {
+				t.Errorf("parseRetryAfter(%q) = %s; want %s", tc.value, got, tc.expected)
+			}
+		})
+	}
+}
@@ -0,0 +1,123 @@
+package search
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+)
+
+// EngineResult is what one engine produced for one query during SearchAll.
+// Err is set if the engine failed, in which case Results is empty. Cached is
+// true if the results came from a ResultCache rather than the engine itself.
+type EngineResult struct {
+	Engine   string
+	Query    string
+	Results  []SearchResult
+	Err      error
+	Duration time.Duration
+	Cached   bool
+}
+
+// SearchAll queries every engine in parallel. Each engine gets its own
+// timeout (if engineTimeout > 0) on top of whatever deadline ctx already has.
+// A failing engine doesn't stop the others; its error is recorded in the
+// returned slice, which is in the same order as engines.
+func SearchAll(ctx context.Context, engines []SearchEngine, req SearchRequest, engineTimeout time.Duration) []EngineResult {
+	return SearchAllQueries(ctx, engines, req, []string{req.Query}, engineTimeout)
+}
+
+// SearchAllQueries is SearchAll for several variants of the query at once;
+// req.Query is replaced by each of queries in turn. Every engine/query pair
+// runs in parallel and the results are ordered by query, then engine.
+func SearchAllQueries(ctx context.Context, engines []SearchEngine, req SearchRequest, queries []string, engineTimeout time.Duration) []EngineResult {
+	results := make([]EngineResult, len(engines)*len(queries))
+
+	var wg sync.WaitGroup
+	for qi, query := range queries {
+		queryReq := req
+		queryReq.Query = query
+
+		for ei, engine := range engines {
+			wg.Add(1)
+			go func(i int, engine SearchEngine, req SearchRequest) {
+				defer wg.Done()
+				results[i] = runEngine(ctx, engine, req, engineTimeout)
+			}(qi*len(engines)+ei, engine, queryReq)
+		}
+	}
+	wg.Wait()
+
+	return results
+}
+
+func runEngine(ctx context.Context, engine SearchEngine, req SearchRequest, timeout time.Duration) (er EngineResult) {
+	er.Engine = engine.Name()
+	er.Query = req.Query
+
+	if timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, timeout)
+		defer cancel()
+	}
+
+	ctx = context.WithValue(ctx, cacheHitKey{}, &er.Cached)
+
+	start := time.Now()
+	defer func() {
+		er.Duration = time.Since(start)
+		// One misbehaving backend shouldn't take the whole run down with it
+		if r := recover(); r != nil {
+			er.Results = nil
+			er.Err = fmt.Errorf("panic: %v", r)
+		}
+	}()
+
+	results, err := engine.Search(ctx, req)
+	if err == nil && ctx.Err() != nil {
+		// Engines that ignore ctx might still return after the deadline
+		err = ctx.Err()
+	}
+	if err != nil {
+		er.Err = err
+		return er
+	}
+	er.Results = results
+
+	return er
+}
+
+// Contributors returns a short, human readable summary of which engines
+// returned results, eg "duckduckgo (3), google (2, cached)". Counts are
+// totals across all queries; "cached" means none of them needed the engine.
+func Contributors(results []EngineResult) string {
+	var order []string
+	counts := make(map[string]int)
+	cached := make(map[string]bool)
+	for _, r := range results {
+		if r.Err == nil && len(r.Results) > 0 {
+			if _, ok := counts[r.Engine]; !ok {
+				order = append(order, r.Engine)
+				cached[r.Engine] = true
+			}
+			counts[r.Engine] += len(r.Results)
+			cached[r.Engine] = cached[r.Engine] && r.Cached
+		}
+	}
+
+	var parts []string
+	for _, engine := range order {
+		if cached[engine] {
+			parts = append(parts, fmt.Sprintf("%s (%d, cached)", engine, counts[engine]))
+		} else {
+			parts = append(parts, fmt.Sprintf("%s (%d)", engine, counts[engine]))
+		}
+	}
+
+	if len(parts) == 0 {
+		return "none"
+	}
+
+	return strings.Join(parts, ", ")
+}
@@ -0,0 +1,125 @@
+package search
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+)
+
+type fakeEngine struct {
+	name    string
+	results []SearchResult
+	err     error
+	delay   time.Duration
+}
+
+func (f *fakeEngine) Name() string               { return f.name }
+func (f *fakeEngine) Capabilities() Capabilities { return Capabilities{} }
+
+func (f *fakeEngine) Search(ctx context.Context, req SearchRequest) ([]SearchResult, error) {
+	select {
+	case <-time.After(f.delay):
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	}
+
+	return f.results, f.err
+}
+
+func TestSearchAll(t *testing.T) {
+	engines := []SearchEngine{
+		&fakeEngine{name: "good", results: []SearchResult{{URL: "https://example.com/a"}}},
+		&fakeEngine{name: "broken", err: errors.New("boom")},
+		&fakeEngine{name: "slow", delay: time.Second, results: []SearchResult{{URL: "https://example.com/b"}}},
+		&fakeEngine{name: "also-good", results: []SearchResult{{URL: "https://example.com/c"}, {URL: "https://example.com/d"}}},
+	}
+
+	start := time.Now()
+	results := SearchAll(context.Background(), engines, SearchRequest{Query: "q"}, 50*time.Millisecond)
+	if elapsed := time.Since(start); elapsed > 500*time.Millisecond {
+		t.Errorf("SearchAll took %s; the slow engine should have timed out", elapsed)
+	}
+
+	if len(results) != len(engines) {
+		t.Fatalf("Expected %d results, got %d", len(engines), len(results))
+	}
+
+	for i, r := range results {
+		if r.Engine != engines[i].Name() {
+			t.Errorf("Expected result %d to be from %s, got %s", i, engines[i].Name(), r.Engine)
+		}
+	}
+
+	if results[0].Err != nil || len(results[0].Results) != 1 {
+		t.Errorf("Expected 1 result and no error from good, got %v, %v", results[0].Results, results[0].Err)
+	}
+	if results[1].Err == nil {
+		t.Error("Expected an error from broken")
+	}
+	if !errors.Is(results[2].Err, context.DeadlineExceeded) {
+		t.Errorf("Expected slow to hit its deadline, got %v", results[2].Err)
+	}
+	if results[3].Err != nil || len(results[3].Results) != 2 {
+		t.Errorf("Expected 2 results and no error from also-good, got %v, %v", results[3].Results, results[3].Err)
+	}
+
+	if got := Contributors(results); got != "good (1), also-good (2)" {
+		t.Errorf("Unexpected contributors: %q", got)
+	}
+}
+
+func TestSearchAllOverallDeadline(t *testing.T) {
+	engines := []SearchEngine{
+		&fakeEngine{name: "slow", delay: time.Second},
+		&fakeEngine{name: "slower", delay: 2 * time.Second},
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+
+	results := SearchAll(ctx, engines, SearchRequest{Query: "q"}, 0)
+	for _, r := range results {
+		if r.Err == nil {
+			t.Errorf("Expected %s to fail once the overall deadline passed", r.Engine)
+		}
+	}
+
+	if got := Contributors(results); got != "none" {
+		t.Errorf("Unexpected contributors: %q", got)
+	}
+}
+
+type echoEngine struct {
+	name string
+}
+
+func (e *echoEngine) Name() string               { return e.name }
+func (e *echoEngine) Capabilities() Capabilities { return Capabilities{} }
+
+func (e *echoEngine) Search(ctx context.Context, req SearchRequest) ([]SearchResult, error) {
+	return []SearchResult{{URL: "https://example.com/" + e.name + "/" + req.Query}}, nil
+}
+
+func TestSearchAllQueries(t *testing.T) {
+	engines := []SearchEngine{&echoEngine{name: "one"}, &echoEngine{name: "two"}}
+
+	results := SearchAllQueries(context.Background(), engines, SearchRequest{MaxResults: 3}, []string{"a", "b"}, 0)
+
+	expected := []string{"one/a", "two/a", "one/b", "two/b"}
+	if len(results) != len(expected) {
+		t.Fatalf("Expected %d results, got %d", len(expected), len(results))
+	}
+	for i, r := range results {
+		if r.Engine+"/"+r.Query != expected[i] {
+			t.Errorf("Expected result %d to be %s, got %s/%s", i, expected[i], r.Engine, r.Query)
+		}
+		if r.Results[0].URL != "https://example.com/"+expected[i] {
+			t.Errorf("Engine didn't get the query: %s", r.Results[0].URL)
+		}
+	}
+
+	if got := Contributors(results); got != "one (2), two (2)" {
+		t.Errorf("Unexpected contributors: %q", got)
+	}
+}
@@ -0,0 +1,93 @@
+package search
+
+import (
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Freshness is the coarse time range that most engines let you ask for
+type Freshness string
+
+const (
+	FreshnessDay   Freshness = "day"
+	FreshnessWeek  Freshness = "week"
+	FreshnessMonth Freshness = "month"
+	FreshnessYear  Freshness = "year"
+)
+
+const (
+	day   = 24 * time.Hour
+	week  = 7 * day
+	month = 31 * day
+	year  = 366 * day
+)
+
+// Swapped out in tests
+var now = time.Now
+
+// ParseSince parses how far back results should go: a number followed by d,
+// w, m or y (eg "7d", "1m", "2y"), or anything time.ParseDuration accepts (eg
+// "36h"). An empty string means no limit.
+func ParseSince(s string) (time.Duration, error) {
+	s = strings.ToLower(strings.TrimSpace(s))
+	if s == "" {
+		return 0, nil
+	}
+
+	units := map[byte]time.Duration{'d': day, 'w': week, 'm': 30 * day, 'y': 365 * day}
+	if unit, ok := units[s[len(s)-1]]; ok {
+		if n, err := strconv.Atoi(s[:len(s)-1]); err == nil && n > 0 {
+			return time.Duration(n) * unit, nil
+		}
+	}
+
+	d, err := time.ParseDuration(s)
+	if err != nil || d <= 0 {
+		return 0, fmt.Errorf("invalid time range %q (want eg 7d, 2w, 1m or 1y)", s)
+	}
+
+	return d, nil
+}
+
+// Freshness returns the smallest of the engines' usual time ranges that covers
+// Since, or "" if there's no limit or it's longer than a year. Engines that
+// can only do these ranges may return somewhat older results than asked for.
+func (r SearchRequest) Freshness() Freshness {
+	switch {
+	case r.Since <= 0:
+		return ""
+	case r.Since <= day:
+		return FreshnessDay
+	case r.Since <= week:
+		return FreshnessWeek
+	case r.Since <= month:
+		return FreshnessMonth
+	case r.Since <= year:
+		return FreshnessYear
+	default:
+		return ""
+	}
+}
+
+// SinceDate returns the oldest date results should have, or the zero time if
+// there's no limit.
+func (r SearchRequest) SinceDate() time.Time {
+	if r.Since <= 0 {
+		return time.Time{}
+	}
+	return now().Add(-r.Since)
+}
+
+// sinceDays is Since rounded up to whole days
+func (r SearchRequest) sinceDays() int {
+	return int(math.Ceil(float64(r.Since) / float64(day)))
+}
+
+// dateRange returns the start and end dates (today) for Since, for engines that
+// take a custom range
+func (r SearchRequest) dateRange() (string, string) {
+	return r.SinceDate().Format(time.DateOnly), now().Format(time.DateOnly)
+}
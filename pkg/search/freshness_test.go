@@ -0,0 +1,79 @@
+package search
+
+import (
+	"testing"
+	"time"
+)
+
+func TestParseSince(t *testing.T) {
+	testCases := []struct {
+		input    string
+		expected time.Duration
+		wantErr  bool
+	}{
+		{"", 0, false},
+		{"7d", 7 * 24 * time.Hour, false},
+		{"2w", 14 * 24 * time.Hour, false},
+		{"1M", 30 * 24 * time.Hour, false},
+		{"1y", 365 * 24 * time.Hour, false},
+		{"36h", 36 * time.Hour, false},
+		{"0d", 0, true},
+		{"-1d", 0, true},
+		{"soon", 0, true},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.input, func(t *testing.T) {
+			got, err := ParseSince(tc.input)
+			if (err != nil) != tc.wantErr {
+				t.Fatalf("ParseSince(%q) error = %v, wantErr %v", tc.input, err, tc.wantErr)
+			}
+			if got != tc.expected {
+				t.Errorf("ParseSince(%q) = %v; want %v", tc.input, got, tc.expected)
+			}
+		})
+	}
+}
+
+func TestFreshness(t *testing.T) {
+	defer func(orig func() time.Time) { now = orig }(now)
+	now = func() time.Time { return time.Date(2024, 6, 15, 12, 0, 0, 0, time.UTC) }
+
+	testCases := []struct {
+		since     string
+		freshness Freshness
+		ddg       string
+		bing      string
+		brave     string
+	}{
+		{"", "", "", "", ""},
+		{"12h", FreshnessDay, "d", "Day", "pd"},
+		{"3d", FreshnessWeek, "w", "Week", "pw"},
+		{"1m", FreshnessMonth, "m", "Month", "pm"},
+		{"6m", FreshnessYear, "y", "2023-12-18..2024-06-15", "py"},
+		{"2y", "", "2022-06-16..2024-06-15", "2022-06-16..2024-06-15", "2022-06-16to2024-06-15"},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.since, func(t *testing.T) {
+			since, err := ParseSince(tc.since)
+			if err != nil {
+				t.Fatalf("ParseSince(%q) failed: %v", tc.since, err)
+			}
+			sr := SearchRequest{Since: since}
+
+			if got := sr.Freshness(); got != tc.freshness {
+				t.Errorf("Freshness() = %q; want %q", got, tc.freshness)
+			}
+			if got := ddgFreshness(sr); got != tc.ddg {
+				t.Errorf("ddgFreshness() = %q; want %q", got, tc.ddg)
+			}
+			if got := bingFreshness(sr); got != tc.bing {
+				t.Errorf("bingFreshness() = %q; want %q", got, tc.bing)
+			}
+			if got := braveFreshness(sr); got != tc.brave {
+				t.Errorf("braveFreshness() = %q; want %q", got, tc.brave)
+			}
+		})
+	}
+}
@@ -0,0 +1,136 @@
+package search
+
+import (
+	"net/url"
+	"sort"
+	"strings"
+)
+
+// RRFConstant is the k in reciprocal-rank fusion, score = weight / (k + rank).
+// 60 is the value from the original paper and works well enough that nobody
+// seems to bother tuning it.
+const RRFConstant = 60.0
+
+// Fuse merges the rankings from several engines (and queries) into a single
+// list using reciprocal-rank fusion, so that pages several engines agree on
+// float to the top. weights scales each engine's contribution (missing engines
+// get 1.0). Each fused result records the engines and queries that returned
+// it. If limit > 0 the list is cut down to that many results.
+func Fuse(engineResults []EngineResult, weights map[string]float64, limit int) []SearchResult {
+	type fused struct {
+		result SearchResult
+		score  float64
+		order  int
+	}
+
+	byURL := make(map[string]*fused)
+	var all []*fused
+
+	for _, er := range engineResults {
+		if er.Err != nil {
+			continue
+		}
+		// Each ranked list only gets to vote once for a URL
+		credited := make(map[string]bool)
+
+		weight := 1.0
+		if w, ok := weights[er.Engine]; ok {
+			weight = w
+		}
+
+		for rank, result := range er.Results {
+			key := NormalizeURL(result.URL)
+
+			f, ok := byURL[key]
+			if !ok {
+				f = &fused{result: result, order: len(all)}
+				f.result.Engines = nil
+				f.result.Queries = nil
+				byURL[key] = f
+				all = append(all, f)
+			}
+
+			if f.result.Title == "" {
+				f.result.Title = result.Title
+			}
+			if f.result.Snippet == "" {
+				f.result.Snippet = result.Snippet
+			}
+			if f.result.Content == "" {
+				f.result.Content = result.Content
+			}
+			if f.result.Published.IsZero() {
+				f.result.Published = result.Published
+			}
+			if len(f.result.Authors) == 0 {
+				f.result.Authors = result.Authors
+			}
+			if !contains(f.result.Engines, er.Engine) {
+				f.result.Engines = append(f.result.Engines, er.Engine)
+			}
+			if er.Query != "" && !contains(f.result.Queries, er.Query) {
+				f.result.Queries = append(f.result.Queries, er.Query)
+			}
+			if !credited[key] {
+				credited[key] = true
+				f.score += weight / (RRFConstant + float64(rank+1))
+			}
+		}
+	}
+
+	// Ties go to whichever was seen first, which follows engine order
+	sort.SliceStable(all, func(i, j int) bool {
+		if all[i].score != all[j].score {
+			return all[i].score > all[j].score
+		}
+		return all[i].order < all[j].order
+	})
+
+	if limit > 0 && len(all) > limit {
+		all = all[:limit]
+	}
+
+	results := make([]SearchResult, 0, len(all))
+	for _, f := range all {
+		results = append(results, f.result)
+	}
+
+	return results
+}
+
+// NormalizeURL reduces a URL to the form used to decide whether two results
+// are the same page: scheme, "www.", fragments, trailing slashes and
+// utm_* tracking parameters are ignored. Other query parameters are kept since
+// they often matter (eg YouTube's ?v=).
+func NormalizeURL(rawURL string) string {
+	u, err := url.Parse(strings.TrimSpace(rawURL))
+	if err != nil || u.Host == "" {
+		return strings.TrimSpace(rawURL)
+	}
+
+	host := strings.TrimPrefix(strings.ToLower(u.Host), "www.")
+	path := strings.TrimRight(u.EscapedPath(), "/")
+
+	q := u.Query()
+	for k := range q {
+		if strings.HasPrefix(strings.ToLower(k), "utm_") {
+			q.Del(k)
+		}
+	}
+
+	normalized := host + path
+	if len(q) > 0 {
+		normalized += "?" + q.Encode()
+	}
+
+	return normalized
+}
+
+func contains(list []string, s string) bool {
+	for _, item := range list {
+		if item == s {
+			return true
+		}
+	}
+	return false
+}
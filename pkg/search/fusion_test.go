@@ -0,0 +1,130 @@
+package search
+
+import (
+	"errors"
+	"reflect"
+	"testing"
+)
+
+func urls(results []SearchResult) []string {
+	var u []string
+	for _, r := range results {
+		u = append(u, r.URL)
+	}
+	return u
+}
+
+func TestFuse(t *testing.T) {
+	engineResults := []EngineResult{
+		{
+			Engine: "duckduckgo",
+			Results: []SearchResult{
+				{URL: "https://a.com/", Title: "A"},
+				{URL: "https://b.com/page"},
+				{URL: "https://c.com"},
+			},
+		},
+		{
+			Engine: "google",
+			Results: []SearchResult{
+				{URL: "https://www.c.com/", Title: "C"},
+				{URL: "http://b.com/page#section", Title: "B"},
+			},
+		},
+		{
+			Engine:  "bing",
+			Err:     errors.New("failed"),
+			Results: []SearchResult{{URL: "https://ignored.com"}},
+		},
+	}
+
+	t.Run("Agreement ranks higher", func(t *testing.T) {
+		results := Fuse(engineResults, nil, 0)
+
+		expected := []string{"https://c.com", "https://b.com/page", "https://a.com/"}
+		if !reflect.DeepEqual(urls(results), expected) {
+			t.Fatalf("Expected %v, got %v", expected, urls(results))
+		}
+
+		if !reflect.DeepEqual(results[0].Engines, []string{"duckduckgo", "google"}) {
+			t.Errorf("Unexpected engines for %s: %v", results[0].URL, results[0].Engines)
+		}
+		if !reflect.DeepEqual(results[2].Engines, []string{"duckduckgo"}) {
+			t.Errorf("Unexpected engines for %s: %v", results[2].URL, results[2].Engines)
+		}
+
+		// Missing titles are filled in from other engines
+		if results[0].Title != "C" || results[1].Title != "B" {
+			t.Errorf("Expected titles to be merged, got %q and %q", results[0].Title, results[1].Title)
+		}
+	})
+
+	t.Run("Weights", func(t *testing.T) {
+		results := Fuse(engineResults, map[string]float64{"duckduckgo": 0.1}, 0)
+
+		expected := []string{"https://c.com", "https://b.com/page", "https://a.com/"}
+		if !reflect.DeepEqual(urls(results), expected) {
+			t.Fatalf("Expected %v, got %v", expected, urls(results))
+		}
+
+		results = Fuse(engineResults, map[string]float64{"google": 0}, 0)
+		expected = []string{"https://a.com/", "https://b.com/page", "https://c.com"}
+		if !reflect.DeepEqual(urls(results), expected) {
+			t.Fatalf("Expected %v, got %v", expected, urls(results))
+		}
+	})
+
+	t.Run("Limit", func(t *testing.T) {
+		results := Fuse(engineResults, nil, 2)
+		if len(results) != 2 {
+			t.Fatalf("Expected 2 results, got %d", len(results))
+		}
+	})
+}
+
+func TestNormalizeURL(t *testing.T) {
+	testCases := []struct {
+		input    string
+		expected string
+	}{
+		{"https://example.com/page", "example.com/page"},
+		{"http://www.Example.com/page/", "example.com/page"},
+		{"https://example.com/page#top", "example.com/page"},
+		{"https://example.com/page?utm_source=x&id=3", "example.com/page?id=3"},
+		{"https://youtube.com/watch?v=abc", "youtube.com/watch?v=abc"},
+		{"not a url", "not a url"},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.input, func(t *testing.T) {
+			if got := NormalizeURL(tc.input); got != tc.expected {
+				t.Errorf("NormalizeURL(%q) = %q; want %q", tc.input, got, tc.expected)
+			}
+		})
+	}
+}
+
+func TestFuseQueries(t *testing.T) {
+	engineResults := []EngineResult{
+		{Engine: "duckduckgo", Query: "go iterators", Results: []SearchResult{{URL: "https://a.com"}, {URL: "https://b.com"}}},
+		{Engine: "google", Query: "go iterators", Results: []SearchResult{{URL: "https://b.com"}}},
+		{Engine: "duckduckgo", Query: "range over func", Results: []SearchResult{{URL: "https://c.com"}, {URL: "https://b.com"}}},
+	}
+
+	results := Fuse(engineResults, nil, 0)
+
+	expected := []string{"https://b.com", "https://a.com", "https://c.com"}
+	if !reflect.DeepEqual(urls(results), expected) {
+		t.Fatalf("Expected %v, got %v", expected, urls(results))
+	}
+
+	if !reflect.DeepEqual(results[0].Queries, []string{"go iterators", "range over func"}) {
+		t.Errorf("Unexpected queries for %s: %v", results[0].URL, results[0].Queries)
+	}
+	if !reflect.DeepEqual(results[0].Engines, []string{"duckduckgo", "google"}) {
+		t.Errorf("Unexpected engines for %s: %v", results[0].URL, results[0].Engines)
+	}
+	if !reflect.DeepEqual(results[2].Queries, []string{"range over func"}) {
+		t.Errorf("Unexpected queries for %s: %v", results[2].URL, results[2].Queries)
+	}
+}
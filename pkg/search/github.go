@@ -0,0 +1,570 @@
+package search
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	"ask-web/pkg/logger"
+)
+
+const GitHubBaseURL = "https://api.github.com"
+
+// What the GitHub engine can search
+const (
+	GitHubIssues       = "issues"
+	GitHubRepositories = "repositories"
+	GitHubDiscussions  = "discussions"
+)
+
+const (
+	githubPageSize = 10
+	// Comments to include with each issue or discussion
+	githubComments = 10
+	// Results to fetch comments or READMEs for, after filtering
+	githubMaxDetails = 5
+	// READMEs can be enormous; the start is what matters
+	githubMaxReadme = 64 * 1024
+)
+
+type githubIssue struct {
+	Title         string    `json:"title"`
+	HTMLURL       string    `json:"html_url"`
+	Number        int       `json:"number"`
+	State         string    `json:"state"`
+	Body          string    `json:"body"`
+	Comments      int       `json:"comments"`
+	RepositoryURL string    `json:"repository_url"`
+	CreatedAt     time.Time `json:"created_at"`
+}
+
+type githubComment struct {
+	Body string `json:"body"`
+	User struct {
+		Login string `json:"login"`
+	} `json:"user"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+type githubRepository struct {
+	FullName    string `json:"full_name"`
+	HTMLURL     string `json:"html_url"`
+	Description string `json:"description"`
+	Stars       int    `json:"stargazers_count"`
+}
+
+type githubSearchResponse[T any] struct {
+	Items []T `json:"items"`
+}
+
+// Discussions can only be searched through GraphQL
+const githubDiscussionQuery = `query($q: String!, $first: Int!, $after: String) {
+  search(query: $q, type: DISCUSSION, first: $first, after: $after) {
+    pageInfo { endCursor hasNextPage }
+    nodes {
+      ... on Discussion {
+        title
+        url
+        body
+        createdAt
+        repository { nameWithOwner }
+        answer { body author { login } }
+        comments(first: 10) { nodes { body isAnswer author { login } } }
+      }
+    }
+  }
+}`
+
+type githubAuthor struct {
+	Login string `json:"login"`
+}
+
+type githubDiscussionResponse struct {
+	Data struct {
+		Search struct {
+			PageInfo struct {
+				EndCursor   string `json:"endCursor"`
+				HasNextPage bool   `json:"hasNextPage"`
+			} `json:"pageInfo"`
+			Nodes []struct {
+				Title      string    `json:"title"`
+				URL        string    `json:"url"`
+				Body       string    `json:"body"`
+				CreatedAt  time.Time `json:"createdAt"`
+				Repository struct {
+					NameWithOwner string `json:"nameWithOwner"`
+				} `json:"repository"`
+				Answer *struct {
+					Body   string       `json:"body"`
+					Author githubAuthor `json:"author"`
+				} `json:"answer"`
+				Comments struct {
+					Nodes []struct {
+						Body     string       `json:"body"`
+						IsAnswer bool         `json:"isAnswer"`
+						Author   githubAuthor `json:"author"`
+					} `json:"nodes"`
+				} `json:"comments"`
+			} `json:"nodes"`
+		} `json:"search"`
+	} `json:"data"`
+	Errors []struct {
+		Message string `json:"message"`
+	} `json:"errors"`
+}
+
+// GitHubEngine searches GitHub issues, repositories and discussions. The
+// content comes from the API (issue bodies and comments, raw READMEs) rather
+// than the rendered pages. A token is optional, except for discussions, but
+// without one the search API only allows 10 requests a minute. Only the
+// search API is held to that; comments and READMEs come from the REST API,
+// which has an hourly limit instead.
+type GitHubEngine struct {
+	token string
+	kinds []string
+
+	// BaseURL and Client default to GitHubBaseURL and a client with the
+	// usual timeout
+	BaseURL string
+	Client  *http.Client
+}
+
+func init() {
+	Register("github", func(cfg EngineConfig) (SearchEngine, error) {
+		var kinds []string
+		if cfg.Opts != nil {
+			kinds = cfg.Opts.GitHubSearch
+		}
+		return NewGitHubEngine(cfg.Keys.GitHubToken, kinds)
+	})
+}
+
+// NewGitHubEngine searches the given kinds (GitHubIssues etc), or issues and
+// repositories if there are none. Discussions are left out without a token.
+func NewGitHubEngine(token string, kinds []string) (*GitHubEngine, error) {
+	if len(kinds) == 0 {
+		kinds = []string{GitHubIssues, GitHubRepositories}
+	}
+
+	e := &GitHubEngine{token: token}
+	for _, kind := range kinds {
+		kind = strings.ToLower(strings.TrimSpace(kind))
+		switch kind {
+		case GitHubIssues, GitHubRepositories:
+		case GitHubDiscussions:
+			if token == "" {
+				logger.GetLogger().Info("Not searching GitHub discussions without GITHUB_TOKEN")
+				continue
+			}
+		default:
+			return nil, fmt.Errorf("unknown GitHub search %q (want %s, %s or %s)",
+				kind, GitHubIssues, GitHubRepositories, GitHubDiscussions)
+		}
+		e.kinds = append(e.kinds, kind)
+	}
+	if len(e.kinds) == 0 {
+		return nil, fmt.Errorf("%w: GITHUB_TOKEN is required to search discussions", ErrNotConfigured)
+	}
+
+	return e, nil
+}
+
+func (e *GitHubEngine) Name() string { return "github" }
+
+func (e *GitHubEngine) Capabilities() Capabilities {
+	// The search API allows 30 requests a minute with a token, 10 without.
+	// This only spaces out the /search requests.
+	interval := 6 * time.Second
+	if e.token != "" {
+		interval = 2 * time.Second
+	}
+
+	return Capabilities{
+		MaxResultsPerRequest: githubPageSize,
+		MinInterval:          interval,
+	}
+}
+
+func (e *GitHubEngine) Search(ctx context.Context, sr SearchRequest) ([]SearchResult, error) {
+	if sr.Query == "" {
+		return nil, fmt.Errorf("search query cannot be empty")
+	}
+
+	client := httpClient(e.Client)
+
+	// Each page is a page of every kind, so the page size has to stay the
+	// same; discussions page with a cursor instead
+	count := pageSize(sr, githubPageSize)
+	page := sr.Offset/(count*len(e.kinds)) + 1
+	cursor := ""
+	moreDiscussions := true
+	details := make(map[string]detailFetcher)
+	// Repositories whose README hasn't been fetched
+	noReadme := make(map[string]bool)
+
+	// With the search API's interval, a timeout only leaves time for a few
+	// searches, so stop at those rather than wait past it and lose the lot.
+	// collectPages covers the first search of each page.
+	budget := requestBudget(ctx)
+	perPage := 0
+	for _, kind := range e.kinds {
+		if kind != GitHubDiscussions {
+			perPage++
+		}
+	}
+	if budget >= 0 {
+		maxPages := sr.MaxPages
+		if maxPages <= 0 {
+			maxPages = DefaultMaxPages
+		}
+		perPage = max(perPage, 1)
+		sr.MaxPages = min(maxPages, max((budget+perPage-1)/perPage, 1))
+	}
+	used := 0
+
+	results, err := collectPages(ctx, sr, func(ctx context.Context, offset int) ([]SearchResult, error) {
+		var lists [][]SearchResult
+		var errs []error
+		// Discussions go through GraphQL, which isn't the search API
+		searches := 0
+		used++
+		for _, kind := range e.kinds {
+			if kind != GitHubDiscussions {
+				searches++
+				if searches > 1 {
+					if budget >= 0 && used >= budget {
+						continue
+					}
+					used++
+					if err := acquireRequest(ctx); err != nil {
+						errs = append(errs, err)
+						continue
+					}
+				}
+			}
+
+			var results []SearchResult
+			var err error
+			switch kind {
+			case GitHubIssues:
+				results, err = e.searchIssues(ctx, client, sr, count, page, details)
+			case GitHubRepositories:
+				results, err = e.searchRepositories(ctx, client, sr, count, page, details, noReadme)
+			case GitHubDiscussions:
+				if !moreDiscussions {
+					continue
+				}
+				results, cursor, moreDiscussions, err = e.searchDiscussions(ctx, client, sr, count, cursor)
+			}
+			if err != nil {
+				errs = append(errs, err)
+				continue
+			}
+			lists = append(lists, results)
+		}
+		page++
+
+		// One kind failing shouldn't lose the others
+		if len(lists) == 0 && len(errs) > 0 {
+			return nil, errors.Join(errs...)
+		}
+		for _, err := range errs {
+			logger.GetLogger().Warn("GitHub search failed: ", err)
+		}
+
+		return interleave(lists), nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	// Comments and READMEs only for what got through the filter, and not
+	// held to the search API's limits
+	err = fetchDetails(withoutRequestGate(ctx), results, githubMaxDetails, func(ctx context.Context, result *SearchResult) error {
+		if fetch, ok := details[result.URL]; ok {
+			return fetch(ctx, result)
+		}
+		return nil
+	})
+	if err != nil {
+		logger.GetLogger().Warn("Stopped getting GitHub comments and READMEs: ", err)
+	}
+
+	// Without the README, the repository page gets downloaded instead
+	for i := range results {
+		if noReadme[results[i].URL] {
+			results[i].Content = ""
+		}
+	}
+
+	return results, nil
+}
+
+// interleave takes the first result of each list, then the second, and so
+// on, so that no one kind crowds out the others.
+func interleave(lists [][]SearchResult) []SearchResult {
+	var results []SearchResult
+	for i := 0; ; i++ {
+		added := false
+		for _, list := range lists {
+			if i < len(list) {
+				results = append(results, list[i])
+				added = true
+			}
+		}
+		if !added {
+			return results
+		}
+	}
+}
+
+// githubQuery adds qualifiers for --since to the query. qualifier is the
+// date field to use, eg "updated".
+func githubQuery(sr SearchRequest, qualifier string) string {
+	q := sr.Query
+	if sr.Since > 0 {
+		q += fmt.Sprintf(" %s:>=%s", qualifier, sr.SinceDate().Format(time.DateOnly))
+	}
+	return q
+}
+
+// searchIssues gets a page of issues, without their comments; details gets
+// what fetches them.
+func (e *GitHubEngine) searchIssues(ctx context.Context, client *http.Client, sr SearchRequest, count int, page int, details map[string]detailFetcher) ([]SearchResult, error) {
+	params := url.Values{}
+	params.Set("q", githubQuery(sr, "updated")+" is:issue")
+	params.Set("per_page", fmt.Sprintf("%d", count))
+	params.Set("page", fmt.Sprintf("%d", page))
+
+	var resp githubSearchResponse[githubIssue]
+	if err := e.getJSON(ctx, client, e.baseURL()+"/search/issues?"+params.Encode(), &resp); err != nil {
+		return nil, err
+	}
+
+	results := make([]SearchResult, 0, len(resp.Items))
+	for _, issue := range resp.Items {
+		repo := githubRepo(issue.RepositoryURL)
+		if issue.Comments > 0 && repo != "" {
+			details[issue.HTMLURL] = func(ctx context.Context, result *SearchResult) error {
+				var comments []githubComment
+				commentsURL := fmt.Sprintf("%s/repos/%s/issues/%d/comments?per_page=%d", e.baseURL(), repo, issue.Number, githubComments)
+				if err := e.getJSON(ctx, client, commentsURL, &comments); err != nil {
+					return err
+				}
+				result.Content = githubIssueContent(issue, repo, comments)
+				return nil
+			}
+		}
+
+		results = append(results, SearchResult{
+			Title:     issue.Title,
+			URL:       issue.HTMLURL,
+			Snippet:   truncateText(issue.Body, maxSnippetLength),
+			Content:   githubIssueContent(issue, repo, nil),
+			Published: issue.CreatedAt,
+		})
+	}
+
+	return results, nil
+}
+
+// githubRepo gets <owner>/<repo> from an API URL for the repository, eg
+// https://api.github.com/repos/<owner>/<repo>.
+func githubRepo(repositoryURL string) string {
+	_, repo, _ := strings.Cut(repositoryURL, "/repos/")
+	return repo
+}
+
+func githubIssueContent(issue githubIssue, repo string, comments []githubComment) string {
+	var b strings.Builder
+	fmt.Fprintf(&b, "# %s (#%d, %s)\n", issue.Title, issue.Number, issue.State)
+	if repo != "" {
+		fmt.Fprintf(&b, "Repository: %s\n", repo)
+	}
+	b.WriteString("\n" + strings.TrimSpace(issue.Body) + "\n")
+
+	for _, c := range comments {
+		fmt.Fprintf(&b, "\n## Comment by %s (%s):\n\n%s\n", c.User.Login, c.CreatedAt.Format(time.DateOnly), strings.TrimSpace(c.Body))
+	}
+
+	return b.String()
+}
+
+// searchRepositories gets a page of repositories, with their descriptions
+// standing in for their READMEs (see fetchDetails); details gets what
+// fetches them, and noReadme the repositories still waiting for one.
+func (e *GitHubEngine) searchRepositories(ctx context.Context, client *http.Client, sr SearchRequest, count int, page int, details map[string]detailFetcher, noReadme map[string]bool) ([]SearchResult, error) {
+	params := url.Values{}
+	params.Set("q", githubQuery(sr, "pushed"))
+	params.Set("per_page", fmt.Sprintf("%d", count))
+	params.Set("page", fmt.Sprintf("%d", page))
+
+	var resp githubSearchResponse[githubRepository]
+	if err := e.getJSON(ctx, client, e.baseURL()+"/search/repositories?"+params.Encode(), &resp); err != nil {
+		return nil, err
+	}
+
+	results := make([]SearchResult, 0, len(resp.Items))
+	for _, repo := range resp.Items {
+		header := fmt.Sprintf("# %s (%d stars)\n%s\n", repo.FullName, repo.Stars, repo.Description)
+		details[repo.HTMLURL] = func(ctx context.Context, result *SearchResult) error {
+			readme, err := e.fetchReadme(ctx, client, repo.FullName)
+			if err != nil {
+				return err
+			}
+			result.Content = header + "\n" + readme
+			delete(noReadme, repo.HTMLURL)
+			return nil
+		}
+		noReadme[repo.HTMLURL] = true
+
+		results = append(results, SearchResult{
+			Title:   repo.FullName,
+			URL:     repo.HTMLURL,
+			Snippet: repo.Description,
+			Content: header,
+		})
+	}
+
+	return results, nil
+}
+
+func (e *GitHubEngine) fetchReadme(ctx context.Context, client *http.Client, fullName string) (string, error) {
+	resp, err := doRequest(ctx, client, e.Name(), func(ctx context.Context) (*http.Request, error) {
+		req, err := e.newRequest(ctx, "GET", e.baseURL()+"/repos/"+fullName+"/readme", nil)
+		if err != nil {
+			return nil, err
+		}
+		req.Header.Set("Accept", "application/vnd.github.raw+json")
+		return req, nil
+	})
+	if err != nil {
+		return "", e.reclassify(err)
+	}
+	defer resp.Body.Close()
+
+	readme, err := io.ReadAll(io.LimitReader(resp.Body, githubMaxReadme))
+	if err != nil {
+		return "", &EngineError{Engine: e.Name(), Kind: ErrTransient, Message: "failed to read README", Err: err}
+	}
+
+	return strings.TrimSpace(string(readme)), nil
+}
+
+func (e *GitHubEngine) searchDiscussions(ctx context.Context, client *http.Client, sr SearchRequest, count int, cursor string) ([]SearchResult, string, bool, error) {
+	variables := map[string]any{
+		"q":     githubQuery(sr, "updated"),
+		"first": count,
+	}
+	if cursor != "" {
+		variables["after"] = cursor
+	}
+	body, err := json.Marshal(map[string]any{
+		"query":     githubDiscussionQuery,
+		"variables": variables,
+	})
+	if err != nil {
+		return nil, "", false, err
+	}
+
+	resp, err := doRequest(ctx, client, e.Name(), func(ctx context.Context) (*http.Request, error) {
+		return e.newRequest(ctx, "POST", e.baseURL()+"/graphql", bytes.NewReader(body))
+	})
+	if err != nil {
+		return nil, "", false, e.reclassify(err)
+	}
+	defer resp.Body.Close()
+
+	var gqlResp githubDiscussionResponse
+	if err := json.NewDecoder(resp.Body).Decode(&gqlResp); err != nil {
+		return nil, "", false, &EngineError{Engine: e.Name(), Message: "error decoding response", Err: err}
+	}
+	if len(gqlResp.Errors) > 0 {
+		return nil, "", false, &EngineError{Engine: e.Name(), Message: gqlResp.Errors[0].Message}
+	}
+
+	search := gqlResp.Data.Search
+	results := make([]SearchResult, 0, len(search.Nodes))
+	for _, d := range search.Nodes {
+		// Other kinds of node come back empty
+		if d.URL == "" {
+			continue
+		}
+
+		var b strings.Builder
+		fmt.Fprintf(&b, "# %s\nRepository: %s\n\n%s\n", d.Title, d.Repository.NameWithOwner, strings.TrimSpace(d.Body))
+		if d.Answer != nil {
+			fmt.Fprintf(&b, "\n## Answer by %s:\n\n%s\n", d.Answer.Author.Login, strings.TrimSpace(d.Answer.Body))
+		}
+		for _, c := range d.Comments.Nodes {
+			if c.IsAnswer {
+				continue
+			}
+			fmt.Fprintf(&b, "\n## Comment by %s:\n\n%s\n", c.Author.Login, strings.TrimSpace(c.Body))
+		}
+
+		results = append(results, SearchResult{
+			Title:     d.Title,
+			URL:       d.URL,
+			Snippet:   truncateText(d.Body, maxSnippetLength),
+			Content:   b.String(),
+			Published: d.CreatedAt,
+		})
+	}
+
+	return results, search.PageInfo.EndCursor, search.PageInfo.HasNextPage, nil
+}
+
+func (e *GitHubEngine) baseURL() string {
+	if e.BaseURL != "" {
+		return strings.TrimRight(e.BaseURL, "/")
+	}
+	return GitHubBaseURL
+}
+
+func (e *GitHubEngine) newRequest(ctx context.Context, method string, reqURL string, body io.Reader) (*http.Request, error) {
+	req, err := http.NewRequestWithContext(ctx, method, reqURL, body)
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Accept", "application/vnd.github+json")
+	req.Header.Set("X-GitHub-Api-Version", "2022-11-28")
+	if e.token != "" {
+		req.Header.Set("Authorization", "Bearer "+e.token)
+	}
+	return req, nil
+}
+
+func (e *GitHubEngine) getJSON(ctx context.Context, client *http.Client, reqURL string, v any) error {
+	resp, err := doRequest(ctx, client, e.Name(), func(ctx context.Context) (*http.Request, error) {
+		return e.newRequest(ctx, "GET", reqURL, nil)
+	})
+	if err != nil {
+		return e.reclassify(err)
+	}
+	defer resp.Body.Close()
+
+	if err := json.NewDecoder(resp.Body).Decode(v); err != nil {
+		return &EngineError{Engine: e.Name(), Message: "error decoding response", Err: err}
+	}
+
+	return nil
+}
+
+// GitHub says it's rate limiting with a 403, which would otherwise look like
+// a bad token
+func (e *GitHubEngine) reclassify(err error) error {
+	var engineErr *EngineError
+	if errors.As(err, &engineErr) && engineErr.StatusCode == http.StatusForbidden &&
+		strings.Contains(strings.ToLower(engineErr.Message), "rate limit") {
+		engineErr.Kind = ErrRateLimited
+	}
+	return err
+}
@@ -0,0 +1,276 @@
+package search
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestGitHubSearch(t *testing.T) {
+	var requests []*http.Request
+	server := fixtureServer(t, map[string]string{
+		"/search/issues": "github_issues.json",
+		"/repos/spf13/viper/issues/1520/comments": "github_comments.json",
+		"/search/repositories":                    "github_repositories.json",
+		"/repos/spf13/viper/readme":               "github_readme.md",
+	}, &requests)
+
+	engine, err := NewGitHubEngine("", nil)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	engine.BaseURL = server.URL
+	engine.Client = server.Client()
+	results, err := engine.Search(context.Background(), SearchRequest{Query: "viper env nested keys", MaxResults: 4, MaxPages: 1})
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	// Issues and repositories take turns
+	expected := []string{
+		"https://github.com/spf13/viper/issues/1520",
+		"https://github.com/spf13/viper",
+		"https://github.com/spf13/viper/issues/1601",
+		"https://github.com/knadh/koanf",
+	}
+	if !reflect.DeepEqual(urls(results), expected) {
+		t.Fatalf("Expected %v, got %v", expected, urls(results))
+	}
+
+	issue := results[0]
+	if !issue.Published.Equal(time.Date(2023, 4, 2, 10, 15, 0, 0, time.UTC)) {
+		t.Errorf("Unexpected published date: %s", issue.Published)
+	}
+	if !strings.HasPrefix(issue.Content, "# How do I bind nested keys to environment variables? (#1520, closed)\nRepository: spf13/viper\n") {
+		t.Errorf("Unexpected issue heading: %q", issue.Content)
+	}
+	if !strings.Contains(issue.Content, "## Comment by maintainer (2023-04-02):\n\nYou need a key replacer:\n\n```go") {
+		t.Errorf("Expected the comments, got %q", issue.Content)
+	}
+	if results[2].Content == "" || strings.Contains(results[2].Content, "## Comment") {
+		t.Errorf("Expected an issue without comments, got %q", results[2].Content)
+	}
+
+	repo := results[1]
+	if !strings.HasPrefix(repo.Content, "# spf13/viper (26000 stars)\nGo configuration with fangs\n\n# Viper") {
+		t.Errorf("Expected the README, got %q", repo.Content)
+	}
+	// No README means the page gets downloaded instead
+	if results[3].Content != "" || results[3].Snippet == "" {
+		t.Errorf("Expected a snippet but no content, got %+v", results[3])
+	}
+
+	var readme *http.Request
+	for _, r := range requests {
+		switch r.URL.Path {
+		case "/search/issues":
+			if q := r.URL.Query(); q.Get("q") != "viper env nested keys is:issue" || q.Get("per_page") != "8" || q.Get("page") != "1" {
+				t.Errorf("Unexpected issue search: %v", q)
+			}
+		case "/repos/spf13/viper/readme":
+			readme = r
+		}
+		if r.Header.Get("Authorization") != "" {
+			t.Error("Expected no Authorization header without a token")
+		}
+	}
+	if readme == nil || readme.Header.Get("Accept") != "application/vnd.github.raw+json" {
+		t.Error("Expected the README to be requested raw")
+	}
+}
+
+func TestGitHubLimited(t *testing.T) {
+	var requests []*http.Request
+	server := fixtureServer(t, map[string]string{
+		"/search/issues": "github_issues.json",
+		"/repos/spf13/viper/issues/1520/comments": "github_comments.json",
+	}, &requests)
+
+	engine, _ := NewGitHubEngine("", []string{"issues"})
+	engine.BaseURL = server.URL
+	engine.Client = server.Client()
+	limited := WithLimits([]SearchEngine{engine}, &memoryUsage{counts: make(map[string]int)}, nil)
+
+	// Only searches wait out the 6s interval, so the comments don't run the
+	// engine past its timeout
+	start := time.Now()
+	results := SearchAll(context.Background(), limited, SearchRequest{Query: "viper env", MaxResults: 2, MaxPages: 1}, MaxTimeoutSeconds*time.Second)
+	if elapsed := time.Since(start); elapsed > time.Second {
+		t.Errorf("Expected no waiting, took %s", elapsed)
+	}
+	if results[0].Err != nil || len(results[0].Results) != 2 {
+		t.Fatalf("Expected 2 results, got %d (%v)", len(results[0].Results), results[0].Err)
+	}
+	if !strings.Contains(results[0].Results[0].Content, "## Comment by maintainer") {
+		t.Errorf("Expected the comments, got %q", results[0].Results[0].Content)
+	}
+
+	// Nothing is fetched for results the filter drops
+	requests = nil
+	limited = WithLimits([]SearchEngine{engine}, &memoryUsage{counts: make(map[string]int)}, nil)
+	results = SearchAll(context.Background(), limited, SearchRequest{
+		Query:      "viper env",
+		MaxResults: 2,
+		MaxPages:   1,
+		Filter: func(r SearchResult) bool {
+			return !strings.HasSuffix(r.URL, "/1520")
+		},
+	}, MaxTimeoutSeconds*time.Second)
+	if results[0].Err != nil {
+		t.Fatalf("Unexpected error: %v", results[0].Err)
+	}
+	for _, r := range requests {
+		if strings.Contains(r.URL.Path, "/comments") {
+			t.Errorf("Expected no comments for a dropped issue, got %s", r.URL.Path)
+		}
+	}
+}
+
+func TestGitHubBudget(t *testing.T) {
+	var requests []*http.Request
+	server := fixtureServer(t, map[string]string{
+		"/search/issues":       "github_issues.json",
+		"/search/repositories": "github_repositories.json",
+	}, &requests)
+
+	engine, _ := NewGitHubEngine("", nil)
+	engine.BaseURL = server.URL
+	engine.Client = server.Client()
+	limited := WithLimits([]SearchEngine{engine}, &memoryUsage{counts: make(map[string]int)}, nil)
+
+	// There's only time for one search before the timeout, so the
+	// repositories and the next page are left out rather than waited for
+	start := time.Now()
+	results := SearchAll(context.Background(), limited, SearchRequest{Query: "viper env", MaxResults: 10, MaxPages: 3}, 3*time.Second)
+	if elapsed := time.Since(start); elapsed > time.Second {
+		t.Errorf("Expected no waiting, took %s", elapsed)
+	}
+	if results[0].Err != nil || len(results[0].Results) == 0 {
+		t.Fatalf("Expected the issues, got %d (%v)", len(results[0].Results), results[0].Err)
+	}
+	var searches []string
+	for _, r := range requests {
+		if strings.HasPrefix(r.URL.Path, "/search/") {
+			searches = append(searches, r.URL.Path)
+		}
+	}
+	if !reflect.DeepEqual(searches, []string{"/search/issues"}) {
+		t.Errorf("Expected one search, got %v", searches)
+	}
+}
+
+func TestGitHubDetailsNotLimited(t *testing.T) {
+	defer func(orig RetryPolicy) { DefaultRetryPolicy = orig }(DefaultRetryPolicy)
+	DefaultRetryPolicy = RetryPolicy{MaxAttempts: 2, BaseDelay: time.Millisecond, MaxDelay: 10 * time.Millisecond}
+
+	readmes := 0
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/search/repositories":
+			w.Write(fixture(t, "github_repositories.json"))
+		case "/repos/spf13/viper/readme":
+			readmes++
+			if readmes == 1 {
+				w.WriteHeader(http.StatusServiceUnavailable)
+				return
+			}
+			w.Write(fixture(t, "github_readme.md"))
+		default:
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}))
+	t.Cleanup(server.Close)
+
+	engine, _ := NewGitHubEngine("", []string{"repositories"})
+	engine.BaseURL = server.URL
+	engine.Client = server.Client()
+	usage := &memoryUsage{counts: make(map[string]int)}
+	limited := NewLimitedEngine(engine, usage, 0)
+
+	results, err := limited.Search(context.Background(), SearchRequest{Query: "viper", MaxResults: 1, MaxPages: 1})
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if readmes != 2 || !strings.Contains(results[0].Content, "# Viper") {
+		t.Fatalf("Expected the README on the second try, got %d tries and %q", readmes, results[0].Content)
+	}
+
+	// Only the search counts, not the README or its retry
+	if used, _ := usage.Usage("github", QuotaDay(time.Now())); used != 1 {
+		t.Errorf("Expected 1 request counted, got %d", used)
+	}
+}
+
+func TestGitHubDiscussions(t *testing.T) {
+	var requests []*http.Request
+	server := fixtureServer(t, map[string]string{"/graphql": "github_discussions.json"}, &requests)
+
+	engine, err := NewGitHubEngine("token", []string{"Discussions"})
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	engine.BaseURL = server.URL
+	results, err := engine.Search(context.Background(), SearchRequest{Query: "viper reload", MaxResults: 5})
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	// The empty node isn't a discussion, and there's no next page
+	if len(results) != 1 || len(requests) != 1 {
+		t.Fatalf("Expected 1 result from 1 request, got %d from %d", len(results), len(requests))
+	}
+	content := results[0].Content
+	if !strings.Contains(content, "## Answer by maintainer:") || strings.Count(content, "guard your own reads") != 1 {
+		t.Errorf("Expected the answer once, got %q", content)
+	}
+	if !strings.Contains(content, "## Comment by someone:\n\nIt would be nice") {
+		t.Errorf("Expected the other comment, got %q", content)
+	}
+
+	r := requests[0]
+	if r.Method != "POST" || r.Header.Get("Authorization") != "Bearer token" {
+		t.Errorf("Expected an authorized POST, got %s with %q", r.Method, r.Header.Get("Authorization"))
+	}
+}
+
+func TestNewGitHubEngine(t *testing.T) {
+	// Discussions need a token
+	engine, err := NewGitHubEngine("", []string{"issues", "discussions"})
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if !reflect.DeepEqual(engine.kinds, []string{GitHubIssues}) {
+		t.Errorf("Expected only issues, got %v", engine.kinds)
+	}
+	if _, err := NewGitHubEngine("", []string{"discussions"}); !errors.Is(err, ErrNotConfigured) {
+		t.Errorf("Expected ErrNotConfigured, got %v", err)
+	}
+	if _, err := NewGitHubEngine("token", []string{"gists"}); err == nil {
+		t.Error("Expected an error for an unknown search")
+	}
+
+	if engine.Capabilities().MinInterval <= (&GitHubEngine{token: "token"}).Capabilities().MinInterval {
+		t.Error("Expected a longer interval without a token")
+	}
+}
+
+func TestGitHubRateLimited(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusForbidden)
+		w.Write(fixture(t, "github_rate_limited.json"))
+	}))
+	defer server.Close()
+
+	engine, _ := NewGitHubEngine("", []string{"issues"})
+	engine.BaseURL = server.URL
+	_, err := engine.Search(context.Background(), SearchRequest{Query: "viper", MaxResults: 5})
+	if !errors.Is(err, ErrRateLimited) {
+		t.Errorf("Expected ErrRateLimited, got %v", err)
+	}
+}
@@ -0,0 +1,381 @@
+package search
+
+import (
+	"context"
+	"fmt"
+	"go/doc"
+	"go/parser"
+	"go/token"
+	"io/fs"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+
+	"ask-web/pkg/logger"
+)
+
+// Each result is another run of go doc
+const goDocPageSize = 10
+
+// The module cache rarely changes much in a day
+const goDocIndexMaxAge = 24 * time.Hour
+
+// A package that go doc can describe
+type goPackage struct {
+	ImportPath string `json:"import_path"`
+	Synopsis   string `json:"synopsis"`
+	// For packages in the module cache: the module's version, and the
+	// package's directory, where go doc has to be run
+	Version string `json:"version,omitempty"`
+	Dir     string `json:"dir,omitempty"`
+}
+
+// GoDocEngine searches the documentation of the standard library and of
+// the modules in the module cache, and returns what go doc says about each
+// package as the results' Content. It never touches the network.
+//
+// Packages are matched on their import paths and synopses, which are
+// indexed the first time the engine searches and kept in Indexes for a day.
+// Only the newest version of each module in the cache is indexed, and go
+// doc can't describe packages whose module's dependencies aren't in the
+// cache too, so those are skipped.
+type GoDocEngine struct {
+	// ModCache defaults to what go env GOMODCACHE says
+	ModCache string
+	// Indexes, if set, keeps the index between runs
+	Indexes IndexStore
+
+	once  sync.Once
+	index *storedIndex[goPackage]
+}
+
+func init() {
+	Register("godoc", func(cfg EngineConfig) (SearchEngine, error) {
+		if _, err := exec.LookPath("go"); err != nil {
+			return nil, fmt.Errorf("%w: go is not installed", ErrNotConfigured)
+		}
+		return &GoDocEngine{Indexes: cfg.Indexes}, nil
+	})
+}
+
+func (e *GoDocEngine) Name() string { return "godoc" }
+
+func (e *GoDocEngine) Capabilities() Capabilities {
+	return Capabilities{MaxResultsPerRequest: goDocPageSize}
+}
+
+func (e *GoDocEngine) Search(ctx context.Context, sr SearchRequest) ([]SearchResult, error) {
+	if sr.Query == "" {
+		return nil, fmt.Errorf("search query cannot be empty")
+	}
+
+	index, err := e.packageIndex(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	var ranked []goPackage
+	for _, match := range index.index.Search(sr.Query) {
+		ranked = append(ranked, index.docs[match.Doc])
+	}
+
+	count := pageSize(sr, goDocPageSize)
+	return collectPages(ctx, sr, func(ctx context.Context, offset int) ([]SearchResult, error) {
+		if offset >= len(ranked) {
+			return nil, nil
+		}
+
+		var results []SearchResult
+		for _, pkg := range ranked[offset:min(offset+count, len(ranked))] {
+			// go doc only finds packages outside the standard library from
+			// inside their module
+			target := pkg.ImportPath
+			if pkg.Dir != "" {
+				target = "."
+			}
+			out, err := runCommand(ctx, pkg.Dir, "go", "doc", target)
+			if err != nil {
+				logger.GetLogger().Warn(fmt.Sprintf("Error running go doc %s: %s", pkg.ImportPath, err))
+				continue
+			}
+
+			ref := pkg.ImportPath
+			if pkg.Version != "" {
+				ref += "@" + pkg.Version
+			}
+			results = append(results, SearchResult{
+				Title:   ref,
+				URL:     "https://pkg.go.dev/" + ref,
+				Snippet: pkg.Synopsis,
+				Content: plainOutput(out),
+			})
+		}
+		return results, nil
+	})
+}
+
+// packageIndex returns the index of packages (see storedIndex), keyed on
+// the module cache and the version of Go, which has the standard library.
+func (e *GoDocEngine) packageIndex(ctx context.Context) (*docIndex[goPackage], error) {
+	e.once.Do(func() {
+		e.index = &storedIndex[goPackage]{
+			engine: e.Name(),
+			what:   "Go packages",
+			store:  e.Indexes,
+			maxAge: goDocIndexMaxAge,
+			key: func(ctx context.Context) string {
+				out, err := runCommand(ctx, "", "go", "env", "GOVERSION")
+				if err != nil {
+					logger.GetLogger().Warn("Error finding the Go version: ", err)
+				}
+				return e.modCache(ctx) + " " + strings.TrimSpace(string(out))
+			},
+			build: e.buildIndex,
+			text: func(pkg goPackage) string {
+				// Path elements are the best guide to what a package is for
+				return pkg.ImportPath + " " + pkg.ImportPath + " " + pkg.Synopsis
+			},
+		}
+	})
+
+	return e.index.get(ctx)
+}
+
+// buildIndex finds the packages in the standard library and the module
+// cache. Either can be missing, but not both, and running out of time is an
+// error rather than a partial index.
+func (e *GoDocEngine) buildIndex(ctx context.Context) ([]goPackage, error) {
+	log := logger.GetLogger()
+	var packages []goPackage
+
+	out, err := runCommand(ctx, "", "go", "list", "-e", "-f", "{{.ImportPath}}\t{{.Doc}}", "std")
+	if ctx.Err() != nil {
+		return nil, ctx.Err()
+	}
+	if err != nil {
+		log.Warn("Error listing the standard library: ", err)
+	}
+	for _, line := range strings.Split(string(out), "\n") {
+		importPath, synopsis, _ := strings.Cut(line, "\t")
+		if importPath == "" || !goDocIndexable(importPath) {
+			continue
+		}
+		packages = append(packages, goPackage{ImportPath: importPath, Synopsis: synopsis})
+	}
+
+	if modCache := e.modCache(ctx); modCache != "" {
+		modules, err := latestModules(ctx, modCache)
+		if err != nil {
+			return nil, err
+		}
+		for _, module := range modules {
+			modPackages, err := modulePackages(ctx, module)
+			if err != nil {
+				return nil, err
+			}
+			packages = append(packages, modPackages...)
+		}
+	}
+	if ctx.Err() != nil {
+		return nil, ctx.Err()
+	}
+
+	if len(packages) == 0 {
+		return nil, fmt.Errorf("no Go packages found")
+	}
+	log.Info(fmt.Sprintf("Indexed %d Go packages", len(packages)))
+
+	return packages, nil
+}
+
+func (e *GoDocEngine) modCache(ctx context.Context) string {
+	if e.ModCache != "" {
+		return e.ModCache
+	}
+
+	out, err := runCommand(ctx, "", "go", "env", "GOMODCACHE")
+	if err != nil {
+		logger.GetLogger().Warn("Error finding the module cache: ", err)
+	}
+	return strings.TrimSpace(string(out))
+}
+
+// Internal packages can't be used, and vendored ones are someone else's
+func goDocIndexable(importPath string) bool {
+	for _, elem := range strings.Split(importPath, "/") {
+		if elem == "internal" || elem == "vendor" || elem == "testdata" {
+			return false
+		}
+	}
+	return true
+}
+
+type cachedModule struct {
+	path     string
+	version  string
+	dir      string
+	modified time.Time
+}
+
+// latestModules finds the modules in the module cache, keeping the most
+// recently downloaded version of each. It only fails if ctx runs out.
+func latestModules(ctx context.Context, modCache string) ([]cachedModule, error) {
+	latest := make(map[string]cachedModule)
+	var order []string
+
+	err := filepath.WalkDir(modCache, func(path string, d fs.DirEntry, err error) error {
+		if ctx.Err() != nil {
+			return ctx.Err()
+		}
+		if err != nil || !d.IsDir() || path == modCache {
+			return nil
+		}
+		// Downloads, checksums and so on
+		if d.Name() == "cache" && filepath.Dir(path) == modCache {
+			return filepath.SkipDir
+		}
+
+		escaped, version, ok := strings.Cut(d.Name(), "@")
+		if !ok {
+			return nil
+		}
+		rel, err := filepath.Rel(modCache, filepath.Join(filepath.Dir(path), escaped))
+		if err != nil {
+			return filepath.SkipDir
+		}
+		info, err := d.Info()
+		if err != nil {
+			return filepath.SkipDir
+		}
+
+		module := cachedModule{
+			path:     unescapeModulePath(filepath.ToSlash(rel)),
+			version:  version,
+			dir:      path,
+			modified: info.ModTime(),
+		}
+		if prev, ok := latest[module.path]; !ok {
+			order = append(order, module.path)
+			latest[module.path] = module
+		} else if module.modified.After(prev.modified) {
+			latest[module.path] = module
+		}
+
+		// Nothing in a module is another module
+		return filepath.SkipDir
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	modules := make([]cachedModule, 0, len(order))
+	for _, path := range order {
+		modules = append(modules, latest[path])
+	}
+	return modules, nil
+}
+
+// The module cache escapes capitals, eg github.com/!burnt!sushi/toml for
+// github.com/BurntSushi/toml, so that it works on case-insensitive file
+// systems
+func unescapeModulePath(escaped string) string {
+	var b strings.Builder
+	upper := false
+	for _, r := range escaped {
+		if r == '!' {
+			upper = true
+			continue
+		}
+		if upper {
+			r -= 'a' - 'A'
+			upper = false
+		}
+		b.WriteRune(r)
+	}
+	return b.String()
+}
+
+// modulePackages finds the importable packages in a module, with their
+// synopses. It only fails if ctx runs out.
+func modulePackages(ctx context.Context, module cachedModule) ([]goPackage, error) {
+	var packages []goPackage
+
+	err := filepath.WalkDir(module.dir, func(path string, d fs.DirEntry, err error) error {
+		if ctx.Err() != nil {
+			return ctx.Err()
+		}
+		if err != nil || !d.IsDir() {
+			return nil
+		}
+		if path != module.dir {
+			name := d.Name()
+			if strings.HasPrefix(name, ".") || strings.HasPrefix(name, "_") {
+				return filepath.SkipDir
+			}
+			// Nested modules are cached separately
+			if _, err := os.Stat(filepath.Join(path, "go.mod")); err == nil {
+				return filepath.SkipDir
+			}
+		}
+
+		rel, err := filepath.Rel(module.dir, path)
+		if err != nil {
+			return nil
+		}
+		importPath := module.path
+		if rel != "." {
+			importPath += "/" + filepath.ToSlash(rel)
+		}
+		if !goDocIndexable(importPath) {
+			return filepath.SkipDir
+		}
+
+		if name, synopsis, ok := packageSynopsis(path); ok && name != "main" {
+			packages = append(packages, goPackage{
+				ImportPath: importPath,
+				Synopsis:   synopsis,
+				Version:    module.version,
+				Dir:        path,
+			})
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return packages, nil
+}
+
+// packageSynopsis reads just the package clauses of the Go files in dir,
+// to get the package's name and the first sentence of its doc comment. ok
+// is false if there are no (non-test) Go files.
+func packageSynopsis(dir string) (name string, synopsis string, ok bool) {
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return "", "", false
+	}
+
+	fset := token.NewFileSet()
+	for _, entry := range entries {
+		fileName := entry.Name()
+		if entry.IsDir() || !strings.HasSuffix(fileName, ".go") || strings.HasSuffix(fileName, "_test.go") {
+			continue
+		}
+
+		f, err := parser.ParseFile(fset, filepath.Join(dir, fileName), nil, parser.PackageClauseOnly|parser.ParseComments)
+		if err != nil {
+			continue
+		}
+		name, ok = f.Name.Name, true
+		if f.Doc != nil {
+			var p doc.Package
+			return name, p.Synopsis(f.Doc.Text()), true
+		}
+	}
+
+	return name, "", ok
+}
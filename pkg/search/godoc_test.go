@@ -0,0 +1,236 @@
+package search
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"reflect"
+	"slices"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestGoDocSearch(t *testing.T) {
+	modCache := t.TempDir()
+	writeFiles(t, modCache, map[string]string{
+		"github.com/!burnt!sushi/toml@v1.3.0/go.mod":            "module github.com/BurntSushi/toml",
+		"github.com/!burnt!sushi/toml@v1.3.0/doc.go":            "// Package toml implements decoding and encoding of TOML files.\npackage toml",
+		"github.com/!burnt!sushi/toml@v1.3.0/decode.go":         "package toml\n\nfunc Decode() {}",
+		"github.com/!burnt!sushi/toml@v1.3.0/decode_test.go":    "// Package toml_test is the wrong doc.\npackage toml_test",
+		"github.com/!burnt!sushi/toml@v1.3.0/internal/tz/tz.go": "// Package tz handles TOML time zones.\npackage tz",
+		"github.com/!burnt!sushi/toml@v1.3.0/cmd/tomlv/main.go": "// Command tomlv validates TOML files.\npackage main",
+		"github.com/!burnt!sushi/toml@v1.2.0/doc.go":            "// Package toml is old.\npackage toml",
+		"golang.org/x/sync@v0.11.0/errgroup/errgroup.go":        "// Package errgroup provides synchronization, error propagation, and Context\n// cancelation for groups of goroutines.\npackage errgroup",
+		"golang.org/x/sync@v0.11.0/nested/go.mod":               "module golang.org/x/sync/nested",
+		"golang.org/x/sync@v0.11.0/nested/nested.go":            "// Package nested is a module of its own.\npackage nested",
+		"cache/download/golang.org/x/sync/@v/v0.11.0.info":      "{}",
+	})
+	old := time.Now().Add(-time.Hour)
+	if err := os.Chtimes(filepath.Join(modCache, "github.com/!burnt!sushi/toml@v1.2.0"), old, old); err != nil {
+		t.Fatal(err)
+	}
+
+	tomlDir := filepath.Join(modCache, "github.com/!burnt!sushi/toml@v1.3.0")
+	var ran []string
+	fakeCommands(t, map[string]string{
+		"go list -e -f {{.ImportPath}}\t{{.Doc}} std": "encoding/json\tPackage json implements encoding and decoding of JSON as defined in RFC 7159.\n" +
+			"internal/abi\tPackage abi describes Go's ABI.\n" +
+			"sync\tPackage sync provides basic synchronization primitives such as mutual exclusion locks.\n",
+		"go doc encoding/json": "package json // import \"encoding/json\"\n\nPackage json implements encoding and decoding of JSON.\n",
+		tomlDir + "$ go doc .": "package toml // import \"github.com/BurntSushi/toml\"\n\nPackage toml implements decoding and encoding of TOML files.\n\nfunc Decode()\n",
+	}, &ran)
+
+	engine := &GoDocEngine{ModCache: modCache}
+	results, err := engine.Search(context.Background(), SearchRequest{Query: "decoding toml", MaxResults: 2, MaxPages: 1})
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	var titles []string
+	for _, result := range results {
+		titles = append(titles, result.Title)
+	}
+	expected := []string{"github.com/BurntSushi/toml@v1.3.0", "encoding/json"}
+	if !reflect.DeepEqual(titles, expected) {
+		t.Fatalf("Expected %v, got %v", expected, titles)
+	}
+
+	toml := results[0]
+	if toml.URL != "https://pkg.go.dev/github.com/BurntSushi/toml@v1.3.0" {
+		t.Errorf("Unexpected URL: %s", toml.URL)
+	}
+	if toml.Snippet != "Package toml implements decoding and encoding of TOML files." {
+		t.Errorf("Unexpected snippet: %q", toml.Snippet)
+	}
+	if toml.Content != "package toml // import \"github.com/BurntSushi/toml\"\n\nPackage toml implements decoding and encoding of TOML files.\n\nfunc Decode()" {
+		t.Errorf("Unexpected content: %q", toml.Content)
+	}
+
+	// Internal packages, commands, nested modules and old versions aren't
+	// indexed
+	var indexed []string
+	for _, pkg := range engine.index.built.docs {
+		indexed = append(indexed, pkg.ImportPath+"@"+pkg.Version)
+	}
+	expected = []string{"encoding/json@", "sync@", "github.com/BurntSushi/toml@v1.3.0", "golang.org/x/sync/errgroup@v0.11.0"}
+	if !reflect.DeepEqual(indexed, expected) {
+		t.Errorf("Expected %v indexed, got %v", expected, indexed)
+	}
+}
+
+func TestGoDocBuild(t *testing.T) {
+	modCache := t.TempDir()
+	list := "go list -e -f {{.ImportPath}}\t{{.Doc}} std"
+	fakeCommands(t, map[string]string{
+		"go doc encoding/json": "package json // import \"encoding/json\"\n",
+	}, nil)
+
+	// Finding nothing is an error, and isn't kept
+	engine := &GoDocEngine{ModCache: modCache}
+	sr := SearchRequest{Query: "json", MaxResults: 1, MaxPages: 1}
+	if _, err := engine.Search(context.Background(), sr); err == nil {
+		t.Fatal("Expected an error with no packages")
+	}
+
+	// A search that runs out of time leaves the build running for the next
+	release := make(chan struct{})
+	orig := runCommand
+	runCommand = func(ctx context.Context, dir string, name string, args ...string) ([]byte, error) {
+		if strings.Join(append([]string{name}, args...), " ") == list {
+			<-release
+			return []byte("encoding/json\tPackage json implements encoding and decoding of JSON.\n"), nil
+		}
+		return orig(ctx, dir, name, args...)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+	_, err := engine.Search(ctx, sr)
+	var engineErr *EngineError
+	if !errors.As(err, &engineErr) || engineErr.Message != "still indexing Go packages" {
+		t.Fatalf("Expected to still be indexing, got %v", err)
+	}
+
+	close(release)
+	results, err := engine.Search(context.Background(), sr)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if len(results) != 1 || results[0].Title != "encoding/json" {
+		t.Errorf("Unexpected results: %v", results)
+	}
+}
+
+// memoryIndexes is an IndexStore that forgets everything at the end of the
+// test
+type memoryIndexes struct {
+	mu    sync.Mutex
+	data  map[string][]byte
+	saved map[string]time.Time
+}
+
+func newMemoryIndexes() *memoryIndexes {
+	return &memoryIndexes{data: make(map[string][]byte), saved: make(map[string]time.Time)}
+}
+
+func (m *memoryIndexes) LoadIndex(engine string, key string) ([]byte, time.Time, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return m.data[engine+" "+key], m.saved[engine+" "+key], nil
+}
+
+func (m *memoryIndexes) SaveIndex(engine string, key string, data []byte) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.data[engine+" "+key] = data
+	m.saved[engine+" "+key] = time.Now()
+	return nil
+}
+
+func TestGoDocStoredIndex(t *testing.T) {
+	modCache := t.TempDir()
+	list := "go list -e -f {{.ImportPath}}\t{{.Doc}} std"
+	commands := map[string]string{
+		"go env GOVERSION":     "go1.23.0\n",
+		list:                   "encoding/json\tPackage json implements encoding and decoding of JSON.\n",
+		"go doc encoding/json": "package json // import \"encoding/json\"\n",
+	}
+	var ran []string
+	fakeCommands(t, commands, &ran)
+
+	store := newMemoryIndexes()
+	sr := SearchRequest{Query: "json", MaxResults: 1, MaxPages: 1}
+	if _, err := (&GoDocEngine{ModCache: modCache, Indexes: store}).Search(context.Background(), sr); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	key := "godoc " + modCache + " go1.23.0"
+	if store.data[key] == nil {
+		t.Fatalf("Expected the index to be stored under %q, got %v", key, store.data)
+	}
+
+	// The next run searches the stored index without building it again
+	ran = nil
+	results, err := (&GoDocEngine{ModCache: modCache, Indexes: store}).Search(context.Background(), sr)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if len(results) != 1 || results[0].Title != "encoding/json" {
+		t.Errorf("Unexpected results: %v", results)
+	}
+	for _, command := range ran {
+		if command == list {
+			t.Error("Expected the stored index to be used")
+		}
+	}
+
+	// Another version of Go has another standard library
+	ran = nil
+	commands["go env GOVERSION"] = "go1.24.0\n"
+	if _, err := (&GoDocEngine{ModCache: modCache, Indexes: store}).Search(context.Background(), sr); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if !slices.Contains(ran, list) {
+		t.Errorf("Expected the index to be built for go1.24.0, ran %v", ran)
+	}
+
+	// An old index is rebuilt, but searched if the search can't wait for that
+	store.saved[key] = time.Now().Add(-2 * goDocIndexMaxAge)
+	commands["go env GOVERSION"] = "go1.23.0\n"
+	release := make(chan struct{})
+	orig := runCommand
+	runCommand = func(ctx context.Context, dir string, name string, args ...string) ([]byte, error) {
+		if strings.Join(append([]string{name}, args...), " ") == list {
+			<-release
+		}
+		return orig(ctx, dir, name, args...)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), requestReserve+200*time.Millisecond)
+	defer cancel()
+	engine := &GoDocEngine{ModCache: modCache, Indexes: store}
+	results, err = engine.Search(ctx, sr)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if len(results) != 1 || results[0].Title != "encoding/json" {
+		t.Errorf("Unexpected results: %v", results)
+	}
+
+	engine.index.mu.Lock()
+	build := engine.index.building
+	engine.index.mu.Unlock()
+	close(release)
+	<-build.done
+	if saved := store.saved[key]; time.Since(saved) > time.Minute {
+		t.Errorf("Expected the rebuilt index to be stored, saved %s", saved)
+	}
+}
+
+func TestUnescapeModulePath(t *testing.T) {
+	if got := unescapeModulePath("github.com/!burnt!sushi/toml"); got != "github.com/BurntSushi/toml" {
+		t.Errorf("Expected github.com/BurntSushi/toml, got %q", got)
+	}
+}
@@ -1,12 +1,20 @@
 package search
 
 import (
+	"context"
 	"encoding/json"
 	"fmt"
 	"net/http"
 	"net/url"
 )
 
+const GoogleBaseURL = "https://www.googleapis.com/customsearch/v1"
+
+const (
+	googlePageSize   = 10
+	googleMaxResults = 100
+)
+
 type googleSearchResult struct {
 	Items []struct {
 		Title   string `json:"title"`
@@ -15,60 +23,126 @@ type googleSearchResult struct {
 	} `json:"items"`
 }
 
-func GoogleSearch(apiKey string, cseID string, query string, maxResults int, filter FilterFunc) ([]SearchResult, error) {
-	baseURL := "https://www.googleapis.com/customsearch/v1"
-	u, err := url.Parse(baseURL)
-	if err != nil {
-		return nil, err
+type GoogleEngine struct {
+	apiKey string
+	cseID  string
+
+	// BaseURL and Client default to GoogleBaseURL and a client with the
+	// usual timeout
+	BaseURL string
+	Client  *http.Client
+}
+
+func init() {
+	Register("google", func(cfg EngineConfig) (SearchEngine, error) {
+		if cfg.Keys.GoogleAPIKey == "" || cfg.Keys.GoogleCSEID == "" {
+			return nil, fmt.Errorf("%w: GOOGLE_API_KEY and GOOGLE_CSE_ID are required", ErrNotConfigured)
+		}
+		return &GoogleEngine{apiKey: cfg.Keys.GoogleAPIKey, cseID: cfg.Keys.GoogleCSEID}, nil
+	})
+}
+
+func (e *GoogleEngine) Name() string { return "google" }
+
+func (e *GoogleEngine) Capabilities() Capabilities {
+	return Capabilities{
+		RequiresKey:          true,
+		MaxResultsPerRequest: googlePageSize,
+		// The free Custom Search JSON API tier
+		DailyQuota: 100,
 	}
+}
 
-	q := u.Query()
-	q.Set("cx", cseID)
-	q.Set("q", query)
-	n := float32(maxResults) * ExtraResultsFactor
-	// fmt.Printf("maxResults: %d, ExtraResultsFactor: %f, n: %f, int(n): %d\n", maxResults, ExtraResultsFactor, n, int(n))
-	q.Set("num", fmt.Sprintf("%d", int(n)))
-	u.RawQuery = q.Encode()
+func GoogleSearch(apiKey string, cseID string, query string, maxResults int, filter FilterFunc) ([]SearchResult, error) {
+	e := &GoogleEngine{apiKey: apiKey, cseID: cseID}
+	return e.Search(context.Background(), SearchRequest{
+		Query:      query,
+		MaxResults: maxResults,
+		Filter:     filter,
+	})
+}
+
+func (e *GoogleEngine) Search(ctx context.Context, sr SearchRequest) ([]SearchResult, error) {
+	client := httpClient(e.Client)
 
-	client := &http.Client{}
+	return collectPages(ctx, sr, func(ctx context.Context, offset int) ([]SearchResult, error) {
+		return e.fetchPage(ctx, client, sr, offset)
+	})
+}
+
+// CSE returns at most 10 results per request and won't go past the 100th
+// result, so asking for more means paging with start.
+func (e *GoogleEngine) fetchPage(ctx context.Context, client *http.Client, sr SearchRequest, offset int) ([]SearchResult, error) {
+	if offset >= googleMaxResults {
+		return nil, nil
+	}
 
-	req, err := http.NewRequest("GET", u.String(), nil)
+	baseURL := e.BaseURL
+	if baseURL == "" {
+		baseURL = GoogleBaseURL
+	}
+	u, err := url.Parse(baseURL)
 	if err != nil {
 		return nil, err
 	}
 
-	req.Header.Add("X-goog-api-key", apiKey)
+	n := pageSize(sr, googlePageSize)
+	n = min(n, googleMaxResults-offset)
+
+	q := u.Query()
+	q.Set("cx", e.cseID)
+	q.Set("q", sr.QueryText())
+	q.Set("num", fmt.Sprintf("%d", n))
+	if offset > 0 {
+		q.Set("start", fmt.Sprintf("%d", offset+1))
+	}
+	if country := sr.Country(); country != "" {
+		q.Set("gl", country)
+	}
+	if lang := sr.Lang(); lang != "" {
+		q.Set("lr", "lang_"+lang)
+		q.Set("hl", lang)
+	}
+	// CSE only has on and off
+	switch sr.SafeSearch {
+	case "":
+	case SafeSearchOff:
+		q.Set("safe", "off")
+	default:
+		q.Set("safe", "active")
+	}
+	if sr.Since > 0 {
+		// dateRestrict takes d[N], w[N], m[N] or y[N]; days are the most exact
+		q.Set("dateRestrict", fmt.Sprintf("d%d", sr.sinceDays()))
+	}
+	u.RawQuery = q.Encode()
 
-	resp, err := client.Do(req)
+	resp, err := doRequest(ctx, client, e.Name(), func(ctx context.Context) (*http.Request, error) {
+		req, err := http.NewRequestWithContext(ctx, "GET", u.String(), nil)
+		if err != nil {
+			return nil, err
+		}
+		req.Header.Add("X-goog-api-key", e.apiKey)
+		return req, nil
+	})
 	if err != nil {
 		return nil, err
 	}
 	defer resp.Body.Close()
 
-	if resp.StatusCode != http.StatusOK {
-		return nil, fmt.Errorf("Google Search API error: %s", resp.Status)
-	}
-
 	var result googleSearchResult
 	if err := json.NewDecoder(resp.Body).Decode(&result); err != nil {
-		return nil, err
+		return nil, &EngineError{Engine: e.Name(), Message: "error decoding response", Err: err}
 	}
 
-	filteredResults := make([]SearchResult, 0, maxResults)
+	results := make([]SearchResult, 0, len(result.Items))
 	for _, item := range result.Items {
-		r := SearchResult{
+		results = append(results, SearchResult{
 			Title:   item.Title,
 			URL:     item.Link,
 			Snippet: item.Snippet,
-		}
-
-		if filter == nil || filter(r) {
-			filteredResults = append(filteredResults, r)
-			if len(filteredResults) == maxResults {
-				break
-			}
-		}
+		})
 	}
 
-	return filteredResults, nil
+	return results, nil
 }
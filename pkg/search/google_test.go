@@ -0,0 +1,127 @@
+package search
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+// googleServer serves the two pages of google fixtures by start
+func googleServer(t *testing.T, requests *[]*http.Request) *httptest.Server {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		*requests = append(*requests, r)
+		w.Header().Set("Content-Type", "application/json")
+		switch r.URL.Query().Get("start") {
+		case "":
+			w.Write(fixture(t, "google_page1.json"))
+		case "4":
+			w.Write(fixture(t, "google_page2.json"))
+		default:
+			w.Write([]byte(`{"kind": "customsearch#search"}`))
+		}
+	}))
+	t.Cleanup(server.Close)
+
+	return server
+}
+
+func TestGoogleSearch(t *testing.T) {
+	var requests []*http.Request
+	server := googleServer(t, &requests)
+
+	engine := &GoogleEngine{apiKey: "key", cseID: "cse", BaseURL: server.URL, Client: server.Client()}
+	results, err := engine.Search(context.Background(), SearchRequest{
+		Query:      "golang generics",
+		MaxResults: 4,
+		Region:     "gb-en",
+		SafeSearch: SafeSearchOff,
+		Since:      3 * 24 * time.Hour,
+		Filter: func(r SearchResult) bool {
+			return !strings.Contains(r.URL, "medium.com")
+		},
+	})
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	expected := []string{
+		"https://go.dev/doc/tutorial/generics",
+		"https://go.dev/blog/intro-generics",
+		"https://gobyexample.com/generics",
+		"https://go.googlesource.com/proposal/+/refs/heads/master/design/43651-type-parameters.md",
+	}
+	if !reflect.DeepEqual(urls(results), expected) {
+		t.Errorf("Expected %v, got %v", expected, urls(results))
+	}
+	if results[2].Title != "Go by Example: Generics" {
+		t.Errorf("Unexpected title: %q", results[2].Title)
+	}
+
+	if len(requests) != 2 {
+		t.Fatalf("Expected 2 requests, got %d", len(requests))
+	}
+	want := map[string]string{
+		"cx":           "cse",
+		"q":            "golang generics",
+		"num":          "8",
+		"gl":           "gb",
+		"lr":           "lang_en",
+		"safe":         "off",
+		"dateRestrict": "d3",
+	}
+	for k, v := range want {
+		if got := requests[0].URL.Query().Get(k); got != v {
+			t.Errorf("Expected %s=%q, got %q", k, v, got)
+		}
+	}
+	if key := requests[0].Header.Get("X-goog-api-key"); key != "key" {
+		t.Errorf("Expected the API key in the header, got %q", key)
+	}
+	if start := requests[1].URL.Query().Get("start"); start != "4" {
+		t.Errorf("Expected start=4 for the second page, got %q", start)
+	}
+}
+
+func TestGoogleSearchErrors(t *testing.T) {
+	defer func(orig RetryPolicy) { DefaultRetryPolicy = orig }(DefaultRetryPolicy)
+	DefaultRetryPolicy = RetryPolicy{MaxAttempts: 2, BaseDelay: time.Millisecond, MaxDelay: 10 * time.Millisecond}
+
+	testCases := []struct {
+		name   string
+		status int
+		body   []byte
+		kind   error
+	}{
+		{"Quota", http.StatusTooManyRequests, fixture(t, "google_quota.json"), ErrQuotaExceeded},
+		{"Bad key", http.StatusBadRequest, []byte(`{"error": {"code": 400, "message": "API key not valid."}}`), nil},
+		{"Forbidden", http.StatusForbidden, []byte(`{"error": {"code": 403, "message": "Forbidden"}}`), ErrAuthFailed},
+		{"Server error", http.StatusInternalServerError, []byte(`{}`), ErrTransient},
+		{"Bad JSON", http.StatusOK, []byte(`{"items": [`), nil},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				w.WriteHeader(tc.status)
+				w.Write(tc.body)
+			}))
+			defer server.Close()
+
+			engine := &GoogleEngine{apiKey: "key", cseID: "cse", BaseURL: server.URL, Client: server.Client()}
+			_, err := engine.Search(context.Background(), SearchRequest{Query: "golang", MaxResults: 3})
+
+			var engineErr *EngineError
+			if !errors.As(err, &engineErr) {
+				t.Fatalf("Expected an EngineError, got %v", err)
+			}
+			if engineErr.Kind != tc.kind {
+				t.Errorf("Expected kind %v, got %v", tc.kind, engineErr.Kind)
+			}
+		})
+	}
+}
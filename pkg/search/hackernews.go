@@ -0,0 +1,211 @@
+package search
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	"ask-web/pkg/logger"
+)
+
+const HackerNewsBaseURL = "https://hn.algolia.com/api/v1"
+
+const hackerNewsPageSize = 20
+
+// Stories to fetch the comments of, after filtering; each is another request
+const hackerNewsMaxThreads = 5
+
+const hackerNewsItemURL = "https://news.ycombinator.com/item?id="
+
+type hackerNewsHit struct {
+	ObjectID    string `json:"objectID"`
+	Title       string `json:"title"`
+	URL         string `json:"url"`
+	Author      string `json:"author"`
+	Points      int    `json:"points"`
+	NumComments int    `json:"num_comments"`
+	StoryText   string `json:"story_text"`
+	CreatedAtI  int64  `json:"created_at_i"`
+}
+
+type hackerNewsSearchResponse struct {
+	Hits []hackerNewsHit `json:"hits"`
+}
+
+// An item is a story or a comment, with its replies
+type hackerNewsItem struct {
+	Author   string           `json:"author"`
+	Text     string           `json:"text"`
+	Points   *int             `json:"points"`
+	Children []hackerNewsItem `json:"children"`
+}
+
+// HackerNewsEngine searches Hacker News stories through Algolia's API. The
+// result is the discussion rather than the story's link: the comment tree,
+// flattened into threaded text, is the Content.
+type HackerNewsEngine struct {
+	// BaseURL and Client default to HackerNewsBaseURL and a client with the
+	// usual timeout
+	BaseURL string
+	Client  *http.Client
+}
+
+func init() {
+	Register("hackernews", func(cfg EngineConfig) (SearchEngine, error) {
+		return &HackerNewsEngine{}, nil
+	})
+}
+
+func (e *HackerNewsEngine) Name() string { return "hackernews" }
+
+func (e *HackerNewsEngine) Capabilities() Capabilities {
+	// Algolia allows 10,000 requests an hour, which we won't get near
+	return Capabilities{
+		MaxResultsPerRequest: hackerNewsPageSize,
+	}
+}
+
+func (e *HackerNewsEngine) Search(ctx context.Context, sr SearchRequest) ([]SearchResult, error) {
+	if sr.Query == "" {
+		return nil, fmt.Errorf("search query cannot be empty")
+	}
+
+	client := httpClient(e.Client)
+
+	// Pages are numbered from 0, so the page size has to stay the same
+	count := pageSize(sr, hackerNewsPageSize)
+	page := sr.Offset / count
+	details := make(map[string]detailFetcher)
+
+	results, err := collectPages(ctx, sr, func(ctx context.Context, offset int) ([]SearchResult, error) {
+		results, err := e.fetchPage(ctx, client, sr, count, page, details)
+		page++
+		return results, err
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	// The stories are still worth something without their comments
+	err = fetchDetails(ctx, results, hackerNewsMaxThreads, func(ctx context.Context, result *SearchResult) error {
+		if fetch, ok := details[result.URL]; ok {
+			return fetch(ctx, result)
+		}
+		return nil
+	})
+	if err != nil {
+		logger.GetLogger().Warn("Stopped getting Hacker News comments: ", err)
+	}
+
+	return results, nil
+}
+
+// fetchPage gets a page of stories, without their comments; details gets
+// what fetches them.
+func (e *HackerNewsEngine) fetchPage(ctx context.Context, client *http.Client, sr SearchRequest, count int, page int, details map[string]detailFetcher) ([]SearchResult, error) {
+	params := url.Values{}
+	params.Set("query", sr.Query)
+	params.Set("tags", "story")
+	params.Set("hitsPerPage", fmt.Sprintf("%d", count))
+	params.Set("page", fmt.Sprintf("%d", page))
+	if sr.Since > 0 {
+		params.Set("numericFilters", fmt.Sprintf("created_at_i>%d", sr.SinceDate().Unix()))
+	}
+
+	var searchResp hackerNewsSearchResponse
+	if err := e.get(ctx, client, "/search?"+params.Encode(), &searchResp); err != nil {
+		return nil, err
+	}
+
+	results := make([]SearchResult, 0, len(searchResp.Hits))
+	for _, hit := range searchResp.Hits {
+		itemURL := hackerNewsItemURL + hit.ObjectID
+		if hit.NumComments > 0 {
+			details[itemURL] = func(ctx context.Context, result *SearchResult) error {
+				var story hackerNewsItem
+				if err := e.get(ctx, client, "/items/"+url.PathEscape(hit.ObjectID), &story); err != nil {
+					return err
+				}
+				result.Content = hackerNewsContent(hit, story)
+				return nil
+			}
+		}
+
+		snippet := fmt.Sprintf("%d points, %d comments", hit.Points, hit.NumComments)
+		if hit.StoryText != "" {
+			snippet = truncateText(htmlText(hit.StoryText), maxSnippetLength)
+		} else if hit.URL != "" {
+			snippet += "; links to " + hit.URL
+		}
+		results = append(results, SearchResult{
+			Title:     hit.Title,
+			URL:       itemURL,
+			Snippet:   snippet,
+			Content:   hackerNewsContent(hit, hackerNewsItem{Text: hit.StoryText}),
+			Published: unixTime(hit.CreatedAtI),
+		})
+	}
+
+	return results, nil
+}
+
+func hackerNewsContent(hit hackerNewsHit, story hackerNewsItem) string {
+	var b strings.Builder
+	fmt.Fprintf(&b, "# %s (%d points, %d comments)\n", hit.Title, hit.Points, hit.NumComments)
+	if hit.URL != "" {
+		b.WriteString("Link: " + hit.URL + "\n")
+	}
+	b.WriteString("Posted by " + hit.Author)
+	if posted := unixTime(hit.CreatedAtI); !posted.IsZero() {
+		b.WriteString(" on " + posted.UTC().Format(time.DateOnly))
+	}
+	b.WriteString("\n")
+	if story.Text != "" {
+		b.WriteString("\n" + htmlToMarkdown(story.Text) + "\n")
+	}
+
+	if comments := flattenThread(hackerNewsThread(story.Children)); comments != "" {
+		b.WriteString("\n## Comments\n\n" + comments + "\n")
+	}
+
+	return b.String()
+}
+
+// Comments come as HTML, and without scores
+func hackerNewsThread(items []hackerNewsItem) []threadComment {
+	comments := make([]threadComment, 0, len(items))
+	for _, item := range items {
+		comments = append(comments, threadComment{
+			Author:  item.Author,
+			Score:   item.Points,
+			Text:    htmlToMarkdown(item.Text),
+			Replies: hackerNewsThread(item.Children),
+		})
+	}
+	return comments
+}
+
+func (e *HackerNewsEngine) get(ctx context.Context, client *http.Client, path string, v any) error {
+	baseURL := e.BaseURL
+	if baseURL == "" {
+		baseURL = HackerNewsBaseURL
+	}
+
+	resp, err := doRequest(ctx, client, e.Name(), func(ctx context.Context) (*http.Request, error) {
+		return http.NewRequestWithContext(ctx, "GET", baseURL+path, nil)
+	})
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if err := json.NewDecoder(resp.Body).Decode(v); err != nil {
+		return &EngineError{Engine: e.Name(), Message: "error decoding response", Err: err}
+	}
+
+	return nil
+}
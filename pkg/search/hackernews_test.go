@@ -0,0 +1,96 @@
+package search
+
+import (
+	"context"
+	"net/http"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestHackerNewsSearch(t *testing.T) {
+	var requests []*http.Request
+	server := fixtureServer(t, map[string]string{
+		"/search":         "hackernews_search.json",
+		"/items/38000001": "hackernews_item.json",
+	}, &requests)
+
+	engine := &HackerNewsEngine{BaseURL: server.URL, Client: server.Client()}
+	results, err := engine.Search(context.Background(), SearchRequest{Query: "kubernetes small team", MaxResults: 2, Since: 30 * 24 * time.Hour})
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	// The discussion, not the story's link
+	expected := []string{
+		"https://news.ycombinator.com/item?id=38000001",
+		"https://news.ycombinator.com/item?id=38000002",
+	}
+	if !reflect.DeepEqual(urls(results), expected) {
+		t.Fatalf("Expected %v, got %v", expected, urls(results))
+	}
+
+	story := results[0]
+	if !story.Published.Equal(time.Unix(1698000000, 0)) {
+		t.Errorf("Unexpected published date: %s", story.Published)
+	}
+	if !strings.HasPrefix(story.Content, "# Why we moved off Kubernetes (412 points, 3 comments)\nLink: https://example.com/blog/leaving-kubernetes\n") {
+		t.Errorf("Unexpected heading: %q", story.Content)
+	}
+	thread := "## Comments\n\n" +
+		"- ops_person:\n  It depends on the team.\n\n  We run `k3s` and it's fine:\n\n  ```\n  k3s server --disable traefik\n  ```\n" +
+		"  - founder:\n    Fair, but we had nobody to run it.\n"
+	if !strings.HasSuffix(story.Content, thread) {
+		t.Errorf("Expected the comment thread %q, got %q", thread, story.Content)
+	}
+
+	ask := results[1]
+	if ask.Snippet != "We are five engineers & one product. Is it overkill?" {
+		t.Errorf("Unexpected snippet: %q", ask.Snippet)
+	}
+	if strings.Contains(ask.Content, "## Comments") {
+		t.Errorf("Expected no comments, got %q", ask.Content)
+	}
+
+	// Only the story with comments costs another request
+	if len(requests) != 2 {
+		t.Fatalf("Expected 2 requests, got %d", len(requests))
+	}
+	q := requests[0].URL.Query()
+	if q.Get("query") != "kubernetes small team" || q.Get("tags") != "story" || q.Get("hitsPerPage") != "4" || q.Get("page") != "0" {
+		t.Errorf("Unexpected search request: %v", q)
+	}
+	if !strings.HasPrefix(q.Get("numericFilters"), "created_at_i>") {
+		t.Errorf("Expected a created_at_i filter for --since, got %q", q.Get("numericFilters"))
+	}
+}
+
+func TestFlattenThread(t *testing.T) {
+	score := func(n int) *int { return &n }
+
+	comments := []threadComment{
+		{Author: "a", Score: score(10), Text: "First\nline two", Replies: []threadComment{
+			{Author: "b", Score: score(-2), Text: "Reply"},
+		}},
+		// Deleted, but the reply is still worth having
+		{Replies: []threadComment{{Author: "c", Text: "Orphan"}}},
+		{Author: "d"},
+	}
+	expected := "- a (10 points):\n  First\n  line two\n" +
+		"  - b (-2 points):\n    Reply\n" +
+		"- [deleted]:\n  [deleted]\n" +
+		"  - c:\n    Orphan"
+	if got := flattenThread(comments); got != expected {
+		t.Errorf("Expected %q, got %q", expected, got)
+	}
+
+	// Too deep
+	deep := threadComment{Author: "x", Text: "bottom"}
+	for i := 0; i < threadMaxDepth; i++ {
+		deep = threadComment{Author: "x", Text: "up", Replies: []threadComment{deep}}
+	}
+	if got := flattenThread([]threadComment{deep}); strings.Contains(got, "bottom") {
+		t.Errorf("Expected replies below depth %d to be dropped, got %q", threadMaxDepth, got)
+	}
+}
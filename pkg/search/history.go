@@ -0,0 +1,66 @@
+package search
+
+import (
+	"context"
+	"fmt"
+	"time"
+)
+
+// Results per request; they come with their text, so they're not cheap
+const historyPageSize = 20
+
+// PageIndex searches the text of pages fetched on earlier runs. It's
+// implemented by the database package; search only knows about the
+// interface.
+type PageIndex interface {
+	// SearchPages returns the pages that best match query, best first, with
+	// their Content filled in. Pages from before since (if it's set) are
+	// left out.
+	SearchPages(query string, since time.Time, limit int, offset int) ([]SearchResult, error)
+}
+
+// HistoryEngine searches the pages downloaded on earlier runs, so that
+// questions close to earlier ones can reuse their sources straight away,
+// and offline.
+type HistoryEngine struct {
+	pages PageIndex
+}
+
+func init() {
+	Register("history", func(cfg EngineConfig) (SearchEngine, error) {
+		if cfg.Pages == nil {
+			return nil, fmt.Errorf("%w: no page history", ErrNotConfigured)
+		}
+		return NewHistoryEngine(cfg.Pages), nil
+	})
+}
+
+func NewHistoryEngine(pages PageIndex) *HistoryEngine {
+	return &HistoryEngine{pages: pages}
+}
+
+func (e *HistoryEngine) Name() string { return "history" }
+
+func (e *HistoryEngine) Capabilities() Capabilities {
+	return Capabilities{MaxResultsPerRequest: historyPageSize}
+}
+
+func (e *HistoryEngine) Search(ctx context.Context, sr SearchRequest) ([]SearchResult, error) {
+	if sr.Query == "" {
+		return nil, fmt.Errorf("search query cannot be empty")
+	}
+
+	var since time.Time
+	if sr.Since > 0 {
+		since = sr.SinceDate()
+	}
+
+	count := pageSize(sr, historyPageSize)
+	return collectPages(ctx, sr, func(ctx context.Context, offset int) ([]SearchResult, error) {
+		results, err := e.pages.SearchPages(sr.Query, since, count, offset)
+		if err != nil {
+			return nil, &EngineError{Engine: e.Name(), Message: "error searching page history", Err: err}
+		}
+		return results, nil
+	})
+}
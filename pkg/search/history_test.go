@@ -0,0 +1,77 @@
+package search
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"testing"
+	"time"
+)
+
+type fakePages struct {
+	pages []SearchResult
+	since []time.Time
+	err   error
+}
+
+func (f *fakePages) SearchPages(query string, since time.Time, limit int, offset int) ([]SearchResult, error) {
+	f.since = append(f.since, since)
+	if f.err != nil {
+		return nil, f.err
+	}
+	if offset >= len(f.pages) {
+		return nil, nil
+	}
+	return f.pages[offset:min(offset+limit, len(f.pages))], nil
+}
+
+func TestHistorySearch(t *testing.T) {
+	pages := &fakePages{}
+	for i := range 25 {
+		pages.pages = append(pages.pages, SearchResult{
+			Title:   fmt.Sprintf("Page %d", i),
+			URL:     fmt.Sprintf("https://example.com/%d", i),
+			Content: "text",
+		})
+	}
+
+	engine := NewHistoryEngine(pages)
+	results, err := engine.Search(context.Background(), SearchRequest{Query: "go iterators", MaxResults: 22, Since: 24 * time.Hour})
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if len(results) != 22 || results[21].URL != "https://example.com/21" {
+		t.Fatalf("Expected the first 22 pages, got %v", urls(results))
+	}
+	if len(pages.since) != 2 {
+		t.Errorf("Expected 2 pages of results, got %d", len(pages.since))
+	}
+	if age := time.Since(pages.since[0]); age < 23*time.Hour || age > 25*time.Hour {
+		t.Errorf("Expected pages from the last day, got since %v", pages.since[0])
+	}
+
+	pages.since = nil
+	if _, err := engine.Search(context.Background(), SearchRequest{Query: "go iterators", MaxResults: 5}); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if !pages.since[0].IsZero() {
+		t.Errorf("Expected no time limit, got %v", pages.since[0])
+	}
+}
+
+func TestHistoryError(t *testing.T) {
+	engine := NewHistoryEngine(&fakePages{err: errors.New("database is locked")})
+	_, err := engine.Search(context.Background(), SearchRequest{Query: "go iterators", MaxResults: 5})
+
+	var engineErr *EngineError
+	if !errors.As(err, &engineErr) || engineErr.Engine != "history" {
+		t.Errorf("Expected an EngineError from history, got %v", err)
+	}
+}
+
+func TestHistoryNotConfigured(t *testing.T) {
+	_, errs := NewEngines([]string{"history"}, nil, EngineConfig{})
+	if len(errs) != 1 || !errors.Is(errs[0], ErrNotConfigured) {
+		t.Errorf("Expected ErrNotConfigured without a page index, got %v", errs)
+	}
+}
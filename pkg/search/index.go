@@ -0,0 +1,208 @@
+package search
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"sync"
+	"time"
+
+	"ask-web/pkg/logger"
+)
+
+// IndexStore keeps the indexes that engines build of what's on this machine,
+// so that later runs don't have to build them again. It's implemented by the
+// database package.
+type IndexStore interface {
+	// LoadIndex returns what was saved for engine under key, and when, or
+	// nil if nothing was.
+	LoadIndex(engine string, key string) ([]byte, time.Time, error)
+	SaveIndex(engine string, key string, data []byte) error
+}
+
+// Longest an index can take to build. It isn't held to a search's timeout,
+// since walking a big module cache or notes directory can take longer than
+// that.
+const indexBuildTimeout = 5 * time.Minute
+
+// storedIndex is an engine's BM25 index of docs of type T. It's built in the
+// background, outside any one search's timeout, and kept in store (if there
+// is one) for the next run. A stored index younger than maxAge is used as
+// it is; an older one is rebuilt, and only searched if the search can't
+// wait for that. A failed build isn't kept, so the next search tries again.
+type storedIndex[T any] struct {
+	engine string
+	// What's indexed, for errors, eg "Go packages"
+	what   string
+	store  IndexStore
+	maxAge time.Duration
+	// key says which of the engine's indexes the store has, eg which
+	// directories it's of
+	key func(ctx context.Context) string
+	// build finds the docs, and text is what's indexed of each
+	build func(ctx context.Context) ([]T, error)
+	text  func(doc T) string
+
+	mu       sync.Mutex
+	built    *docIndex[T]
+	building *indexBuild[T]
+}
+
+type docIndex[T any] struct {
+	docs  []T
+	index *BM25Index
+}
+
+// A build that searches can wait for. stored is set before loaded is
+// closed, and index and err before done is.
+type indexBuild[T any] struct {
+	loaded chan struct{}
+	stored *docIndex[T]
+	done   chan struct{}
+	index  *docIndex[T]
+	err    error
+}
+
+// get returns the index, waiting for it to be built if need be, as long as
+// ctx leaves time to search it.
+func (s *storedIndex[T]) get(ctx context.Context) (*docIndex[T], error) {
+	s.mu.Lock()
+	if s.built != nil {
+		defer s.mu.Unlock()
+		return s.built, nil
+	}
+	b := s.building
+	if b == nil {
+		b = &indexBuild[T]{loaded: make(chan struct{}), done: make(chan struct{})}
+		s.building = b
+		go s.run(b)
+	}
+	s.mu.Unlock()
+
+	wait := ctx
+	if deadline, ok := ctx.Deadline(); ok {
+		var cancel context.CancelFunc
+		wait, cancel = context.WithDeadline(ctx, deadline.Add(-requestReserve))
+		defer cancel()
+	}
+	select {
+	case <-b.done:
+	case <-wait.Done():
+	}
+
+	done := closed(b.done)
+	if done && b.err == nil {
+		return b.index, nil
+	}
+	if closed(b.loaded) && b.stored != nil {
+		if done {
+			logger.GetLogger().Warn(fmt.Sprintf("Searching the stored index of %s: %s", s.what, b.err))
+		}
+		return b.stored, nil
+	}
+	if done {
+		return nil, &EngineError{Engine: s.engine, Message: "error indexing " + s.what, Err: b.err}
+	}
+	return nil, &EngineError{Engine: s.engine, Message: "still indexing " + s.what, Err: ctx.Err()}
+}
+
+func (s *storedIndex[T]) run(b *indexBuild[T]) {
+	ctx, cancel := context.WithTimeout(context.Background(), indexBuildTimeout)
+	defer cancel()
+
+	defer func() {
+		// Nothing else would catch it out here, and it would take the whole
+		// run down with it
+		if r := recover(); r != nil {
+			b.index, b.err = nil, fmt.Errorf("panic: %v", r)
+		}
+		if !closed(b.loaded) {
+			close(b.loaded)
+		}
+
+		s.mu.Lock()
+		if b.err == nil {
+			s.built = b.index
+		}
+		s.building = nil
+		s.mu.Unlock()
+		close(b.done)
+	}()
+
+	var key string
+	fresh := false
+	if s.store != nil {
+		key = s.key(ctx)
+		fresh = s.load(key, b)
+	}
+	close(b.loaded)
+	if fresh {
+		b.index = b.stored
+		return
+	}
+
+	docs, err := s.build(ctx)
+	if err == nil && ctx.Err() != nil {
+		err = ctx.Err()
+	}
+	if err != nil {
+		b.err = err
+		return
+	}
+	b.index = s.newIndex(docs)
+
+	if s.store != nil {
+		s.save(key, docs)
+	}
+}
+
+// load reads the stored index into b.stored, and says whether it's recent
+// enough to use without building it again.
+func (s *storedIndex[T]) load(key string, b *indexBuild[T]) bool {
+	log := logger.GetLogger()
+
+	data, saved, err := s.store.LoadIndex(s.engine, key)
+	if err != nil {
+		log.Warn(fmt.Sprintf("Error loading the index of %s: %s", s.what, err))
+		return false
+	}
+	if data == nil {
+		return false
+	}
+
+	var docs []T
+	if err := json.Unmarshal(data, &docs); err != nil {
+		log.Warn(fmt.Sprintf("Error loading the index of %s: %s", s.what, err))
+		return false
+	}
+	b.stored = s.newIndex(docs)
+
+	return time.Since(saved) < s.maxAge
+}
+
+func (s *storedIndex[T]) save(key string, docs []T) {
+	data, err := json.Marshal(docs)
+	if err == nil {
+		err = s.store.SaveIndex(s.engine, key, data)
+	}
+	if err != nil {
+		logger.GetLogger().Warn(fmt.Sprintf("Error saving the index of %s: %s", s.what, err))
+	}
+}
+
+func (s *storedIndex[T]) newIndex(docs []T) *docIndex[T] {
+	index := &docIndex[T]{docs: docs, index: NewBM25Index()}
+	for _, doc := range docs {
+		index.index.Add(s.text(doc))
+	}
+	return index
+}
+
+func closed(ch chan struct{}) bool {
+	select {
+	case <-ch:
+		return true
+	default:
+		return false
+	}
+}
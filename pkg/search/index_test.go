@@ -0,0 +1,67 @@
+package search
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"testing"
+	"time"
+)
+
+func TestStoredIndex(t *testing.T) {
+	store := newMemoryIndexes()
+	builds := 0
+	var fail error
+	newIndex := func() *storedIndex[string] {
+		return &storedIndex[string]{
+			engine: "test",
+			what:   "words",
+			store:  store,
+			key:    func(ctx context.Context) string { return "key" },
+			build: func(ctx context.Context) ([]string, error) {
+				builds++
+				if builds == 1 {
+					panic("oops")
+				}
+				return []string{"alpha", "beta"}, fail
+			},
+			text: func(doc string) string { return doc },
+		}
+	}
+
+	// A panic is an error rather than the end of the run, and isn't kept
+	index := newIndex()
+	_, err := index.get(context.Background())
+	var engineErr *EngineError
+	if !errors.As(err, &engineErr) || engineErr.Message != "error indexing words" {
+		t.Fatalf("Expected an indexing error, got %v", err)
+	}
+	built, err := index.get(context.Background())
+	if err != nil || len(built.docs) != 2 || builds != 2 {
+		t.Fatalf("Expected the second build to be used, got %v (%v)", built, err)
+	}
+
+	// A failed rebuild falls back to the stored index
+	fail = errors.New("no disk")
+	built, err = newIndex().get(context.Background())
+	if err != nil || len(built.index.Search("beta")) != 1 {
+		t.Errorf("Expected the stored index, got %v (%v)", built, err)
+	}
+
+	// ...unless there isn't one
+	store = newMemoryIndexes()
+	if _, err := newIndex().get(context.Background()); err == nil {
+		t.Error("Expected an error without a stored index")
+	}
+
+	// The stored index is the docs
+	fail = nil
+	newIndex().get(context.Background())
+	var docs []string
+	if err := json.Unmarshal(store.data["test key"], &docs); err != nil || len(docs) != 2 {
+		t.Errorf("Unexpected stored index: %s (%v)", store.data["test key"], err)
+	}
+	if saved := store.saved["test key"]; time.Since(saved) > time.Minute {
+		t.Errorf("Unexpected save time: %s", saved)
+	}
+}
@@ -0,0 +1,180 @@
+package search
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"time"
+)
+
+// UsageTracker counts requests per engine per day. It's implemented by the
+// database package so that the counts survive between runs and are shared by
+// concurrent ones.
+type UsageTracker interface {
+	// Use records one request for engine on day, unless limit > 0 and that
+	// many have already been made. It returns false if the request would go
+	// over the limit.
+	Use(engine string, day string, limit int) (bool, error)
+	// Usage returns how many requests engine has made on day.
+	Usage(engine string, day string) (int, error)
+}
+
+// Quotas reset at midnight UTC, which is close enough to what the APIs do
+// (Google's is midnight Pacific) without caring where the user is.
+func QuotaDay(t time.Time) string {
+	return t.UTC().Format(time.DateOnly)
+}
+
+// LimitedEngine wraps an engine so that its requests are spaced out by its
+// MinInterval and counted against its daily quota. Every page fetched counts
+// as a request, since that's what the APIs charge for.
+type LimitedEngine struct {
+	SearchEngine
+	usage    UsageTracker
+	quota    int
+	interval time.Duration
+
+	mu   sync.Mutex
+	next time.Time
+}
+
+// NewLimitedEngine uses the engine's own Capabilities for its limits, except
+// that quota overrides the daily quota if it's >= 0 (0 meaning unlimited).
+func NewLimitedEngine(engine SearchEngine, usage UsageTracker, quota int) *LimitedEngine {
+	caps := engine.Capabilities()
+	if quota < 0 {
+		quota = caps.DailyQuota
+	}
+
+	return &LimitedEngine{
+		SearchEngine: engine,
+		usage:        usage,
+		quota:        quota,
+		interval:     caps.MinInterval,
+	}
+}
+
+// WithLimits wraps each of engines in a LimitedEngine. quotas overrides the
+// daily quotas for the engines it names.
+func WithLimits(engines []SearchEngine, usage UsageTracker, quotas map[string]int) []SearchEngine {
+	limited := make([]SearchEngine, 0, len(engines))
+	for _, engine := range engines {
+		quota, ok := quotas[engine.Name()]
+		if !ok {
+			quota = -1
+		}
+		limited = append(limited, NewLimitedEngine(engine, usage, quota))
+	}
+	return limited
+}
+
+// Quota returns the daily quota in effect, 0 meaning unlimited.
+func (e *LimitedEngine) Quota() int {
+	return e.quota
+}
+
+// Remaining returns how many requests are left today, or -1 if there's no
+// quota.
+func (e *LimitedEngine) Remaining() (int, error) {
+	if e.quota <= 0 {
+		return -1, nil
+	}
+
+	used, err := e.usage.Usage(e.Name(), QuotaDay(time.Now()))
+	if err != nil {
+		return 0, err
+	}
+
+	return max(e.quota-used, 0), nil
+}
+
+func (e *LimitedEngine) Search(ctx context.Context, sr SearchRequest) ([]SearchResult, error) {
+	// Fail fast rather than waiting out the rate limit to find out
+	if remaining, err := e.Remaining(); err == nil && remaining == 0 {
+		return nil, e.quotaError()
+	}
+
+	return e.SearchEngine.Search(context.WithValue(ctx, requestGateKey{}, e), sr)
+}
+
+// acquire waits until the engine may make another request and counts it
+// against the quota.
+func (e *LimitedEngine) acquire(ctx context.Context) error {
+	e.mu.Lock()
+	wait := time.Until(e.next)
+	e.next = time.Now().Add(max(wait, 0) + e.interval)
+	e.mu.Unlock()
+
+	if wait > 0 {
+		timer := time.NewTimer(wait)
+		defer timer.Stop()
+		select {
+		case <-timer.C:
+		case <-ctx.Done():
+			return ctx.Err()
+		}
+	}
+
+	ok, err := e.usage.Use(e.Name(), QuotaDay(time.Now()), e.quota)
+	if err != nil {
+		// Not being able to count shouldn't stop the search
+		return nil
+	}
+	if !ok {
+		return e.quotaError()
+	}
+
+	return nil
+}
+
+func (e *LimitedEngine) quotaError() error {
+	return &EngineError{
+		Engine:  e.Name(),
+		Kind:    ErrQuotaExceeded,
+		Message: fmt.Sprintf("%d requests today", e.quota),
+	}
+}
+
+// LimitedEngine.Search passes itself to collectPages under this key, so that
+// each page fetch goes through acquire
+type requestGateKey struct{}
+
+func acquireRequest(ctx context.Context) error {
+	if e, ok := ctx.Value(requestGateKey{}).(*LimitedEngine); ok {
+		return e.acquire(ctx)
+	}
+	return nil
+}
+
+// withoutRequestGate is for requests that an engine's rate limit and quota
+// don't cover, eg GitHub's REST API, so that their retries aren't gated
+// either.
+func withoutRequestGate(ctx context.Context) context.Context {
+	return context.WithValue(ctx, requestGateKey{}, nil)
+}
+
+// Time to leave for the last request within a requestBudget
+const requestReserve = 2 * time.Second
+
+// requestBudget is how many more requests a LimitedEngine would let through
+// with requestReserve to spare before ctx's deadline, or -1 if there's no
+// limit to it. Engines that make several requests a search, with a long
+// MinInterval, use it to stop in time: waiting past the deadline would lose
+// the results they already have.
+func requestBudget(ctx context.Context) int {
+	e, ok := ctx.Value(requestGateKey{}).(*LimitedEngine)
+	deadline, hasDeadline := ctx.Deadline()
+	if !ok || !hasDeadline || e.interval <= 0 {
+		return -1
+	}
+
+	e.mu.Lock()
+	wait := max(time.Until(e.next), 0)
+	e.mu.Unlock()
+
+	left := time.Until(deadline) - requestReserve - wait
+	if left < 0 {
+		return 0
+	}
+	return 1 + int(left/e.interval)
+}
@@ -0,0 +1,141 @@
+package search
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"testing"
+	"time"
+)
+
+type memoryUsage struct {
+	mu     sync.Mutex
+	counts map[string]int
+}
+
+func (u *memoryUsage) Use(engine string, day string, limit int) (bool, error) {
+	u.mu.Lock()
+	defer u.mu.Unlock()
+	if limit > 0 && u.counts[engine+day] >= limit {
+		return false, nil
+	}
+	u.counts[engine+day]++
+	return true, nil
+}
+
+func (u *memoryUsage) Usage(engine string, day string) (int, error) {
+	u.mu.Lock()
+	defer u.mu.Unlock()
+	return u.counts[engine+day], nil
+}
+
+// pagedEngine serves endless pages of results through collectPages
+type pagedEngine struct {
+	caps    Capabilities
+	fetches int
+}
+
+func (p *pagedEngine) Name() string               { return "paged" }
+func (p *pagedEngine) Capabilities() Capabilities { return p.caps }
+
+func (p *pagedEngine) Search(ctx context.Context, sr SearchRequest) ([]SearchResult, error) {
+	return collectPages(ctx, sr, func(ctx context.Context, offset int) ([]SearchResult, error) {
+		p.fetches++
+		return page(offset, 2), nil
+	})
+}
+
+func TestLimitedEngineQuota(t *testing.T) {
+	usage := &memoryUsage{counts: make(map[string]int)}
+	engine := &pagedEngine{caps: Capabilities{DailyQuota: 3}}
+	limited := NewLimitedEngine(engine, usage, -1)
+
+	// Two pages fit in the quota...
+	results, err := limited.Search(context.Background(), SearchRequest{MaxResults: 4})
+	if err != nil || len(results) != 4 {
+		t.Fatalf("Expected 4 results, got %d (%v)", len(results), err)
+	}
+	if remaining, _ := limited.Remaining(); remaining != 1 {
+		t.Errorf("Expected 1 request left, got %d", remaining)
+	}
+
+	// ...the third runs out part way, which keeps what was fetched...
+	results, err = limited.Search(context.Background(), SearchRequest{MaxResults: 4})
+	if err != nil || len(results) != 2 {
+		t.Fatalf("Expected 2 results, got %d (%v)", len(results), err)
+	}
+
+	// ...and after that the engine is skipped without being called
+	_, err = limited.Search(context.Background(), SearchRequest{MaxResults: 4})
+	if !errors.Is(err, ErrQuotaExceeded) {
+		t.Errorf("Expected ErrQuotaExceeded, got %v", err)
+	}
+	if engine.fetches != 3 {
+		t.Errorf("Expected 3 fetches, got %d", engine.fetches)
+	}
+}
+
+func TestLimitedEngineQuotaOverride(t *testing.T) {
+	usage := &memoryUsage{counts: make(map[string]int)}
+	engine := &pagedEngine{caps: Capabilities{DailyQuota: 1}}
+
+	limited := WithLimits([]SearchEngine{engine}, usage, map[string]int{"paged": 0})[0].(*LimitedEngine)
+	if limited.Quota() != 0 {
+		t.Fatalf("Expected the quota to be overridden, got %d", limited.Quota())
+	}
+
+	results, err := limited.Search(context.Background(), SearchRequest{MaxResults: 6})
+	if err != nil || len(results) != 6 {
+		t.Errorf("Expected 6 results, got %d (%v)", len(results), err)
+	}
+}
+
+func TestLimitedEngineInterval(t *testing.T) {
+	usage := &memoryUsage{counts: make(map[string]int)}
+	engine := &pagedEngine{caps: Capabilities{MinInterval: 50 * time.Millisecond}}
+	limited := NewLimitedEngine(engine, usage, -1)
+
+	start := time.Now()
+	limited.Search(context.Background(), SearchRequest{MaxResults: 6})
+	if elapsed := time.Since(start); elapsed < 100*time.Millisecond {
+		t.Errorf("Expected 3 requests to take at least 100ms, took %s", elapsed)
+	}
+
+	// Waiting gives up when the context does
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+	limited.next = time.Now().Add(time.Second)
+	if _, err := limited.Search(ctx, SearchRequest{MaxResults: 2}); !errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf("Expected the deadline to be exceeded, got %v", err)
+	}
+}
+
+func TestRequestBudget(t *testing.T) {
+	usage := &memoryUsage{counts: make(map[string]int)}
+	limited := NewLimitedEngine(&pagedEngine{caps: Capabilities{MinInterval: time.Second}}, usage, -1)
+	gated := context.WithValue(context.Background(), requestGateKey{}, limited)
+
+	if budget := requestBudget(gated); budget != -1 {
+		t.Errorf("Expected no budget without a deadline, got %d", budget)
+	}
+
+	ctx, cancel := context.WithTimeout(gated, requestReserve+2500*time.Millisecond)
+	defer cancel()
+	if budget := requestBudget(ctx); budget != 3 {
+		t.Errorf("Expected 3 requests, got %d", budget)
+	}
+
+	// Waiting for the next one counts against it
+	limited.next = time.Now().Add(time.Second)
+	if budget := requestBudget(ctx); budget != 2 {
+		t.Errorf("Expected 2 requests, got %d", budget)
+	}
+	limited.next = time.Now().Add(time.Minute)
+	if budget := requestBudget(ctx); budget != 0 {
+		t.Errorf("Expected no requests, got %d", budget)
+	}
+
+	if budget := requestBudget(withoutRequestGate(ctx)); budget != -1 {
+		t.Errorf("Expected no budget without the gate, got %d", budget)
+	}
+}
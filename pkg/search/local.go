@@ -0,0 +1,280 @@
+package search
+
+import (
+	"context"
+	"fmt"
+	"io/fs"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/PuerkitoBio/goquery"
+
+	"ask-web/pkg/logger"
+)
+
+// Files bigger than this are probably data rather than documents
+const localMaxFileSize = 1024 * 1024
+
+// The kinds of file the local engine indexes
+var localExtensions = []string{
+	// Documents
+	".md", ".markdown", ".txt", ".rst", ".org", ".adoc", ".html", ".htm",
+	// Source
+	".go", ".py", ".js", ".ts", ".rs", ".c", ".h", ".cpp", ".java", ".rb",
+	".sh", ".lua", ".sql", ".yaml", ".yml", ".toml",
+}
+
+// Directories that are never worth indexing
+var localSkipDirs = map[string]bool{
+	"node_modules": true,
+	"vendor":       true,
+	"target":       true,
+}
+
+type localDoc struct {
+	Path     string    `json:"path"`
+	Title    string    `json:"title"`
+	Text     string    `json:"text"`
+	Modified time.Time `json:"modified"`
+}
+
+// LocalEngine searches files in local directories (notes, docs, source)
+// instead of the web, so that questions can be asked of them offline. The
+// files are indexed the first time the engine searches, and ranked with
+// BM25. Results have file:// URLs, which the downloader reads from disk, as
+// long as they're from this engine and in local.dirs (see
+// download.ResultPage).
+type LocalEngine struct {
+	dirs []string
+	// Indexes, if set, keeps the last index, for when the files can't be
+	// read again in time
+	Indexes IndexStore
+
+	once  sync.Once
+	index *storedIndex[localDoc]
+}
+
+func init() {
+	Register("local", func(cfg EngineConfig) (SearchEngine, error) {
+		var dirs []string
+		if cfg.Opts != nil {
+			dirs = cfg.Opts.LocalDirs
+		}
+		if len(dirs) == 0 {
+			return nil, fmt.Errorf("%w: local.dirs is not set", ErrNotConfigured)
+		}
+		engine := NewLocalEngine(dirs)
+		engine.Indexes = cfg.Indexes
+		return engine, nil
+	})
+}
+
+// NewLocalEngine searches the files in dirs and their subdirectories.
+func NewLocalEngine(dirs []string) *LocalEngine {
+	return &LocalEngine{dirs: dirs}
+}
+
+func (e *LocalEngine) Name() string { return "local" }
+
+func (e *LocalEngine) Capabilities() Capabilities {
+	// Nothing to be polite to
+	return Capabilities{}
+}
+
+func (e *LocalEngine) Search(ctx context.Context, sr SearchRequest) ([]SearchResult, error) {
+	if sr.Query == "" {
+		return nil, fmt.Errorf("search query cannot be empty")
+	}
+
+	index, err := e.fileIndex(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	var ranked []SearchResult
+	for _, match := range index.index.Search(sr.Query) {
+		doc := index.docs[match.Doc]
+		if sr.Since > 0 && doc.Modified.Before(sr.SinceDate()) {
+			continue
+		}
+		ranked = append(ranked, SearchResult{
+			Title:     doc.Title,
+			URL:       (&url.URL{Scheme: "file", Path: doc.Path}).String(),
+			Snippet:   localSnippet(doc.Text, sr.Query),
+			Published: doc.Modified,
+		})
+	}
+
+	// Pages only so that results go through the same filtering as any
+	// other engine's
+	count := pageSize(sr, 0)
+	return collectPages(ctx, sr, func(ctx context.Context, offset int) ([]SearchResult, error) {
+		if offset >= len(ranked) {
+			return nil, nil
+		}
+		return ranked[offset:min(offset+count, len(ranked))], nil
+	})
+}
+
+// fileIndex returns the index of the files (see storedIndex). Notes change
+// all the time, so it's read again for every run, and the stored one only
+// searched if that can't be waited for.
+func (e *LocalEngine) fileIndex(ctx context.Context) (*docIndex[localDoc], error) {
+	e.once.Do(func() {
+		e.index = &storedIndex[localDoc]{
+			engine: e.Name(),
+			what:   "local files",
+			store:  e.Indexes,
+			key: func(ctx context.Context) string {
+				var dirs []string
+				for _, dir := range e.dirs {
+					if abs, err := filepath.Abs(dir); err == nil {
+						dir = abs
+					}
+					dirs = append(dirs, dir)
+				}
+				return strings.Join(dirs, "\n")
+			},
+			build: e.build,
+			text: func(doc localDoc) string {
+				// The file name often says what it's about
+				return doc.Title + " " + filepath.Base(doc.Path) + "\n" + doc.Text
+			},
+		}
+	})
+
+	return e.index.get(ctx)
+}
+
+// build reads every file under the directories. Anything that can't be
+// read is skipped, but running out of time is an error rather than a
+// partial index.
+func (e *LocalEngine) build(ctx context.Context) ([]localDoc, error) {
+	log := logger.GetLogger()
+	var docs []localDoc
+
+	for _, dir := range e.dirs {
+		dir, err := filepath.Abs(dir)
+		if err != nil {
+			log.Warn(fmt.Sprintf("Skipping %s: %s", dir, err))
+			continue
+		}
+
+		err = filepath.WalkDir(dir, func(path string, d fs.DirEntry, err error) error {
+			if ctx.Err() != nil {
+				return ctx.Err()
+			}
+			if err != nil {
+				log.Warn(fmt.Sprintf("Skipping %s: %s", path, err))
+				return nil
+			}
+			if d.IsDir() {
+				if path != dir && (strings.HasPrefix(d.Name(), ".") || localSkipDirs[d.Name()]) {
+					return filepath.SkipDir
+				}
+				return nil
+			}
+			if strings.HasPrefix(d.Name(), ".") || !localIndexable(path) {
+				return nil
+			}
+
+			doc, err := readLocalDoc(path, d)
+			if err != nil {
+				log.Warn(fmt.Sprintf("Skipping %s: %s", path, err))
+				return nil
+			}
+			docs = append(docs, doc)
+			return nil
+		})
+		if ctx.Err() != nil {
+			return nil, ctx.Err()
+		}
+		if err != nil {
+			log.Warn(fmt.Sprintf("Error indexing %s: %s", dir, err))
+		}
+	}
+
+	log.Info(fmt.Sprintf("Indexed %d local files", len(docs)))
+
+	return docs, nil
+}
+
+func localIndexable(path string) bool {
+	ext := strings.ToLower(filepath.Ext(path))
+	for _, indexable := range localExtensions {
+		if ext == indexable {
+			return true
+		}
+	}
+	return false
+}
+
+func readLocalDoc(path string, d fs.DirEntry) (localDoc, error) {
+	info, err := d.Info()
+	if err != nil {
+		return localDoc{}, err
+	}
+	if info.Size() > localMaxFileSize {
+		return localDoc{}, fmt.Errorf("bigger than %d bytes", localMaxFileSize)
+	}
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return localDoc{}, err
+	}
+
+	doc := localDoc{
+		Path:     path,
+		Title:    filepath.Base(path),
+		Text:     string(data),
+		Modified: info.ModTime(),
+	}
+
+	switch strings.ToLower(filepath.Ext(path)) {
+	case ".html", ".htm":
+		if page, err := goquery.NewDocumentFromReader(strings.NewReader(doc.Text)); err == nil {
+			if title := collapseSpace(page.Find("title").First().Text()); title != "" {
+				doc.Title = title
+			}
+			doc.Text = page.Find("body").Text()
+		}
+	case ".md", ".markdown":
+		for _, line := range strings.Split(doc.Text, "\n") {
+			if heading, ok := strings.CutPrefix(line, "# "); ok {
+				doc.Title = strings.TrimSpace(heading)
+				break
+			}
+		}
+	}
+
+	return doc, nil
+}
+
+// localSnippet picks the line that matches most of the query's words.
+func localSnippet(text string, query string) string {
+	terms := uniqueTokens(query)
+
+	best, bestMatches := "", 0
+	for _, line := range strings.Split(text, "\n") {
+		lineTerms := make(map[string]bool)
+		for _, token := range tokenize(line) {
+			lineTerms[token] = true
+		}
+
+		matches := 0
+		for _, term := range terms {
+			if lineTerms[term] {
+				matches++
+			}
+		}
+		if matches > bestMatches {
+			best, bestMatches = line, matches
+		}
+	}
+
+	return truncateText(best, maxSnippetLength)
+}
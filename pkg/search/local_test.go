@@ -0,0 +1,130 @@
+package search
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func writeFiles(t *testing.T, dir string, files map[string]string) {
+	t.Helper()
+
+	for name, content := range files {
+		path := filepath.Join(dir, name)
+		if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+}
+
+func TestLocalSearch(t *testing.T) {
+	dir := t.TempDir()
+	writeFiles(t, dir, map[string]string{
+		"nginx.md":                 "# Reverse proxy setup\n\nUse proxy_pass to send requests on to the app.\nRemember proxy_set_header Host.",
+		"backups.txt":              "pg_dump every night, kept for a week",
+		"site/index.html":          "<html><head><title>Proxy docs</title></head><body><p>The reverse proxy config lives in /etc/nginx.</p></body></html>",
+		"src/proxy.go":             "package proxy\n\n// NewReverseProxy wraps httputil for the app\nfunc NewReverseProxy() {}",
+		"photo.jpg":                "reverse proxy",
+		".git/notes.md":            "reverse proxy",
+		"node_modules/x/README.md": "reverse proxy",
+	})
+
+	engine := NewLocalEngine([]string{dir})
+	results, err := engine.Search(context.Background(), SearchRequest{Query: "reverse proxy", MaxResults: 10})
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	titles := make(map[string]SearchResult)
+	for _, result := range results {
+		titles[result.Title] = result
+	}
+	if len(results) != 3 {
+		t.Fatalf("Expected 3 results, got %v", urls(results))
+	}
+
+	md, ok := titles["Reverse proxy setup"]
+	if !ok {
+		t.Fatalf("Expected the markdown heading as the title, got %v", results)
+	}
+	if md.URL != "file://"+filepath.ToSlash(filepath.Join(dir, "nginx.md")) {
+		t.Errorf("Unexpected URL: %s", md.URL)
+	}
+	if md.Content != "" {
+		t.Error("Expected the content to be left to the downloader")
+	}
+	if md.Published.IsZero() {
+		t.Error("Expected the modification time")
+	}
+
+	html, ok := titles["Proxy docs"]
+	if !ok {
+		t.Fatalf("Expected the HTML title, got %v", results)
+	}
+	if html.Snippet != "The reverse proxy config lives in /etc/nginx." {
+		t.Errorf("Unexpected snippet: %q", html.Snippet)
+	}
+	if _, ok := titles["proxy.go"]; !ok {
+		t.Errorf("Expected the source file, got %v", results)
+	}
+
+	// Files changed before --since are left out
+	old := time.Now().Add(-30 * 24 * time.Hour)
+	if err := os.Chtimes(filepath.Join(dir, "nginx.md"), old, old); err != nil {
+		t.Fatal(err)
+	}
+	engine = NewLocalEngine([]string{dir})
+	results, err = engine.Search(context.Background(), SearchRequest{Query: "reverse proxy", MaxResults: 10, Since: 7 * 24 * time.Hour})
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	for _, result := range results {
+		if strings.HasSuffix(result.URL, "nginx.md") {
+			t.Errorf("Expected nginx.md to be too old, got %v", urls(results))
+		}
+	}
+}
+
+func TestLocalStoredIndex(t *testing.T) {
+	dir := t.TempDir()
+	writeFiles(t, dir, map[string]string{"nginx.md": "# Reverse proxy setup"})
+
+	store := newMemoryIndexes()
+	engine := NewLocalEngine([]string{dir})
+	engine.Indexes = store
+	if _, err := engine.Search(context.Background(), SearchRequest{Query: "reverse proxy", MaxResults: 10}); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if store.data["local "+dir] == nil {
+		t.Fatalf("Expected the index to be stored under %q, got %v", dir, store.data)
+	}
+
+	// Notes change, so the next run reads them again rather than trusting
+	// the stored index
+	writeFiles(t, dir, map[string]string{"haproxy.md": "# Another reverse proxy"})
+	engine = NewLocalEngine([]string{dir})
+	engine.Indexes = store
+	results, err := engine.Search(context.Background(), SearchRequest{Query: "reverse proxy", MaxResults: 10})
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if len(results) != 2 {
+		t.Errorf("Expected both files, got %v", urls(results))
+	}
+}
+
+func TestLocalSnippet(t *testing.T) {
+	text := "intro\nthe proxy\nreverse proxy with nginx\nreverse"
+	if got := localSnippet(text, "nginx reverse proxy"); got != "reverse proxy with nginx" {
+		t.Errorf("Expected the best line, got %q", got)
+	}
+	if got := localSnippet(text, "postgres"); got != "" {
+		t.Errorf("Expected no snippet, got %q", got)
+	}
+}
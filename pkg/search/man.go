@@ -0,0 +1,126 @@
+package search
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os/exec"
+	"regexp"
+	"strings"
+
+	"ask-web/pkg/logger"
+)
+
+// Each result is another run of man, so there's no point in lots
+const manPageSize = 10
+
+// A line of apropos output, eg "ls (1) - list directory contents" from
+// man-db or "ls(1) - list directory contents" from mandoc. Pages with
+// several names list them all: "gzip, gunzip, zcat (1) - ...".
+var aproposLine = regexp.MustCompile(`^(.+?)\s*\(([^)]+)\)\s+-+\s+(.*)$`)
+
+type manEntry struct {
+	name        string
+	section     string
+	description string
+}
+
+// ManEngine searches the locally installed man pages with apropos, and
+// returns the rendered pages as the results' Content. It never touches the
+// network.
+type ManEngine struct{}
+
+func init() {
+	Register("man", func(cfg EngineConfig) (SearchEngine, error) {
+		for _, program := range []string{"apropos", "man"} {
+			if _, err := exec.LookPath(program); err != nil {
+				return nil, fmt.Errorf("%w: %s is not installed", ErrNotConfigured, program)
+			}
+		}
+		return &ManEngine{}, nil
+	})
+}
+
+func (e *ManEngine) Name() string { return "man" }
+
+func (e *ManEngine) Capabilities() Capabilities {
+	return Capabilities{MaxResultsPerRequest: manPageSize}
+}
+
+func (e *ManEngine) Search(ctx context.Context, sr SearchRequest) ([]SearchResult, error) {
+	words := uniqueTokens(sr.Query)
+	if len(words) == 0 {
+		return nil, fmt.Errorf("search query cannot be empty")
+	}
+
+	// apropos matches any of the words, in page names or descriptions, so
+	// the ranking is left to BM25
+	out, err := runCommand(ctx, "", "apropos", words...)
+	if err != nil && len(out) == 0 {
+		// It exits non-zero when nothing matches
+		var exitErr *exec.ExitError
+		if errors.As(err, &exitErr) {
+			return nil, nil
+		}
+		return nil, &EngineError{Engine: e.Name(), Message: "apropos failed", Err: err}
+	}
+
+	entries := parseApropos(string(out))
+	index := NewBM25Index()
+	for _, entry := range entries {
+		// A match on the name counts for more
+		index.Add(entry.name + " " + entry.name + " " + entry.description)
+	}
+	var ranked []manEntry
+	for _, match := range index.Search(sr.Query) {
+		ranked = append(ranked, entries[match.Doc])
+	}
+
+	count := pageSize(sr, manPageSize)
+	return collectPages(ctx, sr, func(ctx context.Context, offset int) ([]SearchResult, error) {
+		if offset >= len(ranked) {
+			return nil, nil
+		}
+
+		var results []SearchResult
+		for _, entry := range ranked[offset:min(offset+count, len(ranked))] {
+			page, err := runCommand(ctx, "", "man", entry.section, entry.name)
+			if err != nil {
+				logger.GetLogger().Warn(fmt.Sprintf("Error running man %s %s: %s", entry.section, entry.name, err))
+				continue
+			}
+
+			ref := fmt.Sprintf("%s(%s)", entry.name, entry.section)
+			results = append(results, SearchResult{
+				Title:   ref,
+				URL:     "man:" + ref,
+				Snippet: entry.description,
+				Content: plainOutput(page),
+			})
+		}
+		return results, nil
+	})
+}
+
+// parseApropos gets the pages out of apropos's output, once each.
+func parseApropos(out string) []manEntry {
+	var entries []manEntry
+	seen := make(map[string]bool)
+	for _, line := range strings.Split(out, "\n") {
+		m := aproposLine.FindStringSubmatch(strings.TrimSpace(line))
+		if m == nil {
+			continue
+		}
+
+		// The first name is the page's
+		name := strings.TrimSpace(strings.Split(m[1], ",")[0])
+		entry := manEntry{name: name, section: m[2], description: strings.TrimSpace(m[3])}
+		key := entry.name + "(" + entry.section + ")"
+		if name == "" || seen[key] {
+			continue
+		}
+		seen[key] = true
+		entries = append(entries, entry)
+	}
+	return entries
+}
@@ -0,0 +1,110 @@
+package search
+
+import (
+	"context"
+	"os/exec"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+// fakeCommands stands in for runCommand, answering with the output for the
+// program and its arguments, and recording what was run
+func fakeCommands(t *testing.T, outputs map[string]string, ran *[]string) {
+	t.Helper()
+
+	orig := runCommand
+	t.Cleanup(func() { runCommand = orig })
+	runCommand = func(ctx context.Context, dir string, name string, args ...string) ([]byte, error) {
+		command := strings.Join(append([]string{name}, args...), " ")
+		if dir != "" {
+			command = dir + "$ " + command
+		}
+		if ran != nil {
+			*ran = append(*ran, command)
+		}
+		out, ok := outputs[command]
+		if !ok {
+			return nil, &exec.ExitError{}
+		}
+		return []byte(out), nil
+	}
+}
+
+func TestManSearch(t *testing.T) {
+	var ran []string
+	fakeCommands(t, map[string]string{
+		"apropos list hidden files": "ls (1)               - list directory contents\n" +
+			"dir (1)              - list directory contents\n" +
+			"ls (1)               - list directory contents\n" +
+			"gzip, gunzip, zcat (1) - compress or expand files\n" +
+			"hier(7) - description of the file system hierarchy\n" +
+			"not an apropos line\n",
+		"man 1 ls": "LS(1)                     User Commands                    LS(1)\n\n" +
+			"N\bNA\bAM\bME\bE\n       ls - list directory contents\n\n\n\n" +
+			"       -\b-a\ba, \x1b[1m--all\x1b[0m\n              do not ignore entries starting with .\n",
+		"man 1 dir":  "DIR(1)\n",
+		"man 7 hier": "HIER(7)\n",
+	}, &ran)
+
+	engine := &ManEngine{}
+	results, err := engine.Search(context.Background(), SearchRequest{Query: "list hidden files", MaxResults: 2, MaxPages: 1})
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	// gzip's page is left out, because man can't be run for it
+	var titles []string
+	for _, result := range results {
+		titles = append(titles, result.Title)
+	}
+	if len(titles) < 2 || titles[0] != "ls(1)" && titles[0] != "dir(1)" {
+		t.Fatalf("Expected ls and dir first, got %v", titles)
+	}
+
+	var ls SearchResult
+	for _, result := range results {
+		if result.Title == "ls(1)" {
+			ls = result
+		}
+	}
+	if ls.URL != "man:ls(1)" || ls.Snippet != "list directory contents" {
+		t.Errorf("Unexpected result: %+v", ls)
+	}
+	expected := "LS(1)                     User Commands                    LS(1)\n\n" +
+		"NAME\n       ls - list directory contents\n\n" +
+		"       -a, --all\n              do not ignore entries starting with ."
+	if ls.Content != expected {
+		t.Errorf("Expected the plain page %q, got %q", expected, ls.Content)
+	}
+	if ran[0] != "apropos list hidden files" {
+		t.Errorf("Unexpected apropos command: %q", ran[0])
+	}
+}
+
+func TestManSearchNoMatches(t *testing.T) {
+	fakeCommands(t, nil, nil)
+
+	results, err := (&ManEngine{}).Search(context.Background(), SearchRequest{Query: "xyzzy", MaxResults: 5})
+	if err != nil || len(results) != 0 {
+		t.Errorf("Expected no results and no error, got %v, %v", results, err)
+	}
+}
+
+func TestParseApropos(t *testing.T) {
+	out := "printf (1)           - format and print data\n" +
+		"printf (3)           - formatted output conversion\n" +
+		"printf(1) - format and print data\n" +
+		"git-log (1)          - Show commit logs\n" +
+		"openssl-req (1ssl)   - PKCS#10 certificate request and certificate generating command\n"
+
+	expected := []manEntry{
+		{"printf", "1", "format and print data"},
+		{"printf", "3", "formatted output conversion"},
+		{"git-log", "1", "Show commit logs"},
+		{"openssl-req", "1ssl", "PKCS#10 certificate request and certificate generating command"},
+	}
+	if got := parseApropos(out); !reflect.DeepEqual(got, expected) {
+		t.Errorf("Expected %v, got %v", expected, got)
+	}
+}
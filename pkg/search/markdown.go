@@ -0,0 +1,111 @@
+package search
+
+import (
+	"strings"
+
+	"github.com/PuerkitoBio/goquery"
+)
+
+// htmlToMarkdown turns the HTML that APIs hand back for posts (eg answers on
+// Stack Overflow) into markdown-ish text for the summarizer. The point is to
+// keep code blocks intact and the structure readable; it isn't a general
+// converter and drops links, emphasis and anything else it doesn't know.
+func htmlToMarkdown(fragment string) string {
+	doc, err := goquery.NewDocumentFromReader(strings.NewReader(fragment))
+	if err != nil {
+		return fragment
+	}
+
+	var b strings.Builder
+	writeMarkdown(&b, doc.Find("body"))
+
+	return tidyMarkdown(b.String())
+}
+
+func writeMarkdown(b *strings.Builder, sel *goquery.Selection) {
+	sel.Contents().Each(func(_ int, node *goquery.Selection) {
+		switch name := goquery.NodeName(node); name {
+		case "#text":
+			b.WriteString(collapseInline(node.Text()))
+		case "pre":
+			b.WriteString("\n\n```\n" + strings.Trim(node.Text(), "\n") + "\n```\n\n")
+		case "code":
+			b.WriteString("`" + node.Text() + "`")
+		case "br":
+			b.WriteString("\n")
+		case "hr":
+			b.WriteString("\n\n---\n\n")
+		case "h1", "h2", "h3", "h4", "h5", "h6":
+			b.WriteString("\n\n" + strings.Repeat("#", int(name[1]-'0')) + " ")
+			writeMarkdown(b, node)
+			b.WriteString("\n\n")
+		case "li":
+			// Items can have paragraphs of their own, which would leave
+			// the bullet on a line by itself
+			var inner strings.Builder
+			writeMarkdown(&inner, node)
+			b.WriteString("\n- " + tidyMarkdown(inner.String()))
+		case "blockquote":
+			var inner strings.Builder
+			writeMarkdown(&inner, node)
+			b.WriteString("\n\n")
+			for _, line := range strings.Split(tidyMarkdown(inner.String()), "\n") {
+				b.WriteString(strings.TrimRight("> "+line, " ") + "\n")
+			}
+			b.WriteString("\n")
+		case "p", "div", "ul", "ol", "table", "tr":
+			b.WriteString("\n\n")
+			writeMarkdown(b, node)
+			b.WriteString("\n\n")
+		default:
+			writeMarkdown(b, node)
+		}
+	})
+}
+
+// Whitespace outside <pre> is only there to separate words
+func collapseInline(text string) string {
+	collapsed := strings.Join(strings.Fields(text), " ")
+	if collapsed == "" {
+		if text != "" {
+			return " "
+		}
+		return ""
+	}
+
+	if strings.TrimLeft(text, " \t\n\r") != text {
+		collapsed = " " + collapsed
+	}
+	if strings.TrimRight(text, " \t\n\r") != text {
+		collapsed += " "
+	}
+	return collapsed
+}
+
+// tidyMarkdown trims the lines and squeezes runs of blank lines down to one,
+// leaving code blocks alone.
+func tidyMarkdown(text string) string {
+	var lines []string
+	inCode := false
+	blank := true
+	for _, line := range strings.Split(text, "\n") {
+		if !inCode {
+			line = strings.TrimSpace(line)
+		}
+		if line == "```" {
+			inCode = !inCode
+		}
+
+		if line == "" && !inCode {
+			if blank {
+				continue
+			}
+			blank = true
+		} else {
+			blank = false
+		}
+		lines = append(lines, line)
+	}
+
+	return strings.TrimSpace(strings.Join(lines, "\n"))
+}
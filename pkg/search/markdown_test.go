@@ -0,0 +1,52 @@
+package search
+
+import (
+	"testing"
+)
+
+func TestHTMLToMarkdown(t *testing.T) {
+	testCases := []struct {
+		name     string
+		input    string
+		expected string
+	}{
+		{
+			name:     "Paragraphs",
+			input:    "<p>First\n paragraph.</p>\n\n<p>Second <b>one</b>.</p>",
+			expected: "First paragraph.\n\nSecond one.",
+		},
+		{
+			name:     "Code block",
+			input:    "<p>Try:</p>\n<pre><code>if a &gt; b {\n    return a\n}\n</code></pre>\n<p>Done.</p>",
+			expected: "Try:\n\n```\nif a > b {\n    return a\n}\n```\n\nDone.",
+		},
+		{
+			name:     "Inline code",
+			input:    "<p>Use <code>cmp.Ordered</code> here.</p>",
+			expected: "Use `cmp.Ordered` here.",
+		},
+		{
+			name:     "List",
+			input:    "<ul>\n<li>One</li>\n<li>Two <a href=\"https://go.dev\">link</a></li>\n</ul>",
+			expected: "- One\n- Two link",
+		},
+		{
+			name:     "Heading and quote",
+			input:    "<h2>Update</h2>\n<blockquote><p>Quoted\ntext.</p><p>More.</p></blockquote>",
+			expected: "## Update\n\n> Quoted text.\n>\n> More.",
+		},
+		{
+			name:     "Line break",
+			input:    "<p>One<br>Two</p>",
+			expected: "One\nTwo",
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			if got := htmlToMarkdown(tc.input); got != tc.expected {
+				t.Errorf("htmlToMarkdown(%q) = %q; want %q", tc.input, got, tc.expected)
+			}
+		})
+	}
+}
@@ -0,0 +1,264 @@
+package search
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+
+	"github.com/PuerkitoBio/goquery"
+)
+
+const WikipediaAPIURL = "https://en.wikipedia.org/w/api.php"
+
+// Search results per request. Every result that passes the filter costs
+// another request for its text, so there's no point asking for lots.
+const mediaWikiPageSize = 20
+
+// Wikimedia asks API clients to say who they are
+const mediaWikiUserAgent = "ask-web (https://github.com/duluk/ask-web)"
+
+type mediaWikiError struct {
+	Code string `json:"code"`
+	Info string `json:"info"`
+}
+
+type mediaWikiSearchResponse struct {
+	Error *mediaWikiError `json:"error"`
+	Query struct {
+		Search []struct {
+			PageID  int    `json:"pageid"`
+			Title   string `json:"title"`
+			Snippet string `json:"snippet"`
+		} `json:"search"`
+	} `json:"query"`
+}
+
+type mediaWikiPagesResponse struct {
+	Error *mediaWikiError `json:"error"`
+	Query struct {
+		Pages []struct {
+			PageID  int    `json:"pageid"`
+			Title   string `json:"title"`
+			Missing bool   `json:"missing"`
+			Extract string `json:"extract"`
+			FullURL string `json:"fullurl"`
+		} `json:"pages"`
+	} `json:"query"`
+}
+
+// MediaWikiEngine searches Wikipedia, or any other MediaWiki install, through
+// its API. Rather than leaving the (huge, noisy) pages to be scraped, it gets
+// each article's plain text from the TextExtracts API and returns it as the
+// result's Content.
+type MediaWikiEngine struct {
+	apiURL string
+
+	// Client defaults to one with the usual timeout
+	Client *http.Client
+}
+
+func init() {
+	Register("wikipedia", func(cfg EngineConfig) (SearchEngine, error) {
+		apiURL := ""
+		if cfg.Opts != nil {
+			apiURL = cfg.Opts.WikiURL
+		}
+		return NewMediaWikiEngine(apiURL), nil
+	})
+}
+
+// NewMediaWikiEngine takes the wiki's api.php URL, eg
+// https://wiki.example.com/w/api.php; empty means English Wikipedia.
+func NewMediaWikiEngine(apiURL string) *MediaWikiEngine {
+	if apiURL == "" {
+		apiURL = WikipediaAPIURL
+	}
+	return &MediaWikiEngine{apiURL: apiURL}
+}
+
+func (e *MediaWikiEngine) Name() string { return "wikipedia" }
+
+func (e *MediaWikiEngine) Capabilities() Capabilities {
+	return Capabilities{
+		MaxResultsPerRequest: mediaWikiPageSize,
+	}
+}
+
+func (e *MediaWikiEngine) Search(ctx context.Context, sr SearchRequest) ([]SearchResult, error) {
+	if sr.Query == "" {
+		return nil, fmt.Errorf("search query cannot be empty")
+	}
+
+	client := httpClient(e.Client)
+
+	pageIDs := make(map[string]int)
+	results, err := collectPages(ctx, sr, func(ctx context.Context, offset int) ([]SearchResult, error) {
+		return e.fetchPage(ctx, client, sr, offset, pageIDs)
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	// Only the articles that got through the filter are worth their text
+	err = fetchDetails(ctx, results, 0, func(ctx context.Context, result *SearchResult) error {
+		return e.fetchExtract(ctx, client, pageIDs[result.URL], result)
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return results, nil
+}
+
+// fetchPage gets a page of search results and their URLs, which the search
+// API leaves out. Until the text is fetched, the snippet stands in for it
+// (see fetchDetails). pageIDs maps the URLs to page IDs for fetchExtract.
+func (e *MediaWikiEngine) fetchPage(ctx context.Context, client *http.Client, sr SearchRequest, offset int, pageIDs map[string]int) ([]SearchResult, error) {
+	// There's nothing for sites, regions or time ranges: it's one wiki, in
+	// one language, and articles are always current
+	params := url.Values{}
+	params.Set("action", "query")
+	params.Set("list", "search")
+	params.Set("srsearch", sr.Query)
+	params.Set("srlimit", fmt.Sprintf("%d", pageSize(sr, mediaWikiPageSize)))
+	params.Set("srprop", "snippet")
+	if offset > 0 {
+		params.Set("sroffset", fmt.Sprintf("%d", offset))
+	}
+
+	var searchResp mediaWikiSearchResponse
+	if err := e.get(ctx, client, params, &searchResp); err != nil {
+		return nil, err
+	}
+
+	hits := searchResp.Query.Search
+	if len(hits) == 0 {
+		return nil, nil
+	}
+
+	// One request gets the URLs of the whole page of results
+	if err := acquireRequest(ctx); err != nil {
+		return nil, err
+	}
+	ids := make([]string, 0, len(hits))
+	for _, hit := range hits {
+		ids = append(ids, fmt.Sprintf("%d", hit.PageID))
+	}
+	params = url.Values{}
+	params.Set("action", "query")
+	params.Set("pageids", strings.Join(ids, "|"))
+	params.Set("prop", "info")
+	params.Set("inprop", "url")
+
+	var infoResp mediaWikiPagesResponse
+	if err := e.get(ctx, client, params, &infoResp); err != nil {
+		return nil, err
+	}
+	fullURLs := make(map[int]string)
+	for _, page := range infoResp.Query.Pages {
+		if !page.Missing {
+			fullURLs[page.PageID] = page.FullURL
+		}
+	}
+
+	results := make([]SearchResult, 0, len(hits))
+	for _, hit := range hits {
+		// Deleted since it was indexed
+		fullURL := fullURLs[hit.PageID]
+		if fullURL == "" {
+			continue
+		}
+
+		pageIDs[fullURL] = hit.PageID
+		snippet := htmlText(hit.Snippet)
+		results = append(results, SearchResult{
+			Title:   hit.Title,
+			URL:     fullURL,
+			Snippet: snippet,
+			Content: snippet,
+		})
+	}
+
+	return results, nil
+}
+
+// fetchExtract replaces the Content of result with the page's plain text
+// extract. If that fails, the snippet is left standing in for it rather
+// than having the whole article downloaded.
+func (e *MediaWikiEngine) fetchExtract(ctx context.Context, client *http.Client, pageID int, result *SearchResult) error {
+	// The API only gives out one whole-page extract per request
+	if err := acquireRequest(ctx); err != nil {
+		return err
+	}
+
+	params := url.Values{}
+	params.Set("action", "query")
+	params.Set("pageids", fmt.Sprintf("%d", pageID))
+	params.Set("prop", "extracts|info")
+	params.Set("explaintext", "1")
+	params.Set("exsectionformat", "plain")
+	params.Set("inprop", "url")
+
+	var pagesResp mediaWikiPagesResponse
+	if err := e.get(ctx, client, params, &pagesResp); err != nil {
+		return err
+	}
+
+	for _, page := range pagesResp.Query.Pages {
+		extract := strings.TrimSpace(page.Extract)
+		if page.Missing || page.PageID != pageID || extract == "" {
+			continue
+		}
+		result.Content = extract
+		return nil
+	}
+
+	return &EngineError{Engine: e.Name(), Message: fmt.Sprintf("page %d not found", pageID)}
+}
+
+// get makes an API request and decodes the response into v, one of the
+// response types above.
+func (e *MediaWikiEngine) get(ctx context.Context, client *http.Client, params url.Values, v interface{ apiError() *mediaWikiError }) error {
+	params.Set("format", "json")
+	params.Set("formatversion", "2")
+
+	resp, err := doRequest(ctx, client, e.Name(), func(ctx context.Context) (*http.Request, error) {
+		req, err := http.NewRequestWithContext(ctx, "GET", e.apiURL+"?"+params.Encode(), nil)
+		if err != nil {
+			return nil, err
+		}
+		req.Header.Set("User-Agent", mediaWikiUserAgent)
+		return req, nil
+	})
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if err := json.NewDecoder(resp.Body).Decode(v); err != nil {
+		return &EngineError{Engine: e.Name(), Message: "error decoding response", Err: err}
+	}
+
+	// API errors come back as a 200
+	if apiErr := v.apiError(); apiErr != nil {
+		return &EngineError{Engine: e.Name(), Message: apiErr.Code + ": " + apiErr.Info}
+	}
+
+	return nil
+}
+
+func (r *mediaWikiSearchResponse) apiError() *mediaWikiError { return r.Error }
+func (r *mediaWikiPagesResponse) apiError() *mediaWikiError  { return r.Error }
+
+// htmlText returns the text of an HTML fragment, eg a search snippet with
+// the matches highlighted.
+func htmlText(fragment string) string {
+	doc, err := goquery.NewDocumentFromReader(strings.NewReader(fragment))
+	if err != nil {
+		return fragment
+	}
+	return strings.Join(strings.Fields(doc.Text()), " ")
+}
@@ -0,0 +1,183 @@
+package search
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+// mediaWikiServer answers searches with the search fixture, URLs with the
+// info fixture and extracts with the fixture for the page ID
+func mediaWikiServer(t *testing.T, requests *[]*http.Request) *httptest.Server {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		*requests = append(*requests, r)
+		w.Header().Set("Content-Type", "application/json")
+		q := r.URL.Query()
+		switch {
+		case q.Get("list") == "search" && q.Get("sroffset") == "":
+			w.Write(fixture(t, "mediawiki_search.json"))
+		case q.Get("list") == "search":
+			w.Write([]byte(`{"batchcomplete": true, "query": {"search": []}}`))
+		case q.Get("prop") == "info":
+			w.Write(fixture(t, "mediawiki_info.json"))
+		case q.Get("pageids") != "":
+			w.Write(fixture(t, "mediawiki_extract_"+q.Get("pageids")+".json"))
+		default:
+			w.Write(fixture(t, "mediawiki_error.json"))
+		}
+	}))
+	t.Cleanup(server.Close)
+
+	return server
+}
+
+func TestMediaWikiSearch(t *testing.T) {
+	var requests []*http.Request
+	server := mediaWikiServer(t, &requests)
+
+	engine := NewMediaWikiEngine(server.URL + "/w/api.php")
+	engine.Client = server.Client()
+	results, err := engine.Search(context.Background(), SearchRequest{Query: "golang", MaxResults: 5})
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	expected := []string{"https://en.wikipedia.org/wiki/Go_(programming_language)", "https://en.wikipedia.org/wiki/Robert_Griesemer"}
+	if !reflect.DeepEqual(urls(results), expected) {
+		t.Fatalf("Expected %v, got %v", expected, urls(results))
+	}
+
+	if results[0].Title != "Go (programming language)" {
+		t.Errorf("Unexpected title: %q", results[0].Title)
+	}
+	if results[0].Snippet != "Go is a high-level general purpose programming language that is statically typed and compiled." {
+		t.Errorf("Expected the snippet without markup, got %q", results[0].Snippet)
+	}
+	if !strings.HasPrefix(results[0].Content, "Go is a high-level") || !strings.Contains(results[0].Content, "designed at Google in 2007") {
+		t.Errorf("Expected the article text as content, got %q", results[0].Content)
+	}
+
+	// A search and its URLs, a search past the end, then two extracts
+	if len(requests) != 5 {
+		t.Fatalf("Expected 5 requests, got %d", len(requests))
+	}
+	want := map[string]string{
+		"action":        "query",
+		"list":          "search",
+		"srsearch":      "golang",
+		"srlimit":       "10",
+		"format":        "json",
+		"formatversion": "2",
+	}
+	for k, v := range want {
+		if got := requests[0].URL.Query().Get(k); got != v {
+			t.Errorf("Expected %s=%q, got %q", k, v, got)
+		}
+	}
+	if requests[0].URL.Path != "/w/api.php" {
+		t.Errorf("Expected the configured API path, got %q", requests[0].URL.Path)
+	}
+	if ua := requests[0].Header.Get("User-Agent"); ua != mediaWikiUserAgent {
+		t.Errorf("Expected the ask-web user agent, got %q", ua)
+	}
+	if q := requests[1].URL.Query(); q.Get("pageids") != "25039021|52035489" || q.Get("inprop") != "url" {
+		t.Errorf("Unexpected info request: %v", q)
+	}
+	if q := requests[3].URL.Query(); q.Get("pageids") != "25039021" || q.Get("explaintext") != "1" {
+		t.Errorf("Unexpected extract request: %v", q)
+	}
+	if offset := requests[2].URL.Query().Get("sroffset"); offset != "2" {
+		t.Errorf("Expected sroffset=2 for the second page, got %q", offset)
+	}
+}
+
+func TestMediaWikiFilterFirst(t *testing.T) {
+	var requests []*http.Request
+	server := mediaWikiServer(t, &requests)
+
+	engine := NewMediaWikiEngine(server.URL + "/w/api.php")
+	engine.Client = server.Client()
+	results, err := engine.Search(context.Background(), SearchRequest{
+		Query:      "golang",
+		MaxResults: 1,
+		Filter: func(r SearchResult) bool {
+			// The stand-in text is there for scraped: rules
+			return r.Content != "" && !strings.Contains(r.URL, "Go_(programming_language)")
+		},
+	})
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if len(results) != 1 || !strings.HasPrefix(results[0].Content, "Robert Griesemer (born") {
+		t.Fatalf("Expected the one article with its text, got %v", results)
+	}
+
+	// Only the article that was kept has its text fetched
+	var extracts []string
+	for _, r := range requests {
+		if r.URL.Query().Get("explaintext") != "" {
+			extracts = append(extracts, r.URL.Query().Get("pageids"))
+		}
+	}
+	if !reflect.DeepEqual(extracts, []string{"52035489"}) {
+		t.Errorf("Expected only the kept article's extract, got %v", extracts)
+	}
+
+	// Running out of quota for the extracts is an error, not a warning
+	usage := &memoryUsage{counts: make(map[string]int)}
+	limited := NewLimitedEngine(engine, usage, 3)
+	_, err = limited.Search(context.Background(), SearchRequest{Query: "golang", MaxResults: 2, MaxPages: 1})
+	if !errors.Is(err, ErrQuotaExceeded) {
+		t.Errorf("Expected ErrQuotaExceeded, got %v", err)
+	}
+}
+
+func TestMediaWikiExtractMissing(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		q := r.URL.Query()
+		switch {
+		case q.Get("list") == "search":
+			w.Write(fixture(t, "mediawiki_search.json"))
+		case q.Get("prop") == "info":
+			w.Write(fixture(t, "mediawiki_info.json"))
+		default:
+			w.Write([]byte(`{"batchcomplete": true, "query": {"pages": []}}`))
+		}
+	}))
+	t.Cleanup(server.Close)
+
+	engine := NewMediaWikiEngine(server.URL + "/w/api.php")
+	engine.Client = server.Client()
+	results, err := engine.Search(context.Background(), SearchRequest{Query: "golang", MaxResults: 1, MaxPages: 1})
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	// The snippet is better than downloading the whole article
+	if len(results) != 1 || results[0].Content == "" || results[0].Content != results[0].Snippet {
+		t.Errorf("Expected the snippet as content, got %v", results)
+	}
+}
+
+func TestMediaWikiSearchErrors(t *testing.T) {
+	server := fixtureServer(t, map[string]string{"/api.php": "mediawiki_error.json"}, nil)
+
+	engine := NewMediaWikiEngine(server.URL + "/api.php")
+	_, err := engine.Search(context.Background(), SearchRequest{Query: "golang", MaxResults: 3})
+	if err == nil || !strings.Contains(err.Error(), "srsearch-text-disabled") {
+		t.Errorf("Expected the API's error, got %v", err)
+	}
+
+	if _, err := engine.Search(context.Background(), SearchRequest{MaxResults: 3}); err == nil {
+		t.Error("Expected an error for an empty query")
+	}
+
+	if engine := NewMediaWikiEngine(""); engine.apiURL != WikipediaAPIURL {
+		t.Errorf("Expected Wikipedia by default, got %q", engine.apiURL)
+	}
+}
@@ -0,0 +1,123 @@
+package search
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"ask-web/pkg/logger"
+)
+
+// DefaultMaxPages is how many pages an engine will fetch if the request
+// doesn't say
+const DefaultMaxPages = 3
+
+// pageFetcher returns one page of raw (unfiltered) results starting at offset.
+type pageFetcher func(ctx context.Context, offset int) ([]SearchResult, error)
+
+// collectPages keeps fetching pages until there are MaxResults results that
+// pass the filter, an engine runs out of results, or MaxPages is reached. An
+// error on the first page is returned; an error on a later page just ends the
+// paging with whatever has been collected so far.
+func collectPages(ctx context.Context, sr SearchRequest, fetch pageFetcher) ([]SearchResult, error) {
+	maxPages := sr.MaxPages
+	if maxPages <= 0 {
+		maxPages = DefaultMaxPages
+	}
+
+	seen := make(map[string]bool)
+	filteredResults := make([]SearchResult, 0, sr.MaxResults)
+	offset := sr.Offset
+
+	for page := 0; page < maxPages; page++ {
+		// Waits out the rate limit and counts against the quota, if the
+		// engine is wrapped in a LimitedEngine
+		if err := acquireRequest(ctx); err != nil {
+			if page == 0 {
+				return nil, err
+			}
+			break
+		}
+
+		results, err := fetch(ctx, offset)
+		if err != nil {
+			if page == 0 {
+				return nil, err
+			}
+			break
+		}
+		if len(results) == 0 {
+			break
+		}
+		offset += len(results)
+
+		newResults := 0
+		for _, result := range results {
+			key := NormalizeURL(result.URL)
+			if result.URL == "" || seen[key] {
+				continue
+			}
+			seen[key] = true
+			newResults++
+
+			if sr.Filter == nil || sr.Filter(result) {
+				filteredResults = append(filteredResults, result)
+				if len(filteredResults) == sr.MaxResults {
+					return filteredResults, nil
+				}
+			}
+		}
+
+		// Some engines hand back the last page again when asked to go past
+		// the end
+		if newResults == 0 {
+			break
+		}
+	}
+
+	return filteredResults, nil
+}
+
+// pageSize is how many results to ask an engine for per page: enough to
+// cover filtering losses (see ExtraResultsFactor), within the engine's limit.
+func pageSize(sr SearchRequest, limit int) int {
+	n := int(float32(sr.MaxResults) * ExtraResultsFactor)
+	n = max(n, 1)
+	if limit > 0 {
+		n = min(n, limit)
+	}
+
+	return n
+}
+
+// detailFetcher fills in the parts of a result that take a request of their
+// own, eg an article's text or a thread's comments.
+type detailFetcher func(ctx context.Context, result *SearchResult) error
+
+// fetchDetails runs fetch on the first limit results (all of them if limit is
+// 0), in order. It's for after collectPages, so that nothing is fetched for
+// results the filter drops; engines give results a stand-in Content until
+// then, so that scraped: rules still leave them alone. A failure is logged and
+// the result left as fetch left it, except that running out of quota or time,
+// or being rate limited, stops the fetching and is returned.
+func fetchDetails(ctx context.Context, results []SearchResult, limit int, fetch detailFetcher) error {
+	if limit <= 0 || limit > len(results) {
+		limit = len(results)
+	}
+
+	for i := range results[:limit] {
+		err := fetch(ctx, &results[i])
+		if err == nil {
+			continue
+		}
+		if ctx.Err() != nil {
+			return ctx.Err()
+		}
+		if errors.Is(err, ErrQuotaExceeded) || errors.Is(err, ErrRateLimited) {
+			return err
+		}
+		logger.GetLogger().Warn(fmt.Sprintf("Error getting the rest of %s: %s", results[i].URL, err))
+	}
+
+	return nil
+}
@@ -0,0 +1,162 @@
+package search
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+// pages returns a pageFetcher that serves the given pages in order, recording
+// the offsets it was asked for
+func pages(offsets *[]int, pages ...[]SearchResult) pageFetcher {
+	return func(ctx context.Context, offset int) ([]SearchResult, error) {
+		*offsets = append(*offsets, offset)
+		i := len(*offsets) - 1
+		if i >= len(pages) {
+			return nil, nil
+		}
+		return pages[i], nil
+	}
+}
+
+func page(start, n int) []SearchResult {
+	var results []SearchResult
+	for i := start; i < start+n; i++ {
+		results = append(results, SearchResult{URL: fmt.Sprintf("https://example.com/%d", i)})
+	}
+	return results
+}
+
+func TestCollectPages(t *testing.T) {
+	noOdd := func(r SearchResult) bool {
+		var n int
+		fmt.Sscanf(r.URL, "https://example.com/%d", &n)
+		return n%2 == 0
+	}
+
+	t.Run("Pages until there are enough results", func(t *testing.T) {
+		var offsets []int
+		results, err := collectPages(context.Background(), SearchRequest{MaxResults: 4, Filter: noOdd},
+			pages(&offsets, page(0, 3), page(3, 3), page(6, 3)))
+		if err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+
+		expected := []string{"https://example.com/0", "https://example.com/2", "https://example.com/4", "https://example.com/6"}
+		if !reflect.DeepEqual(urls(results), expected) {
+			t.Errorf("Expected %v, got %v", expected, urls(results))
+		}
+		if !reflect.DeepEqual(offsets, []int{0, 3, 6}) {
+			t.Errorf("Unexpected offsets: %v", offsets)
+		}
+	})
+
+	t.Run("Stops at MaxPages", func(t *testing.T) {
+		var offsets []int
+		results, _ := collectPages(context.Background(), SearchRequest{MaxResults: 10, MaxPages: 2, Offset: 5},
+			pages(&offsets, page(0, 3), page(3, 3), page(6, 3)))
+
+		if len(results) != 6 {
+			t.Errorf("Expected 6 results, got %d", len(results))
+		}
+		if !reflect.DeepEqual(offsets, []int{5, 8}) {
+			t.Errorf("Unexpected offsets: %v", offsets)
+		}
+	})
+
+	t.Run("Stops when the engine runs out", func(t *testing.T) {
+		var offsets []int
+		results, _ := collectPages(context.Background(), SearchRequest{MaxResults: 10, MaxPages: 5},
+			pages(&offsets, page(0, 3)))
+
+		if len(results) != 3 || len(offsets) != 2 {
+			t.Errorf("Expected 3 results from 2 requests, got %d from %d", len(results), len(offsets))
+		}
+	})
+
+	t.Run("Stops when a page repeats", func(t *testing.T) {
+		var offsets []int
+		results, _ := collectPages(context.Background(), SearchRequest{MaxResults: 10, MaxPages: 5},
+			pages(&offsets, page(0, 3), page(0, 3), page(3, 3)))
+
+		if len(results) != 3 || len(offsets) != 2 {
+			t.Errorf("Expected 3 results from 2 requests, got %d from %d", len(results), len(offsets))
+		}
+	})
+
+	t.Run("Errors", func(t *testing.T) {
+		calls := 0
+		fetch := func(ctx context.Context, offset int) ([]SearchResult, error) {
+			calls++
+			if calls == 2 {
+				return nil, errors.New("page 2 failed")
+			}
+			return page(offset, 3), nil
+		}
+
+		results, err := collectPages(context.Background(), SearchRequest{MaxResults: 10}, fetch)
+		if err != nil {
+			t.Errorf("Expected a later page error to be dropped, got %v", err)
+		}
+		if len(results) != 3 {
+			t.Errorf("Expected the first page of results, got %d", len(results))
+		}
+
+		_, err = collectPages(context.Background(), SearchRequest{MaxResults: 10},
+			func(ctx context.Context, offset int) ([]SearchResult, error) {
+				return nil, errors.New("page 1 failed")
+			})
+		if err == nil || !strings.Contains(err.Error(), "page 1 failed") {
+			t.Errorf("Expected the first page error, got %v", err)
+		}
+	})
+}
+
+func TestPageSize(t *testing.T) {
+	if n := pageSize(SearchRequest{MaxResults: 8}, 10); n != 10 {
+		t.Errorf("Expected the page size to be capped at 10, got %d", n)
+	}
+	if n := pageSize(SearchRequest{MaxResults: 3}, 10); n != 6 {
+		t.Errorf("Expected 6, got %d", n)
+	}
+	if n := pageSize(SearchRequest{}, 0); n != 1 {
+		t.Errorf("Expected at least 1, got %d", n)
+	}
+}
+
+func TestFetchDetails(t *testing.T) {
+	results := page(0, 4)
+	var fetched []string
+	fetch := func(ctx context.Context, result *SearchResult) error {
+		fetched = append(fetched, result.URL)
+		if strings.HasSuffix(result.URL, "/1") {
+			return errors.New("not found")
+		}
+		result.Content = "details"
+		return nil
+	}
+
+	// A failure leaves that result alone, and the limit caps the fetches
+	if err := fetchDetails(context.Background(), results, 3, fetch); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if len(fetched) != 3 {
+		t.Errorf("Expected 3 fetches, got %v", fetched)
+	}
+	if results[0].Content != "details" || results[1].Content != "" || results[3].Content != "" {
+		t.Errorf("Unexpected results: %v", results)
+	}
+
+	// Being rate limited stops the fetching
+	fetched = nil
+	err := fetchDetails(context.Background(), page(0, 4), 0, func(ctx context.Context, result *SearchResult) error {
+		fetched = append(fetched, result.URL)
+		return &EngineError{Engine: "test", Kind: ErrRateLimited}
+	})
+	if !errors.Is(err, ErrRateLimited) || len(fetched) != 1 {
+		t.Errorf("Expected to stop at ErrRateLimited, got %v after %d", err, len(fetched))
+	}
+}
@@ -0,0 +1,321 @@
+package search
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	"ask-web/pkg/logger"
+)
+
+const RedditBaseURL = "https://www.reddit.com"
+
+const (
+	redditPageSize = 10
+	// Posts to fetch the comments of, after filtering, if there's time
+	redditMaxThreads = 5
+	// How many top-level comments, and how deep, to ask for
+	redditComments     = 20
+	redditCommentDepth = threadMaxDepth
+)
+
+// Reddit turns away requests without a descriptive User-Agent
+const redditUserAgent = "ask-web (https://github.com/duluk/ask-web)"
+
+// Reddit wraps everything as a "thing" with a kind and data
+type redditListing struct {
+	Data struct {
+		After    string `json:"after"`
+		Children []struct {
+			Kind string          `json:"kind"`
+			Data json.RawMessage `json:"data"`
+		} `json:"children"`
+	} `json:"data"`
+}
+
+type redditPost struct {
+	Title       string  `json:"title"`
+	Permalink   string  `json:"permalink"`
+	URL         string  `json:"url"`
+	IsSelf      bool    `json:"is_self"`
+	Selftext    string  `json:"selftext"`
+	Subreddit   string  `json:"subreddit"`
+	Author      string  `json:"author"`
+	Score       int     `json:"score"`
+	NumComments int     `json:"num_comments"`
+	CreatedUTC  float64 `json:"created_utc"`
+}
+
+type redditComment struct {
+	Author string `json:"author"`
+	Body   string `json:"body"`
+	Score  int    `json:"score"`
+	// A listing, or "" when there are none
+	Replies json.RawMessage `json:"replies"`
+}
+
+// RedditEngine searches Reddit posts through its public JSON endpoints, and
+// returns each post with its comment tree, flattened into threaded text with
+// the scores, as the result's Content.
+type RedditEngine struct {
+	// BaseURL and Client default to RedditBaseURL and a client with the
+	// usual timeout
+	BaseURL string
+	Client  *http.Client
+}
+
+func init() {
+	Register("reddit", func(cfg EngineConfig) (SearchEngine, error) {
+		return &RedditEngine{}, nil
+	})
+}
+
+func (e *RedditEngine) Name() string { return "reddit" }
+
+func (e *RedditEngine) Capabilities() Capabilities {
+	return Capabilities{
+		MaxResultsPerRequest: redditPageSize,
+		// Without OAuth, Reddit allows about 10 requests a minute
+		MinInterval: 6 * time.Second,
+	}
+}
+
+func (e *RedditEngine) Search(ctx context.Context, sr SearchRequest) ([]SearchResult, error) {
+	if sr.Query == "" {
+		return nil, fmt.Errorf("search query cannot be empty")
+	}
+
+	client := httpClient(e.Client)
+
+	// Reddit pages with the name of the last post rather than an offset,
+	// so an offset for the first page can't be honoured
+	after := ""
+	details := make(map[string]detailFetcher)
+
+	// Every request waits out the rate limit, so only page as far as the
+	// timeout allows; waiting past it would lose the lot
+	if budget := requestBudget(ctx); budget >= 0 {
+		maxPages := sr.MaxPages
+		if maxPages <= 0 {
+			maxPages = DefaultMaxPages
+		}
+		sr.MaxPages = min(maxPages, max(budget, 1))
+	}
+
+	results, err := collectPages(ctx, sr, func(ctx context.Context, offset int) ([]SearchResult, error) {
+		if offset > sr.Offset && after == "" {
+			return nil, nil
+		}
+		results, next, err := e.fetchPage(ctx, client, sr, after, details)
+		after = next
+		return results, err
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	// The posts are still worth something without their comments, so only
+	// fetch as many as there's time left for
+	threads := redditMaxThreads
+	if budget := requestBudget(ctx); budget >= 0 {
+		threads = min(threads, budget)
+	}
+	if threads == 0 {
+		return results, nil
+	}
+	err = fetchDetails(ctx, results, threads, func(ctx context.Context, result *SearchResult) error {
+		fetch, ok := details[result.URL]
+		if !ok {
+			return nil
+		}
+		if err := acquireRequest(ctx); err != nil {
+			return err
+		}
+		return fetch(ctx, result)
+	})
+	if err != nil {
+		logger.GetLogger().Warn("Stopped getting Reddit comments: ", err)
+	}
+
+	return results, nil
+}
+
+// fetchPage gets a page of posts, without their comments; details gets what
+// fetches them.
+func (e *RedditEngine) fetchPage(ctx context.Context, client *http.Client, sr SearchRequest, after string, details map[string]detailFetcher) ([]SearchResult, string, error) {
+	params := url.Values{}
+	params.Set("q", sr.Query)
+	params.Set("sort", "relevance")
+	params.Set("type", "link")
+	params.Set("limit", fmt.Sprintf("%d", pageSize(sr, redditPageSize)))
+	params.Set("t", redditTimeRange(sr.Since))
+	if after != "" {
+		params.Set("after", after)
+	}
+
+	var listing redditListing
+	if err := e.get(ctx, client, "/search.json", params, &listing); err != nil {
+		return nil, "", err
+	}
+
+	results := make([]SearchResult, 0, len(listing.Data.Children))
+	for _, child := range listing.Data.Children {
+		var post redditPost
+		if child.Kind != "t3" || json.Unmarshal(child.Data, &post) != nil || post.Permalink == "" {
+			continue
+		}
+
+		postURL := RedditBaseURL + post.Permalink
+		if post.NumComments > 0 {
+			details[postURL] = func(ctx context.Context, result *SearchResult) error {
+				comments, err := e.fetchComments(ctx, client, post.Permalink)
+				if err != nil {
+					return err
+				}
+				result.Content = redditContent(post, comments)
+				return nil
+			}
+		}
+
+		snippet := fmt.Sprintf("r/%s: %d points, %d comments", post.Subreddit, post.Score, post.NumComments)
+		if post.Selftext != "" {
+			snippet = truncateText(post.Selftext, maxSnippetLength)
+		}
+		results = append(results, SearchResult{
+			Title:     post.Title,
+			URL:       postURL,
+			Snippet:   snippet,
+			Content:   redditContent(post, nil),
+			Published: unixTime(int64(post.CreatedUTC)),
+		})
+	}
+
+	return results, listing.Data.After, nil
+}
+
+// redditTimeRange picks the smallest of Reddit's time ranges that covers
+// since.
+func redditTimeRange(since time.Duration) string {
+	switch {
+	case since <= 0:
+		return "all"
+	case since <= time.Hour:
+		return "hour"
+	case since <= 24*time.Hour:
+		return "day"
+	case since <= 7*24*time.Hour:
+		return "week"
+	case since <= 31*24*time.Hour:
+		return "month"
+	case since <= 366*24*time.Hour:
+		return "year"
+	default:
+		return "all"
+	}
+}
+
+// fetchComments gets the comment tree of a post, best first.
+func (e *RedditEngine) fetchComments(ctx context.Context, client *http.Client, permalink string) ([]threadComment, error) {
+	params := url.Values{}
+	params.Set("sort", "top")
+	params.Set("limit", fmt.Sprintf("%d", redditComments))
+	params.Set("depth", fmt.Sprintf("%d", redditCommentDepth))
+
+	// The post, then its comments
+	var listings []redditListing
+	if err := e.get(ctx, client, strings.TrimSuffix(permalink, "/")+".json", params, &listings); err != nil {
+		return nil, err
+	}
+	if len(listings) < 2 {
+		return nil, &EngineError{Engine: e.Name(), Message: "no comments in response"}
+	}
+
+	return redditThread(listings[1]), nil
+}
+
+func redditThread(listing redditListing) []threadComment {
+	var comments []threadComment
+	for _, child := range listing.Data.Children {
+		// "more" is a link to comments that didn't fit
+		var c redditComment
+		if child.Kind != "t1" || json.Unmarshal(child.Data, &c) != nil {
+			continue
+		}
+
+		comment := threadComment{
+			Author: c.Author,
+			Score:  &c.Score,
+			Text:   c.Body,
+		}
+		if bytes.HasPrefix(bytes.TrimSpace(c.Replies), []byte("{")) {
+			var replies redditListing
+			if json.Unmarshal(c.Replies, &replies) == nil {
+				comment.Replies = redditThread(replies)
+			}
+		}
+		comments = append(comments, comment)
+	}
+	return comments
+}
+
+func redditContent(post redditPost, comments []threadComment) string {
+	var b strings.Builder
+	fmt.Fprintf(&b, "# %s (r/%s, %d points, %d comments)\n", post.Title, post.Subreddit, post.Score, post.NumComments)
+	if !post.IsSelf && post.URL != "" {
+		b.WriteString("Link: " + post.URL + "\n")
+	}
+	b.WriteString("Posted by " + post.Author)
+	if posted := unixTime(int64(post.CreatedUTC)); !posted.IsZero() {
+		b.WriteString(" on " + posted.UTC().Format(time.DateOnly))
+	}
+	b.WriteString("\n")
+	if text := strings.TrimSpace(post.Selftext); text != "" {
+		b.WriteString("\n" + text + "\n")
+	}
+
+	if thread := flattenThread(comments); thread != "" {
+		b.WriteString("\n## Comments\n\n" + thread + "\n")
+	}
+
+	return b.String()
+}
+
+func (e *RedditEngine) get(ctx context.Context, client *http.Client, path string, params url.Values, v any) error {
+	// Otherwise text comes back with &amp; and friends in it
+	params.Set("raw_json", "1")
+
+	baseURL := e.BaseURL
+	if baseURL == "" {
+		baseURL = RedditBaseURL
+	}
+
+	resp, err := doRequest(ctx, client, e.Name(), func(ctx context.Context) (*http.Request, error) {
+		req, err := http.NewRequestWithContext(ctx, "GET", baseURL+path+"?"+params.Encode(), nil)
+		if err != nil {
+			return nil, err
+		}
+		req.Header.Set("User-Agent", redditUserAgent)
+		return req, nil
+	})
+	var engineErr *EngineError
+	if errors.As(err, &engineErr) && engineErr.StatusCode == http.StatusForbidden {
+		// There's no key to get wrong; Reddit blocks some networks outright
+		engineErr.Kind = ErrBlocked
+	}
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if err := json.NewDecoder(resp.Body).Decode(v); err != nil {
+		return &EngineError{Engine: e.Name(), Message: "error decoding response", Err: err}
+	}
+
+	return nil
+}
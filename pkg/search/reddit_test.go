@@ -0,0 +1,176 @@
+package search
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestRedditSearch(t *testing.T) {
+	var requests []*http.Request
+	server := fixtureServer(t, map[string]string{
+		"/search.json": "reddit_search.json",
+		"/r/webdev/comments/abc123/thoughts_on_htmx_after_a_year_in_production.json": "reddit_comments.json",
+	}, &requests)
+
+	engine := &RedditEngine{BaseURL: server.URL, Client: server.Client()}
+	results, err := engine.Search(context.Background(), SearchRequest{Query: "htmx production", MaxResults: 2, MaxPages: 1, Since: 7 * 24 * time.Hour})
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	expected := []string{
+		"https://www.reddit.com/r/webdev/comments/abc123/thoughts_on_htmx_after_a_year_in_production/",
+		"https://www.reddit.com/r/programming/comments/def456/htmx_20_released/",
+	}
+	if !reflect.DeepEqual(urls(results), expected) {
+		t.Fatalf("Expected %v, got %v", expected, urls(results))
+	}
+
+	post := results[0]
+	if !post.Published.Equal(time.Unix(1700000000, 0)) {
+		t.Errorf("Unexpected published date: %s", post.Published)
+	}
+	if !strings.HasPrefix(post.Content, "# Thoughts on htmx after a year in production? (r/webdev, 250 points, 4 comments)\nPosted by gopher on 2023-11-14\n\nWe replaced a React SPA with htmx & Go templates. AMA.\n") {
+		t.Errorf("Unexpected heading: %q", post.Content)
+	}
+	thread := "## Comments\n\n" +
+		"- frontend_dev (87 points):\n  Great for CRUD.\n  Painful for anything with lots of client state.\n" +
+		"  - gopher (40 points):\n    Agreed, we kept one React island.\n" +
+		"- skeptic (-3 points):\n  Wait until you need offline support.\n"
+	if !strings.HasSuffix(post.Content, thread) {
+		t.Errorf("Expected the comment thread %q, got %q", thread, post.Content)
+	}
+
+	link := results[1]
+	if !strings.Contains(link.Content, "Link: https://htmx.org/posts/2024-06-17-htmx-2-0-0-is-released/\n") {
+		t.Errorf("Expected the link, got %q", link.Content)
+	}
+	if link.Snippet != "r/programming: 1200 points, 0 comments" {
+		t.Errorf("Unexpected snippet: %q", link.Snippet)
+	}
+
+	if len(requests) != 2 {
+		t.Fatalf("Expected 2 requests, got %d", len(requests))
+	}
+	for _, r := range requests {
+		if r.Header.Get("User-Agent") != redditUserAgent || r.URL.Query().Get("raw_json") != "1" {
+			t.Errorf("Expected the User-Agent and raw_json on %s", r.URL)
+		}
+	}
+	if q := requests[0].URL.Query(); q.Get("q") != "htmx production" || q.Get("t") != "week" || q.Get("limit") != "4" {
+		t.Errorf("Unexpected search request: %v", q)
+	}
+	if q := requests[1].URL.Query(); q.Get("sort") != "top" {
+		t.Errorf("Unexpected comments request: %v", q)
+	}
+}
+
+func TestRedditLimited(t *testing.T) {
+	// Six posts with comments
+	var children []string
+	for i := range 6 {
+		children = append(children, fmt.Sprintf(`{"kind": "t3", "data": {"title": "Post %d", "permalink": "/r/golang/comments/%d/post/", "is_self": true, "selftext": "text", "subreddit": "golang", "num_comments": 3, "created_utc": 1700000000}}`, i, i))
+	}
+	listing := `{"data": {"after": null, "children": [` + strings.Join(children, ",") + `]}}`
+
+	var mu sync.Mutex
+	var threads []string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/search.json" {
+			w.Write([]byte(listing))
+			return
+		}
+		mu.Lock()
+		threads = append(threads, r.URL.Path)
+		mu.Unlock()
+		w.Write(fixture(t, "reddit_comments.json"))
+	}))
+	defer server.Close()
+
+	engine := &RedditEngine{BaseURL: server.URL, Client: server.Client()}
+	sr := SearchRequest{
+		Query:      "go",
+		MaxResults: 6,
+		MaxPages:   1,
+		Filter: func(r SearchResult) bool {
+			return !strings.Contains(r.URL, "/comments/0/")
+		},
+	}
+	results, err := engine.Search(context.Background(), sr)
+	if err != nil || len(results) != 5 {
+		t.Fatalf("Expected 5 results, got %d (%v)", len(results), err)
+	}
+
+	// Only the kept posts, up to the cap, have their comments fetched
+	if len(threads) != redditMaxThreads {
+		t.Errorf("Expected %d comment requests, got %v", redditMaxThreads, threads)
+	}
+	for _, thread := range threads {
+		if strings.Contains(thread, "/comments/0/") {
+			t.Errorf("Expected no comments for the dropped post, got %s", thread)
+		}
+	}
+	if !strings.Contains(results[0].Content, "## Comments") {
+		t.Errorf("Expected the comments, got %q", results[0].Content)
+	}
+
+	// The comments wait out the 6s between requests like the searches, so
+	// with no time for that the posts come back without them rather than
+	// not at all
+	threads = nil
+	limited := WithLimits([]SearchEngine{engine}, &memoryUsage{counts: make(map[string]int)}, nil)
+	start := time.Now()
+	engineResults := SearchAll(context.Background(), limited, sr, 3*time.Second)
+	if elapsed := time.Since(start); elapsed > time.Second {
+		t.Errorf("Expected no waiting, took %s", elapsed)
+	}
+	if engineResults[0].Err != nil || len(engineResults[0].Results) != 5 {
+		t.Fatalf("Expected 5 results, got %d (%v)", len(engineResults[0].Results), engineResults[0].Err)
+	}
+	if len(threads) != 0 {
+		t.Errorf("Expected no comment requests, got %v", threads)
+	}
+}
+
+func TestRedditBlocked(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusForbidden)
+		w.Write([]byte("<html><body>blocked</body></html>"))
+	}))
+	defer server.Close()
+
+	engine := &RedditEngine{BaseURL: server.URL}
+	_, err := engine.Search(context.Background(), SearchRequest{Query: "htmx", MaxResults: 5})
+	if !errors.Is(err, ErrBlocked) {
+		t.Errorf("Expected ErrBlocked, got %v", err)
+	}
+}
+
+func TestRedditTimeRange(t *testing.T) {
+	testCases := []struct {
+		since    time.Duration
+		expected string
+	}{
+		{0, "all"},
+		{time.Hour, "hour"},
+		{2 * time.Hour, "day"},
+		{7 * 24 * time.Hour, "week"},
+		{14 * 24 * time.Hour, "month"},
+		{365 * 24 * time.Hour, "year"},
+		{2 * 365 * 24 * time.Hour, "all"},
+	}
+
+	for _, tc := range testCases {
+		if got := redditTimeRange(tc.since); got != tc.expected {
+			t.Errorf("redditTimeRange(%s) = %q; want %q", tc.since, got, tc.expected)
+		}
+	}
+}
@@ -0,0 +1,142 @@
+package search
+
+import (
+	"fmt"
+	"strings"
+	"time"
+)
+
+type SafeSearch string
+
+const (
+	SafeSearchOff      SafeSearch = "off"
+	SafeSearchModerate SafeSearch = "moderate"
+	SafeSearchStrict   SafeSearch = "strict"
+)
+
+// ParseSafeSearch returns "" for an empty level, which leaves each engine to
+// its own default.
+func ParseSafeSearch(s string) (SafeSearch, error) {
+	switch SafeSearch(strings.ToLower(strings.TrimSpace(s))) {
+	case "":
+		return "", nil
+	case SafeSearchOff:
+		return SafeSearchOff, nil
+	case SafeSearchModerate:
+		return SafeSearchModerate, nil
+	case SafeSearchStrict:
+		return SafeSearchStrict, nil
+	default:
+		return "", fmt.Errorf("invalid safe search level %q (want off, moderate or strict)", s)
+	}
+}
+
+// SearchRequest is what gets handed to every engine for a search. Everything
+// in it is engine neutral; each backend translates the fields into its own
+// parameters (and does its own URL escaping).
+type SearchRequest struct {
+	// Query is the plain search text, not URL escaped
+	Query string
+	// MaxResults is how many results (after filtering) the caller wants
+	MaxResults int
+	// Offset skips this many results, for engines that support it
+	Offset int
+	// MaxPages caps how many pages an engine fetches trying to reach
+	// MaxResults; 0 means DefaultMaxPages
+	MaxPages int
+
+	// Region is "<country>-<language>" as DuckDuckGo uses it, eg "de-de" or
+	// "us-en"; empty (or "wt-wt") means no preference
+	Region string
+	// Language is a two letter code, eg "de"; if empty, the language from
+	// Region is used
+	Language string
+	// SafeSearch is empty if it wasn't asked for, in which case engines
+	// don't send it (and get whatever the engine does by default)
+	SafeSearch SafeSearch
+
+	// Sites restricts results to these domains; ExcludeSites drops them
+	Sites        []string
+	ExcludeSites []string
+
+	// Since asks for results from this far back at most; 0 means no limit.
+	// Each engine maps it to the nearest time range it supports.
+	Since time.Duration
+
+	Filter FilterFunc
+	// FilterKey describes Filter (see filter.Set.String), so that cached
+	// results are only reused with the same filter rules
+	FilterKey string
+}
+
+// Country returns the country part of the region, eg "de" for "de-de", or ""
+// if there's no region preference.
+func (r SearchRequest) Country() string {
+	country, _ := splitRegion(r.Region)
+	return country
+}
+
+// Lang returns the language to ask for, eg "de", or "" if there's no
+// preference.
+func (r SearchRequest) Lang() string {
+	if r.Language != "" {
+		return strings.ToLower(r.Language)
+	}
+	_, lang := splitRegion(r.Region)
+	return lang
+}
+
+// Locale returns the language and country as a locale, eg "de-DE". If only
+// one of them is known, that one is returned on its own.
+func (r SearchRequest) Locale() string {
+	lang, country := r.Lang(), r.Country()
+	switch {
+	case lang != "" && country != "":
+		return lang + "-" + strings.ToUpper(country)
+	case lang != "":
+		return lang
+	default:
+		return country
+	}
+}
+
+// QueryText returns the query with site: operators added for Sites and
+// ExcludeSites, for engines that don't have parameters for them.
+func (r SearchRequest) QueryText() string {
+	parts := []string{r.Query}
+
+	var sites []string
+	for _, site := range r.Sites {
+		sites = append(sites, "site:"+site)
+	}
+	switch len(sites) {
+	case 0:
+	case 1:
+		parts = append(parts, sites[0])
+	default:
+		parts = append(parts, "("+strings.Join(sites, " OR ")+")")
+	}
+
+	for _, site := range r.ExcludeSites {
+		parts = append(parts, "-site:"+site)
+	}
+
+	return strings.Join(parts, " ")
+}
+
+func splitRegion(region string) (country, lang string) {
+	region = strings.ToLower(strings.TrimSpace(region))
+	if region == "" || region == "wt-wt" {
+		return "", ""
+	}
+
+	country, lang, _ = strings.Cut(region, "-")
+	if country == "wt" {
+		country = ""
+	}
+	if lang == "wt" {
+		lang = ""
+	}
+
+	return country, lang
+}
@@ -0,0 +1,134 @@
+package search
+
+import (
+	"testing"
+)
+
+func TestSearchRequestLocale(t *testing.T) {
+	testCases := []struct {
+		name    string
+		req     SearchRequest
+		country string
+		lang    string
+		locale  string
+		ddg     string
+	}{
+		{
+			name:   "No preference",
+			req:    SearchRequest{},
+			ddg:    "wt-wt",
+			locale: "",
+		},
+		{
+			name:   "Worldwide",
+			req:    SearchRequest{Region: "wt-wt"},
+			ddg:    "wt-wt",
+			locale: "",
+		},
+		{
+			name:    "Region",
+			req:     SearchRequest{Region: "de-de"},
+			country: "de",
+			lang:    "de",
+			locale:  "de-DE",
+			ddg:     "de-de",
+		},
+		{
+			name:    "Language overrides region",
+			req:     SearchRequest{Region: "ch-de", Language: "FR"},
+			country: "ch",
+			lang:    "fr",
+			locale:  "fr-CH",
+			ddg:     "ch-fr",
+		},
+		{
+			name:   "Language only",
+			req:    SearchRequest{Language: "es"},
+			lang:   "es",
+			locale: "es",
+			ddg:    "wt-wt",
+		},
+		{
+			name:    "Country only",
+			req:     SearchRequest{Region: "us"},
+			country: "us",
+			locale:  "us",
+			ddg:     "us-us",
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			if got := tc.req.Country(); got != tc.country {
+				t.Errorf("Country() = %q; want %q", got, tc.country)
+			}
+			if got := tc.req.Lang(); got != tc.lang {
+				t.Errorf("Lang() = %q; want %q", got, tc.lang)
+			}
+			if got := tc.req.Locale(); got != tc.locale {
+				t.Errorf("Locale() = %q; want %q", got, tc.locale)
+			}
+			if got := ddgRegion(tc.req); got != tc.ddg {
+				t.Errorf("ddgRegion() = %q; want %q", got, tc.ddg)
+			}
+		})
+	}
+}
+
+func TestSearchRequestQueryText(t *testing.T) {
+	testCases := []struct {
+		name     string
+		req      SearchRequest
+		expected string
+	}{
+		{
+			name:     "Plain query",
+			req:      SearchRequest{Query: "go generics"},
+			expected: "go generics",
+		},
+		{
+			name:     "One site",
+			req:      SearchRequest{Query: "go generics", Sites: []string{"go.dev"}},
+			expected: "go generics site:go.dev",
+		},
+		{
+			name:     "Several sites and exclusions",
+			req:      SearchRequest{Query: "go generics", Sites: []string{"go.dev", "github.com"}, ExcludeSites: []string{"medium.com"}},
+			expected: "go generics (site:go.dev OR site:github.com) -site:medium.com",
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			if got := tc.req.QueryText(); got != tc.expected {
+				t.Errorf("QueryText() = %q; want %q", got, tc.expected)
+			}
+		})
+	}
+}
+
+func TestParseSafeSearch(t *testing.T) {
+	testCases := []struct {
+		input    string
+		expected SafeSearch
+		wantErr  bool
+	}{
+		{"off", SafeSearchOff, false},
+		{"Strict", SafeSearchStrict, false},
+		{"", "", false},
+		{"moderate", SafeSearchModerate, false},
+		{"maximum", "", true},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.input, func(t *testing.T) {
+			got, err := ParseSafeSearch(tc.input)
+			if (err != nil) != tc.wantErr {
+				t.Fatalf("ParseSafeSearch(%q) error = %v, wantErr %v", tc.input, err, tc.wantErr)
+			}
+			if got != tc.expected {
+				t.Errorf("ParseSafeSearch(%q) = %q; want %q", tc.input, got, tc.expected)
+			}
+		})
+	}
+}
@@ -0,0 +1,106 @@
+package search
+
+import (
+	"context"
+	"io"
+	"math/rand/v2"
+	"net/http"
+	"time"
+)
+
+// RetryPolicy controls how doRequest retries transient failures and rate
+// limits: exponential backoff from BaseDelay, with jitter, capped at MaxDelay.
+// A Retry-After from the engine is used instead when it's given, unless it's
+// longer than MaxDelay, in which case the error is returned for the caller to
+// deal with.
+type RetryPolicy struct {
+	MaxAttempts int
+	BaseDelay   time.Duration
+	MaxDelay    time.Duration
+}
+
+var DefaultRetryPolicy = RetryPolicy{
+	MaxAttempts: 3,
+	BaseDelay:   500 * time.Millisecond,
+	MaxDelay:    10 * time.Second,
+}
+
+// doRequest sends the request made by newRequest, retrying according to
+// DefaultRetryPolicy. newRequest is called for each attempt so that bodies
+// and contexts are fresh. On success the response (always 200) is returned
+// and the caller must close its body; otherwise the error is an EngineError
+// or a context error.
+func doRequest(ctx context.Context, client *http.Client, engine string, newRequest func(ctx context.Context) (*http.Request, error)) (*http.Response, error) {
+	policy := DefaultRetryPolicy
+
+	for attempt := 1; ; attempt++ {
+		resp, err := tryRequest(ctx, client, engine, newRequest)
+		if err == nil {
+			return resp, nil
+		}
+
+		engineErr, ok := err.(*EngineError)
+		if !ok || !engineErr.Retryable() || attempt >= policy.MaxAttempts {
+			return nil, err
+		}
+
+		delay := policy.backoff(attempt)
+		if engineErr.RetryAfter > 0 {
+			if engineErr.RetryAfter > policy.MaxDelay {
+				return nil, err
+			}
+			delay = engineErr.RetryAfter
+		}
+
+		// No point waiting if there won't be time to try again
+		if deadline, ok := ctx.Deadline(); ok && time.Until(deadline) < delay {
+			return nil, err
+		}
+
+		timer := time.NewTimer(delay)
+		select {
+		case <-timer.C:
+		case <-ctx.Done():
+			timer.Stop()
+			return nil, ctx.Err()
+		}
+
+		// Retries are requests too, as far as rate limits and quotas go
+		if err := acquireRequest(ctx); err != nil {
+			return nil, err
+		}
+	}
+}
+
+func tryRequest(ctx context.Context, client *http.Client, engine string, newRequest func(ctx context.Context) (*http.Request, error)) (*http.Response, error) {
+	req, err := newRequest(ctx)
+	if err != nil {
+		return nil, &EngineError{Engine: engine, Message: "error creating request", Err: err}
+	}
+
+	resp, err := client.Do(req)
+	if err != nil {
+		return nil, classifyNetworkError(engine, err)
+	}
+
+	if resp.StatusCode != http.StatusOK {
+		defer resp.Body.Close()
+		body, _ := io.ReadAll(io.LimitReader(resp.Body, 4096))
+		return nil, classifyResponse(engine, resp, body)
+	}
+
+	return resp, nil
+}
+
+// backoff is the delay before retry number attempt: BaseDelay doubled for
+// each earlier attempt, capped at MaxDelay, then somewhere between half that
+// and all of it so that parallel requests don't retry in lockstep.
+func (p RetryPolicy) backoff(attempt int) time.Duration {
+	delay := p.BaseDelay << (attempt - 1)
+	if delay <= 0 || delay > p.MaxDelay {
+		delay = p.MaxDelay
+	}
+
+	half := delay / 2
+	return half + rand.N(half+1)
+}
@@ -0,0 +1,111 @@
+package search
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+// statusServer answers with each of statuses in turn, then 200
+func statusServer(t *testing.T, retryAfter string, statuses ...int) (*httptest.Server, *int) {
+	requests := 0
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests++
+		if requests <= len(statuses) {
+			if retryAfter != "" {
+				w.Header().Set("Retry-After", retryAfter)
+			}
+			w.WriteHeader(statuses[requests-1])
+			return
+		}
+		w.Write([]byte("ok"))
+	}))
+	t.Cleanup(server.Close)
+	return server, &requests
+}
+
+func TestDoRequest(t *testing.T) {
+	defer func(orig RetryPolicy) { DefaultRetryPolicy = orig }(DefaultRetryPolicy)
+	DefaultRetryPolicy = RetryPolicy{MaxAttempts: 3, BaseDelay: time.Millisecond, MaxDelay: 50 * time.Millisecond}
+
+	get := func(url string) func(ctx context.Context) (*http.Request, error) {
+		return func(ctx context.Context) (*http.Request, error) {
+			return http.NewRequestWithContext(ctx, "GET", url, nil)
+		}
+	}
+
+	t.Run("Retries transient failures", func(t *testing.T) {
+		server, requests := statusServer(t, "", http.StatusServiceUnavailable, http.StatusBadGateway)
+		resp, err := doRequest(context.Background(), server.Client(), "test", get(server.URL))
+		if err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+		resp.Body.Close()
+		if *requests != 3 {
+			t.Errorf("Expected 3 requests, got %d", *requests)
+		}
+	})
+
+	t.Run("Gives up after MaxAttempts", func(t *testing.T) {
+		server, requests := statusServer(t, "", 503, 503, 503, 503)
+		_, err := doRequest(context.Background(), server.Client(), "test", get(server.URL))
+		if !errors.Is(err, ErrTransient) {
+			t.Errorf("Expected ErrTransient, got %v", err)
+		}
+		if *requests != 3 {
+			t.Errorf("Expected 3 requests, got %d", *requests)
+		}
+	})
+
+	t.Run("Doesn't retry auth failures", func(t *testing.T) {
+		server, requests := statusServer(t, "", http.StatusUnauthorized)
+		_, err := doRequest(context.Background(), server.Client(), "test", get(server.URL))
+		if !errors.Is(err, ErrAuthFailed) {
+			t.Errorf("Expected ErrAuthFailed, got %v", err)
+		}
+		if *requests != 1 {
+			t.Errorf("Expected 1 request, got %d", *requests)
+		}
+	})
+
+	t.Run("Honours Retry-After", func(t *testing.T) {
+		server, requests := statusServer(t, "0", http.StatusTooManyRequests)
+		resp, err := doRequest(context.Background(), server.Client(), "test", get(server.URL))
+		if err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+		resp.Body.Close()
+		if *requests != 2 {
+			t.Errorf("Expected 2 requests, got %d", *requests)
+		}
+	})
+
+	t.Run("Retry-After too long to wait", func(t *testing.T) {
+		server, requests := statusServer(t, "3600", http.StatusTooManyRequests)
+		_, err := doRequest(context.Background(), server.Client(), "test", get(server.URL))
+		var engineErr *EngineError
+		if !errors.As(err, &engineErr) || engineErr.Kind != ErrRateLimited || engineErr.RetryAfter != time.Hour {
+			t.Errorf("Expected a rate limit error with an hour to wait, got %v", err)
+		}
+		if *requests != 1 {
+			t.Errorf("Expected 1 request, got %d", *requests)
+		}
+	})
+}
+
+func TestBackoff(t *testing.T) {
+	policy := RetryPolicy{BaseDelay: 100 * time.Millisecond, MaxDelay: time.Second}
+
+	for attempt, limit := range []time.Duration{100, 200, 400, 800, 1000, 1000} {
+		limit *= time.Millisecond
+		for i := 0; i < 20; i++ {
+			delay := policy.backoff(attempt + 1)
+			if delay < limit/2 || delay > limit {
+				t.Fatalf("backoff(%d) = %s; want between %s and %s", attempt+1, delay, limit/2, limit)
+			}
+		}
+	}
+}
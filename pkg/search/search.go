@@ -1,19 +1,25 @@
 package search
 
 import (
-	"context"
-	"errors"
-	"fmt"
-
-	"github.com/sashabaranov/go-openai"
-
-	"ask-web/pkg/config"
+	"net/http"
+	"time"
 )
 
 type SearchResult struct {
 	Title   string
 	URL     string
 	Snippet string
+	// Content is the page's text, for engines that return it (eg from an
+	// API) so that the page doesn't have to be downloaded and cleaned up
+	Content string
+	// Published and Authors are filled in by engines that know them (eg
+	// arxiv); Published is the zero time otherwise
+	Published time.Time
+	Authors   []string
+
+	// Engines and queries that returned this URL; filled in by Fuse
+	Engines []string
+	Queries []string
 }
 
 type APIKeys struct {
@@ -22,7 +28,12 @@ type APIKeys struct {
 	GoogleCSEID   string
 	BingAPIKey    string
 	BingConfigKey string
-	OpenAIKey     string
+	BraveAPIKey   string
+	// StackExchangeKey is optional; it raises the daily quota
+	StackExchangeKey string
+	// GitHubToken is optional, except for searching discussions
+	GitHubToken string
+	OpenAIKey   string
 }
 
 type FilterFunc func(SearchResult) bool
@@ -30,39 +41,25 @@ type FilterFunc func(SearchResult) bool
 const MaxTimeoutSeconds = 10
 const ExtraResultsFactor = 2.0
 
-// Take a query argument and then send it to the OpenAI API to generate a concise search query to use
-func CreateSearchQuery(opts *config.Opts, apiKey string, query string) (string, error) {
-	client := openai.NewClient(apiKey)
-
-	systemPrompt := "You are generating a query to pass to a search engine. Return only the query, do not generate extraneous information. Try not to include dates unless in the prompt itself; your knowledge base is cutoff and you may get it wrong."
-	prompt := fmt.Sprintf("%s: '%s'", opts.QueryPrompt, query)
+// Longest snippet to cut from a post, for engines that only have its text
+const maxSnippetLength = 300
 
-	ctx := context.Background()
-
-	req := openai.ChatCompletionRequest{
-		Model:       openai.GPT4oMini,
-		MaxTokens:   50,
-		Temperature: 0.3,
-		Messages: []openai.ChatCompletionMessage{
-			{
-				Role:    openai.ChatMessageRoleSystem,
-				Content: systemPrompt,
-			},
-			{
-				Role:    openai.ChatMessageRoleUser,
-				Content: prompt,
-			},
-		},
+// httpClient returns client, or one with the default timeout if it's nil, so
+// that engines can be given a client (eg in tests) but don't need one.
+func httpClient(client *http.Client) *http.Client {
+	if client != nil {
+		return client
 	}
-
-	resp, err := client.CreateChatCompletion(ctx, req)
-	if err != nil {
-		return "", err
+	return &http.Client{
+		Timeout: time.Duration(MaxTimeoutSeconds) * time.Second,
 	}
+}
 
-	if len(resp.Choices) == 0 {
-		return "", errors.New("no query generated")
+// unixTime converts a timestamp from an API, where 0 (or a missing field)
+// means the date isn't known, and so the zero time rather than 1970.
+func unixTime(seconds int64) time.Time {
+	if seconds <= 0 {
+		return time.Time{}
 	}
-
-	return resp.Choices[0].Message.Content, nil
+	return time.Unix(seconds, 0)
 }
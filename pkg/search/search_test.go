@@ -1,15 +1,63 @@
 package search
 
 import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
 	"testing"
+	"time"
 )
 
-// Test search for DDG since no API key is required
+// fixture returns the contents of testdata/name. The fixtures are responses
+// recorded from the real engines, trimmed down.
+func fixture(t *testing.T, name string) []byte {
+	t.Helper()
+
+	data, err := os.ReadFile(filepath.Join("testdata", name))
+	if err != nil {
+		t.Fatalf("Error reading fixture: %v", err)
+	}
+	return data
+}
+
+// fixtureServer serves the fixture for each path in fixtures and a 404 for
+// anything else. The requests it gets are appended to *requests.
+func fixtureServer(t *testing.T, fixtures map[string]string, requests *[]*http.Request) *httptest.Server {
+	t.Helper()
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		name, ok := fixtures[r.URL.Path]
+		if !ok {
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+		if requests != nil {
+			*requests = append(*requests, r)
+		}
+		if strings.HasSuffix(name, ".json") {
+			w.Header().Set("Content-Type", "application/json")
+		} else {
+			w.Header().Set("Content-Type", "text/html; charset=utf-8")
+		}
+		w.Write(fixture(t, name))
+	}))
+	t.Cleanup(server.Close)
+
+	return server
+}
+
 func TestDDGSearch(t *testing.T) {
+	server := fixtureServer(t, map[string]string{"/html/": "ddg.html"}, nil)
+	engine := &DDGEngine{BaseURL: server.URL + "/html/", Client: server.Client()}
+
 	tests := []struct {
 		name       string
 		query      string
 		maxResults int
+		region     string
 		wantErr    bool
 	}{
 		{
@@ -22,6 +70,7 @@ func TestDDGSearch(t *testing.T) {
 			name:       "Custom options",
 			query:      "golang programming",
 			maxResults: 3,
+			region:     "de-de",
 			wantErr:    false,
 		},
 		{
@@ -34,7 +83,11 @@ func TestDDGSearch(t *testing.T) {
 
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
-			results, err := DDGSearch(tt.query, tt.maxResults, nil)
+			results, err := engine.Search(context.Background(), SearchRequest{
+				Query:      tt.query,
+				MaxResults: tt.maxResults,
+				Region:     tt.region,
+			})
 
 			if (err != nil) != tt.wantErr {
 				t.Errorf("Search() error = %v, wantErr %v", err, tt.wantErr)
@@ -63,3 +116,12 @@ func TestDDGSearch(t *testing.T) {
 		})
 	}
 }
+
+func TestUnixTime(t *testing.T) {
+	if !unixTime(0).IsZero() {
+		t.Errorf("Expected no date for 0, got %s", unixTime(0))
+	}
+	if got := unixTime(1648000000); !got.Equal(time.Unix(1648000000, 0)) {
+		t.Errorf("Unexpected time: %s", got)
+	}
+}
@@ -0,0 +1,82 @@
+package search
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+const searxngResponseJSON = `{
+	"query": "golang",
+	"number_of_results": 0,
+	"results": [
+		{"url": "https://go.dev/", "title": "The Go Programming Language", "content": "Go is an open source programming language.", "engine": "duckduckgo"},
+		{"url": "https://pkg.go.dev/", "title": "Go Packages", "content": "Discover packages.", "engine": "bing"},
+		{"url": "https://gobyexample.com/", "title": "Go by Example", "content": "Hands-on introduction.", "engine": "google"}
+	]
+}`
+
+func TestSearxNGSearch(t *testing.T) {
+	var gotQuery map[string][]string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/search" {
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+		gotQuery = r.URL.Query()
+		w.Header().Set("Content-Type", "application/json")
+		w.Write([]byte(searxngResponseJSON))
+	}))
+	defer server.Close()
+
+	engine := NewSearxNGEngine(server.URL+"/", []string{"general", "it"}, []string{"duckduckgo", "bing"})
+	results, err := engine.Search(context.Background(), SearchRequest{
+		Query:      "golang",
+		MaxResults: 2,
+		Region:     "de-de",
+		SafeSearch: SafeSearchStrict,
+		Filter: func(r SearchResult) bool {
+			return r.URL != "https://pkg.go.dev/"
+		},
+	})
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	if len(results) != 2 || results[0].URL != "https://go.dev/" || results[1].URL != "https://gobyexample.com/" {
+		t.Errorf("Unexpected results: %v", results)
+	}
+	if results[0].Snippet != "Go is an open source programming language." {
+		t.Errorf("Unexpected snippet: %q", results[0].Snippet)
+	}
+
+	expected := map[string]string{
+		"q":          "golang",
+		"format":     "json",
+		"categories": "general,it",
+		"engines":    "duckduckgo,bing",
+		"language":   "de-DE",
+		"safesearch": "2",
+	}
+	for k, v := range expected {
+		if len(gotQuery[k]) != 1 || gotQuery[k][0] != v {
+			t.Errorf("Expected %s=%q, got %v", k, v, gotQuery[k])
+		}
+	}
+}
+
+func TestSearxNGSearchErrors(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusForbidden)
+	}))
+	defer server.Close()
+
+	engine := NewSearxNGEngine(server.URL, nil, nil)
+	if _, err := engine.Search(context.Background(), SearchRequest{Query: "golang", MaxResults: 3}); err == nil {
+		t.Error("Expected an error for a 403")
+	}
+	if _, err := engine.Search(context.Background(), SearchRequest{MaxResults: 3}); err == nil {
+		t.Error("Expected an error for an empty query")
+	}
+}
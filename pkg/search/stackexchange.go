@@ -0,0 +1,354 @@
+package search
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"html"
+	"net/http"
+	"net/url"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+	"unicode/utf8"
+
+	"ask-web/pkg/logger"
+)
+
+const StackExchangeBaseURL = "https://api.stackexchange.com/2.3"
+
+const (
+	// The API's limit is 100, but every question comes with its answers
+	sePageSize = 10
+	// The accepted answer (if any) and the best of the rest
+	seAnswersPerQuestion = 2
+)
+
+type seQuestion struct {
+	QuestionID       int      `json:"question_id"`
+	Title            string   `json:"title"`
+	Link             string   `json:"link"`
+	Body             string   `json:"body"`
+	Score            int      `json:"score"`
+	Tags             []string `json:"tags"`
+	AcceptedAnswerID int      `json:"accepted_answer_id"`
+	CreationDate     int64    `json:"creation_date"`
+}
+
+type seAnswer struct {
+	AnswerID   int    `json:"answer_id"`
+	QuestionID int    `json:"question_id"`
+	Body       string `json:"body"`
+	Score      int    `json:"score"`
+	IsAccepted bool   `json:"is_accepted"`
+}
+
+// Every response is wrapped like this
+type seResponse[T any] struct {
+	Items []T `json:"items"`
+	seWrapper
+}
+
+type seWrapper struct {
+	HasMore        bool   `json:"has_more"`
+	QuotaRemaining int    `json:"quota_remaining"`
+	Backoff        int    `json:"backoff"`
+	ErrorName      string `json:"error_name"`
+	ErrorMessage   string `json:"error_message"`
+}
+
+func (w *seWrapper) wrapper() *seWrapper { return w }
+
+// StackExchangeEngine searches one Stack Exchange site (Stack Overflow by
+// default) and returns each question with its accepted and top answers as
+// the result's Content, converted to markdown so that code blocks survive.
+type StackExchangeEngine struct {
+	site string
+	key  string
+
+	// BaseURL and Client default to StackExchangeBaseURL and a client with
+	// the usual timeout
+	BaseURL string
+	Client  *http.Client
+
+	// The API can tell us to leave it alone for a while
+	mu      sync.Mutex
+	backoff time.Time
+}
+
+func init() {
+	Register("stackexchange", func(cfg EngineConfig) (SearchEngine, error) {
+		site := ""
+		if cfg.Opts != nil {
+			site = cfg.Opts.StackExchangeSite
+		}
+		return NewStackExchangeEngine(site, cfg.Keys.StackExchangeKey), nil
+	})
+}
+
+// NewStackExchangeEngine searches site, eg "superuser" or "unix"; empty
+// means "stackoverflow". The key is optional but raises the daily quota.
+func NewStackExchangeEngine(site string, key string) *StackExchangeEngine {
+	if site == "" {
+		site = "stackoverflow"
+	}
+	return &StackExchangeEngine{site: site, key: key}
+}
+
+func (e *StackExchangeEngine) Name() string { return "stackexchange" }
+
+func (e *StackExchangeEngine) Capabilities() Capabilities {
+	// Per IP without a key; 10,000 with one. Each page of results is up to
+	// three requests: the questions, their accepted answers and the rest.
+	quota := 300
+	if e.key != "" {
+		quota = 10000
+	}
+
+	return Capabilities{
+		MaxResultsPerRequest: sePageSize,
+		// No more than 30 requests a second, which we're never going to hit
+		DailyQuota: quota,
+	}
+}
+
+func (e *StackExchangeEngine) Search(ctx context.Context, sr SearchRequest) ([]SearchResult, error) {
+	if sr.Query == "" {
+		return nil, fmt.Errorf("search query cannot be empty")
+	}
+
+	client := httpClient(e.Client)
+
+	// Pages are numbered, so the page size has to stay the same
+	count := pageSize(sr, sePageSize)
+	page := sr.Offset/count + 1
+
+	return collectPages(ctx, sr, func(ctx context.Context, offset int) ([]SearchResult, error) {
+		results, err := e.fetchPage(ctx, client, sr, count, page)
+		page++
+		return results, err
+	})
+}
+
+func (e *StackExchangeEngine) fetchPage(ctx context.Context, client *http.Client, sr SearchRequest, count int, page int) ([]SearchResult, error) {
+	params := url.Values{}
+	params.Set("q", sr.Query)
+	params.Set("order", "desc")
+	params.Set("sort", "relevance")
+	params.Set("pagesize", fmt.Sprintf("%d", count))
+	params.Set("page", fmt.Sprintf("%d", page))
+	// Questions without answers aren't much use
+	params.Set("answers", "1")
+	if sr.Since > 0 {
+		params.Set("fromdate", fmt.Sprintf("%d", sr.SinceDate().Unix()))
+	}
+
+	var questions seResponse[seQuestion]
+	if err := e.get(ctx, client, "/search/advanced", params, &questions); err != nil {
+		return nil, err
+	}
+	if len(questions.Items) == 0 {
+		return nil, nil
+	}
+
+	// Answers are worth the extra request, but the questions are still
+	// worth something without them
+	answers, err := e.fetchAnswers(ctx, client, questions.Items)
+	if err != nil {
+		logger.GetLogger().Warn("Error getting Stack Exchange answers: ", err)
+	}
+
+	results := make([]SearchResult, 0, len(questions.Items))
+	for _, q := range questions.Items {
+		question := htmlToMarkdown(q.Body)
+		results = append(results, SearchResult{
+			Title:     html.UnescapeString(q.Title),
+			URL:       q.Link,
+			Snippet:   truncateText(question, maxSnippetLength),
+			Content:   seContent(q, question, answers[q.QuestionID]),
+			Published: unixTime(q.CreationDate),
+		})
+	}
+
+	return results, nil
+}
+
+// fetchAnswers gets the best answers for all of questions, keyed by question
+// ID. The accepted answers are asked for by ID, since one question with lots
+// of well voted answers could crowd them out of the top 100.
+func (e *StackExchangeEngine) fetchAnswers(ctx context.Context, client *http.Client, questions []seQuestion) (map[int][]seAnswer, error) {
+	ids := make([]string, 0, len(questions))
+	var acceptedIDs []string
+	accepted := make(map[int]int)
+	for _, q := range questions {
+		ids = append(ids, fmt.Sprintf("%d", q.QuestionID))
+		if q.AcceptedAnswerID != 0 {
+			acceptedIDs = append(acceptedIDs, fmt.Sprintf("%d", q.AcceptedAnswerID))
+			accepted[q.QuestionID] = q.AcceptedAnswerID
+		}
+	}
+
+	var answers []seAnswer
+	if len(acceptedIDs) > 0 {
+		acceptedAnswers, err := e.getAnswers(ctx, client, "/answers/"+strings.Join(acceptedIDs, ";"))
+		if err != nil {
+			return nil, err
+		}
+		answers = append(answers, acceptedAnswers...)
+	}
+	topAnswers, err := e.getAnswers(ctx, client, "/questions/"+strings.Join(ids, ";")+"/answers")
+	if err != nil {
+		return nil, err
+	}
+	answers = append(answers, topAnswers...)
+
+	seen := make(map[int]bool)
+	byQuestion := make(map[int][]seAnswer)
+	for _, a := range answers {
+		if seen[a.AnswerID] {
+			continue
+		}
+		seen[a.AnswerID] = true
+		byQuestion[a.QuestionID] = append(byQuestion[a.QuestionID], a)
+	}
+
+	// The accepted answer first, then by votes
+	for id, answers := range byQuestion {
+		sort.SliceStable(answers, func(i, j int) bool {
+			iAccepted := answers[i].AnswerID == accepted[id] || answers[i].IsAccepted
+			jAccepted := answers[j].AnswerID == accepted[id] || answers[j].IsAccepted
+			if iAccepted != jAccepted {
+				return iAccepted
+			}
+			return answers[i].Score > answers[j].Score
+		})
+		byQuestion[id] = answers[:min(len(answers), seAnswersPerQuestion)]
+	}
+
+	return byQuestion, nil
+}
+
+// getAnswers gets up to 100 answers, best first, from one of the answers
+// methods, eg /answers/{ids}.
+func (e *StackExchangeEngine) getAnswers(ctx context.Context, client *http.Client, path string) ([]seAnswer, error) {
+	if err := acquireRequest(ctx); err != nil {
+		return nil, err
+	}
+
+	params := url.Values{}
+	params.Set("order", "desc")
+	params.Set("sort", "votes")
+	params.Set("pagesize", "100")
+
+	var resp seResponse[seAnswer]
+	if err := e.get(ctx, client, path, params, &resp); err != nil {
+		return nil, err
+	}
+
+	return resp.Items, nil
+}
+
+// get makes a request to the API with the parameters every request needs,
+// and decodes the response into v, an seResponse.
+func (e *StackExchangeEngine) get(ctx context.Context, client *http.Client, path string, params url.Values, v interface{ wrapper() *seWrapper }) error {
+	if err := e.waitBackoff(ctx); err != nil {
+		return err
+	}
+
+	params.Set("site", e.site)
+	params.Set("filter", "withbody")
+	if e.key != "" {
+		params.Set("key", e.key)
+	}
+
+	baseURL := e.BaseURL
+	if baseURL == "" {
+		baseURL = StackExchangeBaseURL
+	}
+
+	resp, err := doRequest(ctx, client, e.Name(), func(ctx context.Context) (*http.Request, error) {
+		return http.NewRequestWithContext(ctx, "GET", baseURL+path+"?"+params.Encode(), nil)
+	})
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if err := json.NewDecoder(resp.Body).Decode(v); err != nil {
+		return &EngineError{Engine: e.Name(), Message: "error decoding response", Err: err}
+	}
+
+	w := v.wrapper()
+	if w.Backoff > 0 {
+		// This applies to the method, but it's simpler (and kinder) to
+		// leave the whole API alone
+		logger.GetLogger().Info(fmt.Sprintf("Stack Exchange asked for a %ds backoff", w.Backoff))
+		e.mu.Lock()
+		e.backoff = time.Now().Add(time.Duration(w.Backoff) * time.Second)
+		e.mu.Unlock()
+	}
+	if w.ErrorName != "" {
+		return &EngineError{Engine: e.Name(), Message: w.ErrorName + ": " + w.ErrorMessage}
+	}
+
+	return nil
+}
+
+func (e *StackExchangeEngine) waitBackoff(ctx context.Context) error {
+	e.mu.Lock()
+	wait := time.Until(e.backoff)
+	e.mu.Unlock()
+
+	if wait <= 0 {
+		return nil
+	}
+
+	timer := time.NewTimer(wait)
+	defer timer.Stop()
+	select {
+	case <-timer.C:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// seContent lays out a question and its answers for the summarizer.
+func seContent(q seQuestion, question string, answers []seAnswer) string {
+	var b strings.Builder
+	fmt.Fprintf(&b, "# %s\n\n", html.UnescapeString(q.Title))
+	fmt.Fprintf(&b, "Question (score %d", q.Score)
+	if len(q.Tags) > 0 {
+		fmt.Fprintf(&b, "; tags: %s", strings.Join(q.Tags, ", "))
+	}
+	b.WriteString("):\n\n" + question + "\n")
+
+	for _, a := range answers {
+		label := "Answer"
+		if a.IsAccepted || a.AnswerID == q.AcceptedAnswerID {
+			label = "Accepted answer"
+		}
+		fmt.Fprintf(&b, "\n## %s (score %d):\n\n%s\n", label, a.Score, htmlToMarkdown(a.Body))
+	}
+
+	return b.String()
+}
+
+// truncateText cuts text down to at most n bytes, at a word boundary, and
+// puts it on one line.
+func truncateText(text string, n int) string {
+	text = strings.Join(strings.Fields(text), " ")
+	if len(text) <= n {
+		return text
+	}
+
+	cut := strings.LastIndex(text[:n], " ")
+	if cut <= 0 {
+		cut = n
+		for cut > 0 && !utf8.RuneStart(text[cut]) {
+			cut--
+		}
+	}
+	return text[:cut] + "..."
+}
@@ -0,0 +1,128 @@
+package search
+
+import (
+	"context"
+	"net/http"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestStackExchangeSearch(t *testing.T) {
+	var requests []*http.Request
+	server := fixtureServer(t, map[string]string{
+		"/search/advanced":             "stackexchange_search.json",
+		"/answers/7102":                "stackexchange_accepted.json",
+		"/questions/7100;7200/answers": "stackexchange_answers.json",
+	}, &requests)
+
+	engine := NewStackExchangeEngine("", "key")
+	engine.BaseURL = server.URL
+	engine.Client = server.Client()
+	results, err := engine.Search(context.Background(), SearchRequest{Query: "go generic max", MaxResults: 2, Since: 365 * 24 * time.Hour})
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	expected := []string{
+		"https://stackoverflow.com/questions/7100/how-do-i-write-a-generic-max-function-in-go",
+		"https://stackoverflow.com/questions/7200/constraints-ordered-vs-cmp-ordered",
+	}
+	if !reflect.DeepEqual(urls(results), expected) {
+		t.Fatalf("Expected %v, got %v", expected, urls(results))
+	}
+
+	result := results[0]
+	if result.Title != "How do I write a generic Max function in Go's 1.18?" {
+		t.Errorf("Expected the title unescaped, got %q", result.Title)
+	}
+	if !strings.HasPrefix(result.Snippet, "I want a `Max` function that works for any ordered type.") {
+		t.Errorf("Unexpected snippet: %q", result.Snippet)
+	}
+	if !result.Published.Equal(time.Unix(1648000000, 0)) {
+		t.Errorf("Unexpected published date: %s", result.Published)
+	}
+
+	// The accepted answer comes first even with fewer votes (and when it's
+	// not among the top answers), and only two answers are kept
+	accepted := strings.Index(result.Content, "## Accepted answer (score 64):")
+	top := strings.Index(result.Content, "## Answer (score 120):")
+	if accepted < 0 || top < 0 || accepted > top {
+		t.Errorf("Expected the accepted answer then the top answer, got %q", result.Content)
+	}
+	if strings.Contains(result.Content, "reflection") {
+		t.Errorf("Expected the third answer to be left out, got %q", result.Content)
+	}
+	if !strings.Contains(result.Content, "```\nfunc Max[T cmp.Ordered](a, b T) T {\n    if a > b {") {
+		t.Errorf("Expected the code block to be kept, got %q", result.Content)
+	}
+	if !strings.Contains(result.Content, "Question (score 87; tags: go, generics):") {
+		t.Errorf("Expected the question's score and tags, got %q", result.Content)
+	}
+	if !strings.Contains(results[1].Content, "> Which one?") {
+		t.Errorf("Expected the quote in the second answer, got %q", results[1].Content)
+	}
+
+	if len(requests) != 3 {
+		t.Fatalf("Expected 3 requests, got %d", len(requests))
+	}
+	want := map[string]string{
+		"q":        "go generic max",
+		"site":     "stackoverflow",
+		"filter":   "withbody",
+		"key":      "key",
+		"pagesize": "4",
+		"page":     "1",
+		"answers":  "1",
+	}
+	for k, v := range want {
+		if got := requests[0].URL.Query().Get(k); got != v {
+			t.Errorf("Expected %s=%q, got %q", k, v, got)
+		}
+	}
+	if requests[0].URL.Query().Get("fromdate") == "" {
+		t.Error("Expected fromdate for --since")
+	}
+	if q := requests[2].URL.Query(); q.Get("sort") != "votes" || q.Get("filter") != "withbody" {
+		t.Errorf("Unexpected answers request: %v", q)
+	}
+}
+
+func TestStackExchangeSearchErrors(t *testing.T) {
+	server := fixtureServer(t, map[string]string{"/search/advanced": "stackexchange_search.json"}, nil)
+
+	// Without answers, the questions are still returned
+	engine := NewStackExchangeEngine("superuser", "")
+	engine.BaseURL = server.URL
+	results, err := engine.Search(context.Background(), SearchRequest{Query: "go", MaxResults: 2})
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if len(results) != 2 || strings.Contains(results[0].Content, "answer") {
+		t.Errorf("Expected 2 results without answers, got %+v", results)
+	}
+
+	if engine.Capabilities().DailyQuota != 300 || NewStackExchangeEngine("", "key").Capabilities().DailyQuota != 10000 {
+		t.Error("Expected a bigger quota with a key")
+	}
+}
+
+func TestTruncateText(t *testing.T) {
+	testCases := []struct {
+		input    string
+		n        int
+		expected string
+	}{
+		{"short", 10, "short"},
+		{"one two\nthree four", 12, "one two..."},
+		{"unbrokenword", 5, "unbro..."},
+		{"ééééé", 3, "é..."},
+	}
+
+	for _, tc := range testCases {
+		if got := truncateText(tc.input, tc.n); got != tc.expected {
+			t.Errorf("truncateText(%q, %d) = %q; want %q", tc.input, tc.n, got, tc.expected)
+		}
+	}
+}
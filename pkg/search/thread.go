@@ -0,0 +1,72 @@
+package search
+
+import (
+	"fmt"
+	"strings"
+)
+
+const (
+	// Comments to keep from a discussion, counting replies
+	threadMaxComments = 50
+	// Replies nested deeper than this are dropped
+	threadMaxDepth = 4
+)
+
+// threadComment is a comment on a forum (Hacker News, Reddit) and its
+// replies, in whatever order the site ranks them.
+type threadComment struct {
+	Author string
+	// Score is nil when the site doesn't give one out
+	Score   *int
+	Text    string
+	Replies []threadComment
+}
+
+// flattenThread lays a comment tree out as indented text, so that the
+// summarizer can see who is replying to whom and how well it went down.
+// Each comment is a bullet with its author and score, and the text under
+// it; replies are indented one level further.
+func flattenThread(comments []threadComment) string {
+	var b strings.Builder
+	written := 0
+	writeThread(&b, comments, 0, &written)
+	return strings.TrimRight(b.String(), "\n")
+}
+
+func writeThread(b *strings.Builder, comments []threadComment, depth int, written *int) {
+	if depth >= threadMaxDepth {
+		return
+	}
+
+	indent := strings.Repeat("  ", depth)
+	for _, c := range comments {
+		if *written >= threadMaxComments {
+			return
+		}
+
+		text := strings.TrimSpace(c.Text)
+		// A deleted comment can still have replies worth keeping
+		if text == "" && len(c.Replies) == 0 {
+			continue
+		}
+		if text == "" {
+			text = "[deleted]"
+		}
+		author := c.Author
+		if author == "" {
+			author = "[deleted]"
+		}
+
+		b.WriteString(indent + "- " + author)
+		if c.Score != nil {
+			fmt.Fprintf(b, " (%d points)", *c.Score)
+		}
+		b.WriteString(":\n")
+		for _, line := range strings.Split(text, "\n") {
+			b.WriteString(strings.TrimRight(indent+"  "+line, " ") + "\n")
+		}
+		*written++
+
+		writeThread(b, c.Replies, depth+1, written)
+	}
+}
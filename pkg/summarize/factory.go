@@ -1,24 +1,43 @@
 package summarize
 
 import (
+	"context"
+	"errors"
 	"fmt"
 
 	"ask-web/pkg/config"
 )
 
-const (
-	ModelOpenAI = "chatgpt"
-	ModelGoogle = "gemini"
-)
-
 func NewSummarizer(model string, apiKey string, opts *config.Opts) (Summarizer, error) {
-	switch model {
-	case ModelOpenAI:
-		return NewOpenAISummarizer(apiKey, opts)
-	case ModelGoogle:
-		return NewGoogleSummarizer(apiKey, opts)
-	default:
-		return nil, fmt.Errorf("unsupported model: %s. Supported models: %s, %s",
-			model, ModelOpenAI, ModelGoogle)
+	llm, err := NewCompleter(model, apiKey)
+	if err != nil {
+		return nil, err
 	}
+
+	return &LLMSummarizer{
+		llm:          llm,
+		opts:         opts,
+		systemPrompt: fmt.Sprintf("Fit the response within %d tokens", opts.MaxTokens),
+	}, nil
+}
+
+// LLMSummarizer summarizes with whichever provider's Completer it's given.
+type LLMSummarizer struct {
+	llm          Completer
+	opts         *config.Opts
+	systemPrompt string
+}
+
+func (s *LLMSummarizer) Summarize(ctx context.Context, contents []string, query string) (string, error) {
+	summary, err := s.llm.Complete(ctx, CompletionRequest{
+		System:      s.systemPrompt,
+		Prompt:      buildPrompt(contents, query, s.opts.SummaryPrompt),
+		MaxTokens:   s.opts.MaxTokens,
+		Temperature: float32(s.opts.Temperature),
+	})
+	if errors.Is(err, errNoResponse) {
+		return "", errors.New("no summary generated")
+	}
+
+	return summary, err
 }
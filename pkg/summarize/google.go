@@ -2,13 +2,10 @@ package summarize
 
 import (
 	"context"
-	"errors"
 	"fmt"
 
 	"github.com/google/generative-ai-go/genai"
 	"google.golang.org/api/option"
-
-	"ask-web/pkg/config"
 )
 
 type GenerativeModel interface {
@@ -17,44 +14,38 @@ type GenerativeModel interface {
 	SetMaxOutputTokens(int32)
 }
 
-type GoogleSummarizer struct {
-	client       *genai.Client
-	model        GenerativeModel // Changed from *genai.GenerativeModel
-	opts         *config.Opts
-	systemPrompt string
+type GoogleCompleter struct {
+	newModel func(name string) GenerativeModel
 }
 
-func NewGoogleSummarizer(apiKey string, opts *config.Opts) (*GoogleSummarizer, error) {
-	ctx := context.Background()
-	client, err := genai.NewClient(ctx, option.WithAPIKey(apiKey))
+func NewGoogleCompleter(apiKey string) (*GoogleCompleter, error) {
+	client, err := genai.NewClient(context.Background(), option.WithAPIKey(apiKey))
 	if err != nil {
 		return nil, fmt.Errorf("failed to create Google AI client: %w", err)
 	}
 
-	rawModel := client.GenerativeModel("gemini-2.0-flash-001")
-	var model GenerativeModel = rawModel
-
-	model.SetTemperature(float32(opts.Temperature))
-	model.SetMaxOutputTokens(int32(opts.MaxTokens)) // Cast to int32
-
-	return &GoogleSummarizer{
-		client:       client,
-		model:        model,
-		opts:         opts,
-		systemPrompt: fmt.Sprintf("Fit the response within %d tokens", opts.MaxTokens),
+	return &GoogleCompleter{
+		newModel: func(name string) GenerativeModel { return client.GenerativeModel(name) },
 	}, nil
 }
 
-func (s *GoogleSummarizer) Summarize(ctx context.Context, contents []string, query string) (string, error) {
-	prompt := buildPrompt(contents, query, s.opts.SummaryPrompt)
+func (c *GoogleCompleter) Complete(ctx context.Context, req CompletionRequest) (string, error) {
+	name := req.Model
+	if name == "" {
+		name = DefaultGoogleModel
+	}
+
+	model := c.newModel(name)
+	model.SetTemperature(req.Temperature)
+	model.SetMaxOutputTokens(int32(req.MaxTokens))
 
-	resp, err := s.model.GenerateContent(ctx, genai.Text(s.systemPrompt+"\n\n"+prompt))
+	resp, err := model.GenerateContent(ctx, genai.Text(req.System+"\n\n"+req.Prompt))
 	if err != nil {
 		return "", err
 	}
 
 	if len(resp.Candidates) == 0 || len(resp.Candidates[0].Content.Parts) == 0 {
-		return "", errors.New("no summary generated")
+		return "", errNoResponse
 	}
 
 	return fmt.Sprintf("%v", resp.Candidates[0].Content.Parts[0]), nil
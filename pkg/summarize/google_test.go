@@ -93,8 +93,10 @@ func TestGoogleSummarizer_Summarize(t *testing.T) {
 				response: tc.mockResponse,
 			}
 
-			summarizer := &GoogleSummarizer{
-				model: mockModel,
+			summarizer := &LLMSummarizer{
+				llm: &GoogleCompleter{
+					newModel: func(string) GenerativeModel { return mockModel },
+				},
 				opts: &config.Opts{
 					MaxTokens:     tc.maxTokens,
 					SummaryPrompt: "Please provide a detailed summary of the following text that is directly related to the query",
@@ -2,59 +2,49 @@ package summarize
 
 import (
 	"context"
-	"errors"
-	"fmt"
 
 	"github.com/sashabaranov/go-openai"
-
-	"ask-web/pkg/config"
 )
 
 type OpenAIModel interface {
 	CreateChatCompletion(ctx context.Context, request openai.ChatCompletionRequest) (openai.ChatCompletionResponse, error)
 }
 
-type OpenAISummarizer struct {
-	client       OpenAIModel
-	opts         *config.Opts
-	systemPrompt string
+type OpenAICompleter struct {
+	client OpenAIModel
 }
 
-func NewOpenAISummarizer(apiKey string, opts *config.Opts) (*OpenAISummarizer, error) {
-	client := openai.NewClient(apiKey)
-	return &OpenAISummarizer{
-		client:       client,
-		opts:         opts,
-		systemPrompt: fmt.Sprintf("Fit the response within %d tokens", opts.MaxTokens),
-	}, nil
+func NewOpenAICompleter(apiKey string) *OpenAICompleter {
+	return &OpenAICompleter{client: openai.NewClient(apiKey)}
 }
 
-func (s *OpenAISummarizer) Summarize(ctx context.Context, contents []string, query string) (string, error) {
-	prompt := buildPrompt(contents, query, s.opts.SummaryPrompt)
+func (c *OpenAICompleter) Complete(ctx context.Context, req CompletionRequest) (string, error) {
+	model := req.Model
+	if model == "" {
+		model = DefaultOpenAIModel
+	}
 
-	req := openai.ChatCompletionRequest{
-		Model:       openai.GPT4oMini,
-		MaxTokens:   s.opts.MaxTokens,
-		Temperature: float32(s.opts.Temperature),
+	resp, err := c.client.CreateChatCompletion(ctx, openai.ChatCompletionRequest{
+		Model:       model,
+		MaxTokens:   req.MaxTokens,
+		Temperature: req.Temperature,
 		Messages: []openai.ChatCompletionMessage{
 			{
 				Role:    openai.ChatMessageRoleSystem,
-				Content: s.systemPrompt,
+				Content: req.System,
 			},
 			{
 				Role:    openai.ChatMessageRoleUser,
-				Content: prompt,
+				Content: req.Prompt,
 			},
 		},
-	}
-
-	resp, err := s.client.CreateChatCompletion(ctx, req)
+	})
 	if err != nil {
 		return "", err
 	}
 
 	if len(resp.Choices) == 0 {
-		return "", errors.New("no summary generated")
+		return "", errNoResponse
 	}
 
 	return resp.Choices[0].Message.Content, nil
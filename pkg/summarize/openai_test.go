@@ -19,9 +19,9 @@ func (m *mockOpenAIModel) CreateChatCompletion(ctx context.Context, request open
 	return m.createChatCompletionFunc(ctx, request)
 }
 
-func newTestOpenAISummarizer(opts *config.Opts, mockClient OpenAIModel) *OpenAISummarizer {
-	return &OpenAISummarizer{
-		client:       mockClient,
+func newTestOpenAISummarizer(opts *config.Opts, mockClient OpenAIModel) *LLMSummarizer {
+	return &LLMSummarizer{
+		llm:          &OpenAICompleter{client: mockClient},
 		opts:         opts,
 		systemPrompt: fmt.Sprintf("Fit the response within %d tokens", opts.MaxTokens),
 	}
@@ -0,0 +1,49 @@
+package summarize
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/sashabaranov/go-openai"
+)
+
+const (
+	ModelOpenAI = "chatgpt"
+	ModelGoogle = "gemini"
+)
+
+const (
+	DefaultOpenAIModel = openai.GPT4oMini
+	DefaultGoogleModel = "gemini-2.0-flash-001"
+)
+
+var errNoResponse = errors.New("no response generated")
+
+// CompletionRequest is a single system + user prompt for whichever LLM
+// provider is in use. An empty Model means the provider's default.
+type CompletionRequest struct {
+	Model       string
+	System      string
+	Prompt      string
+	MaxTokens   int
+	Temperature float32
+}
+
+// Completer is the bare LLM call that summarizing and query rewriting are
+// both built on, without tying them to a particular provider.
+type Completer interface {
+	Complete(ctx context.Context, req CompletionRequest) (string, error)
+}
+
+func NewCompleter(model string, apiKey string) (Completer, error) {
+	switch model {
+	case ModelOpenAI:
+		return NewOpenAICompleter(apiKey), nil
+	case ModelGoogle:
+		return NewGoogleCompleter(apiKey)
+	default:
+		return nil, fmt.Errorf("unsupported model: %s. Supported models: %s, %s",
+			model, ModelOpenAI, ModelGoogle)
+	}
+}
@@ -0,0 +1,75 @@
+package summarize
+
+import (
+	"context"
+	"testing"
+
+	"github.com/google/generative-ai-go/genai"
+	"github.com/sashabaranov/go-openai"
+)
+
+func TestOpenAICompleter(t *testing.T) {
+	var sent openai.ChatCompletionRequest
+	completer := &OpenAICompleter{
+		client: &mockOpenAIModel{
+			createChatCompletionFunc: func(ctx context.Context, request openai.ChatCompletionRequest) (openai.ChatCompletionResponse, error) {
+				sent = request
+				return openai.ChatCompletionResponse{
+					Choices: []openai.ChatCompletionChoice{
+						{Message: openai.ChatCompletionMessage{Content: "go iterators"}},
+					},
+				}, nil
+			},
+		},
+	}
+
+	got, err := completer.Complete(context.Background(), CompletionRequest{
+		System:    "system",
+		Prompt:    "prompt",
+		MaxTokens: 50,
+	})
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if got != "go iterators" {
+		t.Errorf("Expected 'go iterators', got '%s'", got)
+	}
+	if sent.Model != DefaultOpenAIModel {
+		t.Errorf("Expected default model %s, got %s", DefaultOpenAIModel, sent.Model)
+	}
+	if sent.MaxTokens != 50 || len(sent.Messages) != 2 || sent.Messages[1].Content != "prompt" {
+		t.Errorf("Unexpected request: %+v", sent)
+	}
+
+	completer.Complete(context.Background(), CompletionRequest{Model: "gpt-4o"})
+	if sent.Model != "gpt-4o" {
+		t.Errorf("Expected model gpt-4o, got %s", sent.Model)
+	}
+}
+
+func TestGoogleCompleter(t *testing.T) {
+	var name string
+	completer := &GoogleCompleter{
+		newModel: func(n string) GenerativeModel {
+			name = n
+			return &mockGoogleModel{
+				response: mockGoogleResponse{
+					candidates: []*genai.Candidate{
+						{Content: &genai.Content{Parts: []genai.Part{genai.Text("go iterators")}}},
+					},
+				},
+			}
+		},
+	}
+
+	got, err := completer.Complete(context.Background(), CompletionRequest{Prompt: "prompt"})
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if got != "go iterators" {
+		t.Errorf("Expected 'go iterators', got '%s'", got)
+	}
+	if name != DefaultGoogleModel {
+		t.Errorf("Expected default model %s, got %s", DefaultGoogleModel, name)
+	}
+}
@@ -0,0 +1,42 @@
+package summarize
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	"ask-web/pkg/config"
+	"ask-web/pkg/search"
+)
+
+// NewSnippetSummarizer is NewSummarizer for --fast, where there are only
+// search snippets to go on rather than whole pages, so the snippet prompt is
+// used in place of the summary one.
+func NewSnippetSummarizer(model string, apiKey string, opts *config.Opts) (Summarizer, error) {
+	snippetOpts := *opts
+	snippetOpts.SummaryPrompt = opts.SnippetPrompt
+	return NewSummarizer(model, apiKey, &snippetOpts)
+}
+
+// FormatSnippets turns search results into contents for a Summarizer, one per
+// result, numbered so that the answer can refer to them. Results without a
+// snippet are still included since the title alone is sometimes the answer.
+func FormatSnippets(results []search.SearchResult) []string {
+	contents := make([]string, 0, len(results))
+	for i, result := range results {
+		var b strings.Builder
+		fmt.Fprintf(&b, "[%d] %s\nURL: %s", i+1, strings.TrimSpace(result.Title), result.URL)
+		if len(result.Authors) > 0 {
+			b.WriteString("\nAuthors: " + strings.Join(result.Authors, ", "))
+		}
+		if !result.Published.IsZero() {
+			b.WriteString("\nPublished: " + result.Published.Format(time.DateOnly))
+		}
+		if snippet := strings.TrimSpace(result.Snippet); snippet != "" {
+			b.WriteString("\n" + snippet)
+		}
+		contents = append(contents, b.String())
+	}
+
+	return contents
+}
@@ -0,0 +1,64 @@
+package summarize
+
+import (
+	"reflect"
+	"testing"
+	"time"
+
+	"ask-web/pkg/config"
+	"ask-web/pkg/search"
+)
+
+func TestFormatSnippets(t *testing.T) {
+	results := []search.SearchResult{
+		{Title: "The Go Programming Language ", URL: "https://go.dev/", Snippet: " Go is an open source programming language. "},
+		{Title: "Go by Example", URL: "https://gobyexample.com/"},
+		{
+			Title:     "Featherweight Go",
+			URL:       "http://arxiv.org/abs/2005.11710v5",
+			Snippet:   "We describe a design for generics in Go.",
+			Authors:   []string{"Robert Griesemer", "Philip Wadler"},
+			Published: time.Date(2020, 5, 24, 8, 0, 0, 0, time.UTC),
+		},
+	}
+
+	expected := []string{
+		"[1] The Go Programming Language\nURL: https://go.dev/\nGo is an open source programming language.",
+		"[2] Go by Example\nURL: https://gobyexample.com/",
+		"[3] Featherweight Go\nURL: http://arxiv.org/abs/2005.11710v5\nAuthors: Robert Griesemer, Philip Wadler\nPublished: 2020-05-24\nWe describe a design for generics in Go.",
+	}
+	if got := FormatSnippets(results); !reflect.DeepEqual(got, expected) {
+		t.Errorf("Expected %q, got %q", expected, got)
+	}
+
+	if got := FormatSnippets(nil); len(got) != 0 {
+		t.Errorf("Expected no contents, got %q", got)
+	}
+}
+
+func TestNewSnippetSummarizer(t *testing.T) {
+	opts := &config.Opts{SummaryPrompt: "Summarize", SnippetPrompt: "Answer from the snippets", MaxTokens: 100}
+
+	summarizer, err := NewSnippetSummarizer(ModelOpenAI, "key", opts)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	s, ok := summarizer.(*LLMSummarizer)
+	if !ok {
+		t.Fatalf("Expected an LLMSummarizer, got %T", summarizer)
+	}
+	if _, ok := s.llm.(*OpenAICompleter); !ok {
+		t.Errorf("Expected an OpenAICompleter, got %T", s.llm)
+	}
+	if s.opts.SummaryPrompt != "Answer from the snippets" {
+		t.Errorf("Expected the snippet prompt, got %q", s.opts.SummaryPrompt)
+	}
+	if opts.SummaryPrompt != "Summarize" {
+		t.Errorf("Expected the original opts to be left alone, got %q", opts.SummaryPrompt)
+	}
+
+	if _, err := NewSnippetSummarizer("claude", "key", opts); err == nil {
+		t.Error("Expected an error for an unsupported model")
+	}
+}
@@ -167,18 +167,24 @@ func TestSetupKeys(t *testing.T) {
 		{
 			name: "All keys from environment",
 			envVars: map[string]string{
-				"GOOGLE_API_KEY":  "env_google_api_key",
-				"GOOGLE_CSE_ID":   "env_google_cse_id",
-				"BING_API_KEY":    "env_bing_api_key",
-				"BING_CONFIG_KEY": "env_bing_config_key",
-				"OPENAI_API_KEY":  "env_openai_api_key",
+				"GOOGLE_API_KEY":    "env_google_api_key",
+				"GOOGLE_CSE_ID":     "env_google_cse_id",
+				"BING_API_KEY":      "env_bing_api_key",
+				"BING_CONFIG_KEY":   "env_bing_config_key",
+				"BRAVE_API_KEY":     "env_brave_api_key",
+				"OPENAI_API_KEY":    "env_openai_api_key",
+				"STACKEXCHANGE_KEY": "env_stackexchange_key",
+				"GITHUB_TOKEN":      "env_github_token",
 			},
 			expected: search.APIKeys{
-				GoogleAPIKey:  "env_google_api_key",
-				GoogleCSEID:   "env_google_cse_id",
-				BingAPIKey:    "env_bing_api_key",
-				BingConfigKey: "env_bing_config_key",
-				OpenAIKey:     "env_openai_api_key",
+				GoogleAPIKey:     "env_google_api_key",
+				GoogleCSEID:      "env_google_cse_id",
+				BingAPIKey:       "env_bing_api_key",
+				BingConfigKey:    "env_bing_config_key",
+				BraveAPIKey:      "env_brave_api_key",
+				OpenAIKey:        "env_openai_api_key",
+				StackExchangeKey: "env_stackexchange_key",
+				GitHubToken:      "env_github_token",
 			},
 		},
 		{
@@ -188,6 +194,7 @@ func TestSetupKeys(t *testing.T) {
 				"google-cse-id":   "file_google_cse_id",
 				"bing-api-key":    "file_bing_api_key",
 				"bing-config-key": "file_bing_config_key",
+				"brave-api-key":   "file_brave_api_key",
 				"openai-api-key":  "file_openai_api_key",
 			},
 			expected: search.APIKeys{
@@ -195,6 +202,7 @@ func TestSetupKeys(t *testing.T) {
 				GoogleCSEID:   "file_google_cse_id",
 				BingAPIKey:    "file_bing_api_key",
 				BingConfigKey: "file_bing_config_key",
+				BraveAPIKey:   "file_brave_api_key",
 				OpenAIKey:     "file_openai_api_key",
 			},
 		},
@@ -229,7 +237,10 @@ func TestSetupKeys(t *testing.T) {
 			os.Unsetenv("GOOGLE_CSE_ID")
 			os.Unsetenv("BING_API_KEY")
 			os.Unsetenv("BING_CONFIG_KEY")
+			os.Unsetenv("BRAVE_API_KEY")
 			os.Unsetenv("OPENAI_API_KEY")
+			os.Unsetenv("STACKEXCHANGE_KEY")
+			os.Unsetenv("GITHUB_TOKEN")
 
 			for k, v := range tc.envVars {
 				os.Setenv(k, v)